@@ -0,0 +1,299 @@
+package mcpnetbird
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const netbirdAuditLogEnvVar = "NETBIRD_AUDIT_LOG"
+
+// maxAuditErrorLength bounds the error text kept per record; API errors include the response body
+const maxAuditErrorLength = 1024
+
+// auditLogBackups is how many rotated audit log files are kept
+const auditLogBackups = 5
+
+// redacted replaces secret argument values in audit records
+const redacted = "[REDACTED]"
+
+// AuditIdentityHeader is the HTTP header, set by an authenticating proxy in front
+// of the SSE server, that identifies the caller in audit records
+var AuditIdentityHeader = "X-Forwarded-User"
+
+// AuditRecord is a single line of the audit log
+type AuditRecord struct {
+	Time       time.Time      `json:"time"`
+	Tool       string         `json:"tool"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	Session    string         `json:"session,omitempty"`
+	Caller     string         `json:"caller,omitempty"`
+	RemoteAddr string         `json:"remote_addr,omitempty"`
+	TokenID    string         `json:"token_id,omitempty"`
+	Host       string         `json:"host"`
+	DurationMS int64          `json:"duration_ms"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	APIStatus  int            `json:"api_status,omitempty"`
+	TraceID    string         `json:"trace_id,omitempty"`
+}
+
+// AuditLogger writes AuditRecords as JSON lines
+type AuditLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// GlobalAuditLogger receives a record for every tool call. It is nil (auditing disabled) unless enabled at startup.
+var GlobalAuditLogger *AuditLogger
+
+// NewAuditLogger creates an AuditLogger writing to w
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{w: w}
+}
+
+// LoadAuditLogTarget returns the audit log target with priority: CLI > env var. Empty disables auditing.
+func LoadAuditLogTarget(cliTarget string) string {
+	if cliTarget != "" {
+		return cliTarget
+	}
+	return os.Getenv(netbirdAuditLogEnvVar)
+}
+
+// OpenAuditLogger opens the audit log named by target: "stdout", "stderr" or a
+// file path. Files are rotated once they exceed maxSizeMB, keeping the last
+// five. stdout is refused in stdio mode, where it carries the protocol.
+func OpenAuditLogger(target, transport string, maxSizeMB int) (*AuditLogger, error) {
+	switch target {
+	case "stdout", "-":
+		if transport == "stdio" {
+			return nil, fmt.Errorf("audit log cannot be written to stdout in stdio mode; use stderr or a file")
+		}
+		return NewAuditLogger(os.Stdout), nil
+	case "stderr":
+		return NewAuditLogger(os.Stderr), nil
+	}
+	if maxSizeMB <= 0 {
+		return nil, fmt.Errorf("audit log max size must be positive: %d", maxSizeMB)
+	}
+	file, err := newRotatingFile(target, int64(maxSizeMB)<<20, auditLogBackups)
+	if err != nil {
+		return nil, fmt.Errorf("opening audit log: %w", err)
+	}
+	return NewAuditLogger(file), nil
+}
+
+// Log writes rec as a single JSON line
+func (a *AuditLogger) Log(rec AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshaling audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.w.Write(line)
+	return err
+}
+
+// auditHandler wraps a tool handler so each call is recorded in GlobalAuditLogger
+func auditHandler(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		logger := GlobalAuditLogger
+		if logger == nil {
+			return handler(ctx, request)
+		}
+
+		start := time.Now()
+		result, err := handler(ctx, request)
+
+		rec := AuditRecord{
+			Time:       start.UTC(),
+			Tool:       name,
+			Arguments:  RedactArguments(request.GetArguments()),
+			Host:       resolveAPIHost(ctx),
+			DurationMS: time.Since(start).Milliseconds(),
+			Status:     "ok",
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+			rec.TraceID = spanContext.TraceID().String()
+		}
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			rec.Session = session.SessionID()
+		}
+		if identity, ok := ctx.Value(callerIdentityKey{}).(callerIdentity); ok {
+			rec.Caller = identity.user
+			rec.RemoteAddr = identity.remoteAddr
+		}
+		if token := NetbirdAPIKeyFromContext(ctx); token != "" {
+			sum := sha256.Sum256([]byte(token))
+			rec.TokenID = hex.EncodeToString(sum[:6])
+		}
+		if err := callError(result, err); err != nil {
+			rec.Status = "error"
+			rec.Error = err.Error()
+			if len(rec.Error) > maxAuditErrorLength {
+				rec.Error = rec.Error[:maxAuditErrorLength] + "..."
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				rec.APIStatus = apiErr.StatusCode
+			}
+		}
+
+		if logErr := logger.Log(rec); logErr != nil {
+			slog.ErrorContext(ctx, "Writing audit record failed", "tool", name, "error", logErr)
+		}
+		return result, err
+	}
+}
+
+// RedactArguments returns a copy of args with secret values replaced. A value is
+// secret if its key names a key, token, password or secret (IDs such as key_id
+// are kept), or if it looks like a Netbird personal access token.
+func RedactArguments(args map[string]any) map[string]any {
+	if args == nil {
+		return nil
+	}
+	out := make(map[string]any, len(args))
+	for key, value := range args {
+		if isSecretKey(key) {
+			out[key] = redacted
+			continue
+		}
+		out[key] = redactValue(value)
+	}
+	return out
+}
+
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return RedactArguments(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item)
+		}
+		return out
+	case string:
+		if strings.HasPrefix(v, "nbp_") {
+			return redacted
+		}
+	}
+	return value
+}
+
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "id" || strings.HasSuffix(key, "_id") || strings.HasSuffix(key, "_ids") {
+		return false
+	}
+	for _, secret := range []string{"key", "token", "password", "secret"} {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+type callerIdentityKey struct{}
+
+// callerIdentity identifies who sent an SSE request
+type callerIdentity struct {
+	user       string
+	remoteAddr string
+}
+
+// ExtractCallerIdentitySSE is an SSEContextFunc that records the caller's
+// identity header and address for the audit log.
+var ExtractCallerIdentitySSE server.SSEContextFunc = func(ctx context.Context, req *http.Request) context.Context {
+	identity := callerIdentity{user: req.Header.Get(AuditIdentityHeader)}
+	if forwarded := req.Header.Get("X-Forwarded-For"); forwarded != "" {
+		identity.remoteAddr = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	} else if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		identity.remoteAddr = host
+	} else {
+		identity.remoteAddr = req.RemoteAddr
+	}
+	return context.WithValue(ctx, callerIdentityKey{}, identity)
+}
+
+// rotatingFile is an io.Writer that appends to a file and rotates it to
+// path.1, path.2, ... once it would grow beyond maxSize bytes
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+// Write is not safe for concurrent use; AuditLogger serializes calls
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, fmt.Errorf("rotating %s: %w", rf.path, err)
+		}
+	}
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	if rf.maxBackups > 0 {
+		for i := rf.maxBackups - 1; i > 0; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+		}
+		if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(rf.path); err != nil {
+		return err
+	}
+	return rf.open()
+}
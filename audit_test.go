@@ -0,0 +1,143 @@
+package mcpnetbird
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type auditTestParams struct {
+	Name string `json:"name"`
+	Key  string `json:"key,omitempty"`
+}
+
+func TestAuditHandler_RecordsCalls(t *testing.T) {
+	var buf bytes.Buffer
+	GlobalAuditLogger = NewAuditLogger(&buf)
+	defer func() { GlobalAuditLogger = nil }()
+
+	tool := MustTool("audit_test", "test tool", UpdateTool, func(ctx context.Context, args auditTestParams) (string, error) {
+		if args.Name == "fail" {
+			return "", fmt.Errorf("creating thing: %w", &APIError{StatusCode: 403, Body: "forbidden"})
+		}
+		return "done", nil
+	})
+
+	req := httptest.NewRequest("POST", "/message?sessionId=abc", nil)
+	req.Header.Set(AuditIdentityHeader, "alice@example.com")
+	req.RemoteAddr = "192.0.2.10:51234"
+	ctx := ExtractCallerIdentitySSE(WithNetbirdConfig(context.Background(), "nbp_secret", "netbird.example.com"), req)
+
+	for _, name := range []string{"ok", "fail"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Name = "audit_test"
+		request.Params.Arguments = map[string]any{"name": name, "key": "A1B2-C3D4"}
+		_, _ = tool.Handler(ctx, request)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 audit records, got %d: %s", len(lines), buf.String())
+	}
+	if strings.Contains(buf.String(), "A1B2-C3D4") || strings.Contains(buf.String(), "nbp_secret") {
+		t.Errorf("expected secrets to be redacted, got %s", buf.String())
+	}
+
+	var ok, failed AuditRecord
+	if err := json.Unmarshal([]byte(lines[0]), &ok); err != nil {
+		t.Fatalf("invalid record: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &failed); err != nil {
+		t.Fatalf("invalid record: %v", err)
+	}
+
+	if ok.Tool != "audit_test" || ok.Status != "ok" || ok.Host != "netbird.example.com" {
+		t.Errorf("unexpected record: %+v", ok)
+	}
+	if ok.Caller != "alice@example.com" || ok.RemoteAddr != "192.0.2.10" || ok.TokenID == "" {
+		t.Errorf("expected caller identity, got %+v", ok)
+	}
+	if ok.Arguments["name"] != "ok" || ok.Arguments["key"] != redacted {
+		t.Errorf("unexpected arguments: %v", ok.Arguments)
+	}
+	if failed.Status != "error" || failed.APIStatus != 403 || !strings.Contains(failed.Error, "forbidden") {
+		t.Errorf("expected API error to be recorded, got %+v", failed)
+	}
+}
+
+func TestRedactArguments(t *testing.T) {
+	args := map[string]any{
+		"key_id":      "key-1",
+		"api_token":   "abc",
+		"description": "nbp_abcdef",
+		"settings": map[string]any{
+			"client_secret": "s3cret",
+			"peers":         []any{"peer-1", map[string]any{"password": "hunter2"}},
+		},
+	}
+	out := RedactArguments(args)
+
+	if out["key_id"] != "key-1" {
+		t.Errorf("expected IDs to be kept, got %v", out["key_id"])
+	}
+	if out["api_token"] != redacted || out["description"] != redacted {
+		t.Errorf("expected token values to be redacted, got %v", out)
+	}
+	settings := out["settings"].(map[string]any)
+	if settings["client_secret"] != redacted {
+		t.Errorf("expected nested secret to be redacted, got %v", settings)
+	}
+	peers := settings["peers"].([]any)
+	if peers[0] != "peer-1" || peers[1].(map[string]any)["password"] != redacted {
+		t.Errorf("expected secrets in arrays to be redacted, got %v", peers)
+	}
+	if args["api_token"] != "abc" {
+		t.Error("expected the original arguments to be left unchanged")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	rf, err := newRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for file, want := range expected {
+		data, err := os.ReadFile(file)
+		if err != nil || string(data) != want {
+			t.Errorf("%s: expected %q, got %q (%v)", file, want, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("expected only 2 backups to be kept")
+	}
+}
+
+func TestOpenAuditLogger_RefusesStdoutInStdioMode(t *testing.T) {
+	if _, err := OpenAuditLogger("stdout", "stdio", 10); err == nil {
+		t.Error("expected error for stdout in stdio mode")
+	}
+	if _, err := OpenAuditLogger("stdout", "sse", 10); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
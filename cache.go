@@ -0,0 +1,179 @@
+package mcpnetbird
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const netbirdCacheTTLEnvVar = "NETBIRD_CACHE_TTL"
+
+// relatedCollections lists the collections whose GET responses embed data from
+// another collection, so a write to the key also invalidates the values.
+// For example peers embed their groups and networks list their policies.
+// Writes may also change other collections as a side effect: groups list the
+// network resources in them, and deleting a peer drops it from routes and
+// network routers.
+var relatedCollections = map[string][]string{
+	"groups":         {"peers", "networks", "policies", "setup-keys", "users"},
+	"peers":          {"groups", "routes", "networks"},
+	"policies":       {"networks"},
+	"posture-checks": {"policies"},
+	"networks":       {"policies", "groups"},
+}
+
+// ResponseCache is an in-memory cache of GET responses. Entries are partitioned
+// by API token so callers never see data fetched with another token.
+type ResponseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]map[string]cacheEntry // token hash -> URL -> entry
+	hits    uint64
+	misses  uint64
+}
+
+type cacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// CacheStats reports the size and effectiveness of a ResponseCache
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// GlobalResponseCache is shared by every NetbirdClient. It is nil (caching disabled) unless enabled at startup.
+var GlobalResponseCache *ResponseCache
+
+// NewResponseCache creates a cache whose entries expire after ttl
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:     ttl,
+		entries: make(map[string]map[string]cacheEntry),
+	}
+}
+
+// LoadCacheTTL returns the cache TTL with priority: CLI > env var. Zero disables caching.
+func LoadCacheTTL(cliTTL time.Duration) (time.Duration, error) {
+	if cliTTL != 0 {
+		if cliTTL < 0 {
+			return 0, fmt.Errorf("cache TTL cannot be negative: %s", cliTTL)
+		}
+		return cliTTL, nil
+	}
+	envTTL := os.Getenv(netbirdCacheTTLEnvVar)
+	if envTTL == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(envTTL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s '%s': %w", netbirdCacheTTLEnvVar, envTTL, err)
+	}
+	if ttl < 0 {
+		return 0, fmt.Errorf("invalid %s '%s': cannot be negative", netbirdCacheTTLEnvVar, envTTL)
+	}
+	return ttl, nil
+}
+
+// tokenKey hashes the API token so the raw token is not kept as a map key
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached response body for url if present and not expired
+func (rc *ResponseCache) get(token, url string) ([]byte, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	partition := rc.entries[tokenKey(token)]
+	entry, ok := partition[url]
+	if ok && time.Now().Before(entry.expires) {
+		rc.hits++
+		return entry.data, true
+	}
+	if ok {
+		delete(partition, url)
+	}
+	rc.misses++
+	return nil, false
+}
+
+// set stores a response body for url
+func (rc *ResponseCache) set(token, url string, data []byte) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	key := tokenKey(token)
+	partition, ok := rc.entries[key]
+	if !ok {
+		partition = make(map[string]cacheEntry)
+		rc.entries[key] = partition
+	}
+	partition[url] = cacheEntry{data: data, expires: time.Now().Add(rc.ttl)}
+}
+
+// invalidate removes every entry, for all tokens, under the collection that path
+// belongs to and under the collections listed in relatedCollections.
+func (rc *ResponseCache) invalidate(baseURL, path string) {
+	collection := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)[0]
+	if collection == "" {
+		rc.ClearAll()
+		return
+	}
+	prefixes := []string{baseURL + "/" + collection}
+	for _, related := range relatedCollections[collection] {
+		prefixes = append(prefixes, baseURL+"/"+related)
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for _, partition := range rc.entries {
+		for url := range partition {
+			for _, prefix := range prefixes {
+				if url == prefix || strings.HasPrefix(url, prefix+"/") || strings.HasPrefix(url, prefix+"?") {
+					delete(partition, url)
+					break
+				}
+			}
+		}
+	}
+}
+
+// Clear removes all entries cached for token and returns how many were removed
+func (rc *ResponseCache) Clear(token string) int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	key := tokenKey(token)
+	removed := len(rc.entries[key])
+	delete(rc.entries, key)
+	return removed
+}
+
+// ClearAll removes all entries for every token
+func (rc *ResponseCache) ClearAll() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.entries = make(map[string]map[string]cacheEntry)
+}
+
+// Stats returns the number of cached entries and the hit/miss counters
+func (rc *ResponseCache) Stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	stats := CacheStats{Hits: rc.hits, Misses: rc.misses}
+	for _, partition := range rc.entries {
+		stats.Entries += len(partition)
+	}
+	return stats
+}
@@ -0,0 +1,209 @@
+package mcpnetbird
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingServer serves a JSON list for every GET and counts requests per path
+func countingServer() (*httptest.Server, func(path string) int) {
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		counts[r.Method+" "+r.URL.Path]++
+		mu.Unlock()
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"id":"1"}]`))
+	}))
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[path]
+	}
+}
+
+func TestResponseCache_ServesRepeatedGets(t *testing.T) {
+	server, count := countingServer()
+	defer server.Close()
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	client.cache = NewResponseCache(time.Minute)
+	ctx := WithNetbirdAPIKey(context.Background(), "token-a")
+
+	for i := 0; i < 3; i++ {
+		var items []map[string]string
+		if err := client.Get(ctx, "/policies", &items); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(items) != 1 || items[0]["id"] != "1" {
+			t.Fatalf("unexpected response: %v", items)
+		}
+	}
+
+	if got := count("GET /policies"); got != 1 {
+		t.Errorf("expected 1 upstream request, got %d", got)
+	}
+	stats := client.cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestResponseCache_PartitionedByToken(t *testing.T) {
+	server, count := countingServer()
+	defer server.Close()
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	client.cache = NewResponseCache(time.Minute)
+
+	var items []map[string]string
+	_ = client.Get(WithNetbirdAPIKey(context.Background(), "token-a"), "/peers", &items)
+	_ = client.Get(WithNetbirdAPIKey(context.Background(), "token-b"), "/peers", &items)
+
+	if got := count("GET /peers"); got != 2 {
+		t.Errorf("expected each token to fetch separately, got %d requests", got)
+	}
+
+	if removed := client.cache.Clear("token-a"); removed != 1 {
+		t.Errorf("expected 1 entry removed, got %d", removed)
+	}
+	if stats := client.cache.Stats(); stats.Entries != 1 {
+		t.Errorf("expected token-b entry to remain, got %+v", stats)
+	}
+}
+
+func TestResponseCache_Expiry(t *testing.T) {
+	server, count := countingServer()
+	defer server.Close()
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	client.cache = NewResponseCache(10 * time.Millisecond)
+	ctx := WithNetbirdAPIKey(context.Background(), "token-a")
+
+	var items []map[string]string
+	_ = client.Get(ctx, "/groups", &items)
+	time.Sleep(20 * time.Millisecond)
+	_ = client.Get(ctx, "/groups", &items)
+
+	if got := count("GET /groups"); got != 2 {
+		t.Errorf("expected expired entry to be refetched, got %d requests", got)
+	}
+}
+
+func TestResponseCache_InvalidatedOnWrite(t *testing.T) {
+	server, count := countingServer()
+	defer server.Close()
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	client.cache = NewResponseCache(time.Minute)
+	ctx := WithNetbirdAPIKey(context.Background(), "token-a")
+
+	var items []map[string]string
+	for _, path := range []string{"/groups", "/groups/g1", "/peers", "/routes", "/policies"} {
+		_ = client.Get(ctx, path, &items)
+	}
+
+	// Updating a group invalidates groups and the collections that embed groups
+	if err := client.Put(ctx, "/groups/g1", map[string]string{"name": "x"}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, path := range []string{"/groups", "/groups/g1", "/peers", "/routes", "/policies"} {
+		_ = client.Get(ctx, path, &items)
+	}
+
+	expected := map[string]int{
+		"GET /groups":    2,
+		"GET /groups/g1": 2,
+		"GET /peers":     2,
+		"GET /policies":  2,
+		"GET /routes":    1,
+	}
+	for path, want := range expected {
+		if got := count(path); got != want {
+			t.Errorf("%s: expected %d requests, got %d", path, want, got)
+		}
+	}
+}
+
+func TestResponseCache_InvalidatedOnRelatedWrites(t *testing.T) {
+	tests := []struct {
+		write       string
+		invalidated []string
+		kept        []string
+	}{
+		// Network resources are listed in the groups they belong to
+		{"/networks/n1/resources/r1", []string{"/networks", "/groups", "/policies"}, []string{"/peers", "/routes"}},
+		// Deleting a peer drops it from routes and network routers
+		{"/peers/p1", []string{"/peers", "/groups", "/routes", "/networks"}, []string{"/policies", "/users"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.write, func(t *testing.T) {
+			server, count := countingServer()
+			defer server.Close()
+
+			client := NewNetbirdClientWithBaseURL(server.URL)
+			client.cache = NewResponseCache(time.Minute)
+			ctx := WithNetbirdAPIKey(context.Background(), "token-a")
+
+			var items []map[string]string
+			paths := append(append([]string{}, tt.invalidated...), tt.kept...)
+			for _, path := range paths {
+				_ = client.Get(ctx, path, &items)
+			}
+			if err := client.Delete(ctx, tt.write); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, path := range paths {
+				_ = client.Get(ctx, path, &items)
+			}
+
+			for _, path := range tt.invalidated {
+				if got := count("GET " + path); got != 2 {
+					t.Errorf("%s: expected the cache to be invalidated, got %d requests", path, got)
+				}
+			}
+			for _, path := range tt.kept {
+				if got := count("GET " + path); got != 1 {
+					t.Errorf("%s: expected the cache to be kept, got %d requests", path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCacheTTL(t *testing.T) {
+	original := os.Getenv(netbirdCacheTTLEnvVar)
+	defer os.Setenv(netbirdCacheTTLEnvVar, original)
+
+	os.Setenv(netbirdCacheTTLEnvVar, "")
+	if ttl, err := LoadCacheTTL(0); err != nil || ttl != 0 {
+		t.Errorf("expected caching disabled by default, got %v, %v", ttl, err)
+	}
+
+	os.Setenv(netbirdCacheTTLEnvVar, "45s")
+	if ttl, err := LoadCacheTTL(0); err != nil || ttl != 45*time.Second {
+		t.Errorf("expected env TTL, got %v, %v", ttl, err)
+	}
+	if ttl, err := LoadCacheTTL(time.Minute); err != nil || ttl != time.Minute {
+		t.Errorf("expected CLI TTL to take priority, got %v, %v", ttl, err)
+	}
+
+	os.Setenv(netbirdCacheTTLEnvVar, "soon")
+	if _, err := LoadCacheTTL(0); err == nil {
+		t.Error("expected error for invalid env TTL")
+	}
+	if _, err := LoadCacheTTL(-time.Second); err == nil {
+		t.Error("expected error for negative TTL")
+	}
+}
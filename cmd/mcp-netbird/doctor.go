@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/XNet-NGO/mcp-netbird/tools"
+)
+
+// runDoctor implements `mcp-netbird doctor`, which runs the same checks as the
+// netbird_diagnostics tool from the command line. It exits non-zero when a check fails.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	apiToken := fs.String("api-token", "", "Netbird API token")
+	apiHost := fs.String("api-host", "", "Netbird API host (without protocol)")
+	asJSON := fs.Bool("json", false, "Print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: mcp-netbird doctor [flags]\n\nCheck connectivity to the Netbird API and the permissions of the API token.\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	cfg, err := mcpnetbird.NewConfigLoader(*apiToken, *apiHost).LoadConfig("", "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	ctx := mcpnetbird.WithNetbirdConfig(context.Background(), cfg.APIToken, cfg.APIHost)
+	report := tools.RunNetbirdDiagnostics(ctx)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		printDiagnostics(os.Stdout, report)
+	}
+	if report.Status == "fail" {
+		return 1
+	}
+	return 0
+}
+
+func printDiagnostics(w io.Writer, report *tools.DiagnosticsReport) {
+	fmt.Fprintf(w, "Netbird API: %s\n\n", report.APIURL)
+	for _, check := range report.Checks {
+		fmt.Fprintf(w, "[%-7s] %-11s %s\n", check.Status, check.Name, check.Detail)
+	}
+
+	if len(report.Categories) > 0 {
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "CATEGORY\tREAD\tWRITE")
+		for _, a := range report.Categories {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", a.Category, yesNo(a.Read), yesNo(a.Write))
+		}
+		_ = tw.Flush()
+	}
+	fmt.Fprintf(w, "\nResult: %s\n", report.Status)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -21,19 +21,31 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
 	"github.com/XNet-NGO/mcp-netbird/tools"
 )
 
-func newServer() *server.MCPServer {
+// Build information, set with -ldflags "-X main.version=... -X main.commit=... -X main.date=..."
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+func newServer(toolPermissions string) *server.MCPServer {
+	mcpnetbird.ToolFilter = toolPermissionFilter(toolPermissions)
+
 	s := server.NewMCPServer(
 		"mcp-netbird",
-		"0.1.0",
+		version,
 	)
 	tools.AddNetbirdPeerTools(s)
 	tools.AddNetbirdGroupTools(s)
@@ -48,11 +60,50 @@ func newServer() *server.MCPServer {
 	tools.AddNetbirdSetupKeyTools(s)
 	tools.AddNetbirdUserTools(s)
 	tools.AddNetbirdAccountTools(s)
+	tools.AddNetbirdSearchTools(s)
+	tools.AddNetbirdCacheTools(s)
+	tools.AddNetbirdJournalTools(s)
+	tools.AddNetbirdDiagnosticsTools(s)
+	tools.AddNetbirdMembershipTools(s)
+	tools.AddNetbirdDependencyTools(s)
+	tools.AddNetbirdTopologyTools(s)
+	tools.AddNetbirdResources(s)
+	tools.AddNetbirdPrompts(s)
 	return s
 }
 
-func run(transport, addr string) error {
-	s := newServer()
+// toolPermissionFilter looks up the role of the configured API token so that
+// newServer can annotate or hide the tools it can't use. It returns nil, which
+// registers every tool unchanged, when the mode is off, no token is configured
+// or the lookup fails.
+func toolPermissionFilter(mode string) func(mcp.Tool) (mcp.Tool, bool) {
+	if mode == tools.ToolPermissionsOff {
+		return nil
+	}
+	cfg, err := mcpnetbird.GlobalConfigLoader.LoadConfig("", "")
+	if err != nil || cfg.APIToken == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx = mcpnetbird.WithNetbirdConfig(ctx, cfg.APIToken, cfg.APIHost)
+	user, err := tools.FetchNetbirdCurrentUser(ctx)
+	if err != nil {
+		slog.Warn("Could not look up the API token's permissions; registering all tools", "error", err)
+		return nil
+	}
+	filter, err := tools.NewToolPermissionFilter(user, mode)
+	if err != nil {
+		slog.Warn("Ignoring tool permissions", "error", err)
+		return nil
+	}
+	slog.Info("Applying API token permissions to tools", "role", user.Role, "service_user", user.IsServiceUser, "mode", mode)
+	return filter
+}
+
+func run(transport, addr string, checkAPI bool, toolPermissions string) error {
+	s := newServer(toolPermissions)
 
 	switch transport {
 	case "stdio":
@@ -63,8 +114,20 @@ func run(transport, addr string) error {
 		srv := server.NewSSEServer(s,
 			server.WithSSEContextFunc(mcpnetbird.ComposedSSEContextFunc),
 		)
-		log.Printf("SSE server listening on %s", addr)
-		if err := srv.Start(addr); err != nil {
+		mux := http.NewServeMux()
+		mux.Handle("/", srv)
+		mux.Handle("/healthz", mcpnetbird.HealthHandler(version))
+		if checkAPI {
+			mux.Handle("/readyz", mcpnetbird.ReadinessHandler(mcpnetbird.CheckAPIConnectivity))
+		} else {
+			mux.Handle("/readyz", mcpnetbird.ReadinessHandler(nil))
+		}
+		if metrics := mcpnetbird.GlobalMetrics; metrics != nil {
+			mux.Handle(srv.CompleteSsePath(), metrics.TrackSSESessions(srv))
+			mux.Handle("/metrics", metrics.Handler())
+		}
+		slog.Info("SSE server listening", "address", addr, "version", version, "metrics", mcpnetbird.GlobalMetrics != nil)
+		if err := http.ListenAndServe(addr, mux); err != nil {
 			return fmt.Errorf("server error: %v", err)
 		}
 	default:
@@ -77,10 +140,25 @@ func run(transport, addr string) error {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		os.Exit(runDoctor(os.Args[2:]))
+	}
+
 	var transport string
 	var apiToken string
 	var apiHost string
-	
+	var cacheTTL time.Duration
+	var confirmDestructive bool
+	var auditLog string
+	var auditLogMaxSize int
+	var logLevel string
+	var logFormat string
+	var enableMetrics bool
+	var tracing string
+	var readinessCheckAPI bool
+	var showVersion bool
+	var toolPermissions string
+
 	flag.StringVar(&transport, "t", "stdio", "Transport type (stdio or sse)")
 	flag.StringVar(
 		&transport,
@@ -91,12 +169,82 @@ func main() {
 	addr := flag.String("sse-address", "localhost:8001", "The host and port to start the sse server on")
 	flag.StringVar(&apiToken, "api-token", "", "Netbird API token")
 	flag.StringVar(&apiHost, "api-host", "", "Netbird API host (without protocol)")
+	flag.DurationVar(&cacheTTL, "cache-ttl", 0, "Cache GET responses for this long, e.g. 30s (0 disables caching)")
+	flag.StringVar(&logLevel, "log-level", "", "Log level: debug, info, warn or error (default info)")
+	flag.StringVar(&logFormat, "log-format", "", "Log format: text or json (default text)")
+	flag.StringVar(&auditLog, "audit-log", "", "Write a JSON-lines audit record of every tool call to this file, or to stdout or stderr")
+	flag.IntVar(&auditLogMaxSize, "audit-log-max-size", 10, "Rotate the audit log file after this many megabytes")
+	flag.StringVar(&mcpnetbird.AuditIdentityHeader, "audit-identity-header", mcpnetbird.AuditIdentityHeader, "HTTP header identifying the caller in audit records (SSE mode)")
+	flag.BoolVar(&enableMetrics, "metrics", false, "Serve Prometheus metrics on /metrics (SSE mode only)")
+	flag.StringVar(&tracing, "tracing", "", "Export OpenTelemetry traces of tool calls and API requests: otlp or stdout (default disabled)")
+	flag.BoolVar(&readinessCheckAPI, "readiness-check-api", false, "Make /readyz verify Netbird API connectivity with the configured token (SSE mode only)")
+	flag.StringVar(&toolPermissions, "tool-permissions", tools.ToolPermissionsAnnotate, "How to list tools the configured API token's role can't use: annotate, hide or off")
+	flag.BoolVar(&showVersion, "version", false, "Print the version and exit")
+	flag.BoolVar(&confirmDestructive, "confirm-destructive", true, "Require a confirmation token from a second call before delete and bulk tools make changes")
 	flag.Parse()
 
+	if showVersion {
+		fmt.Printf("mcp-netbird %s (commit %s, built %s)\n", version, commit, date)
+		return
+	}
+
+	// Logs go to stderr in every mode; stdout carries the protocol in stdio mode
+	if err := mcpnetbird.SetupLogging(logLevel, logFormat); err != nil {
+		panic(err)
+	}
+
 	// Create global ConfigLoader instance with CLI flag values
 	mcpnetbird.GlobalConfigLoader = mcpnetbird.NewConfigLoader(apiToken, apiHost)
 
-	if err := run(transport, *addr); err != nil {
+	ttl, err := mcpnetbird.LoadCacheTTL(cacheTTL)
+	if err != nil {
+		panic(err)
+	}
+	if ttl > 0 {
+		mcpnetbird.GlobalResponseCache = mcpnetbird.NewResponseCache(ttl)
+	}
+	tools.RequireConfirmation = confirmDestructive
+
+	if target := mcpnetbird.LoadAuditLogTarget(auditLog); target != "" {
+		logger, err := mcpnetbird.OpenAuditLogger(target, transport, auditLogMaxSize)
+		if err != nil {
+			panic(err)
+		}
+		mcpnetbird.GlobalAuditLogger = logger
+	}
+
+	if enableMetrics {
+		if transport == "sse" {
+			mcpnetbird.GlobalMetrics = mcpnetbird.NewMetrics()
+		} else {
+			slog.Warn("Metrics are only served in SSE mode; ignoring -metrics", "transport", transport)
+		}
+	}
+
+	if exporter := mcpnetbird.LoadTracingExporter(tracing); exporter != "" {
+		shutdown, err := mcpnetbird.SetupTracing(context.Background(), exporter, version)
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err := shutdown(context.Background()); err != nil {
+				slog.Warn("Failed to flush traces", "error", err)
+			}
+		}()
+	}
+
+	switch toolPermissions {
+	case tools.ToolPermissionsOff, tools.ToolPermissionsAnnotate, tools.ToolPermissionsHide:
+	default:
+		panic(fmt.Errorf("invalid tool permissions mode '%s': must be annotate, hide or off", toolPermissions))
+	}
+	// Tokens sent in SSE request headers may belong to other users than the configured one
+	if transport == "sse" && apiToken == "" && toolPermissions != tools.ToolPermissionsOff {
+		slog.Info("Tool permissions are only applied in SSE mode with -api-token; listing all tools")
+		toolPermissions = tools.ToolPermissionsOff
+	}
+
+	if err := run(transport, *addr, readinessCheckAPI, toolPermissions); err != nil {
 		panic(err)
 	}
 }
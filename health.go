@@ -0,0 +1,68 @@
+package mcpnetbird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds the API connectivity check of a readiness probe
+const readinessTimeout = 5 * time.Second
+
+// HealthHandler serves the liveness probe, which succeeds while the process is serving requests
+func HealthHandler(version string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProbeResponse(w, http.StatusOK, map[string]any{"status": "ok", "version": version})
+	})
+}
+
+// ReadinessHandler serves the readiness probe. When check is nil the server is
+// always ready; otherwise it is ready only while check succeeds.
+func ReadinessHandler(check func(context.Context) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+			defer cancel()
+			if err := check(ctx); err != nil {
+				writeProbeResponse(w, http.StatusServiceUnavailable, map[string]any{"status": "not_ready", "error": err.Error()})
+				return
+			}
+		}
+		writeProbeResponse(w, http.StatusOK, map[string]any{"status": "ready"})
+	})
+}
+
+func writeProbeResponse(w http.ResponseWriter, status int, body map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// CheckAPIConnectivity verifies that the Netbird API is reachable and accepts the
+// token configured through CLI flags or environment variables. Tokens sent per
+// request in HTTP headers can't be checked ahead of time.
+func CheckAPIConnectivity(ctx context.Context) error {
+	loader := GlobalConfigLoader
+	if loader == nil {
+		loader = NewConfigLoader("", "")
+	}
+	cfg, err := loader.LoadConfig("", "")
+	if err != nil {
+		return err
+	}
+	if cfg.APIToken == "" {
+		return errors.New("no Netbird API token configured")
+	}
+
+	ctx = WithNetbirdConfig(ctx, cfg.APIToken, cfg.APIHost)
+	return checkAPIConnectivity(ctx, NewNetbirdClient(ctx))
+}
+
+// checkAPIConnectivity lists accounts, which every valid token may do, bypassing
+// the response cache so the probe always reaches the API
+func checkAPIConnectivity(ctx context.Context, client *NetbirdClient) error {
+	return client.Uncached().Get(ctx, "/accounts", nil)
+}
@@ -0,0 +1,95 @@
+package mcpnetbird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func probe(t *testing.T, handler http.Handler) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("expected JSON body, got %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, body
+}
+
+func TestHealthHandler(t *testing.T) {
+	code, body := probe(t, HealthHandler("1.2.3"))
+	if code != http.StatusOK || body["status"] != "ok" || body["version"] != "1.2.3" {
+		t.Errorf("unexpected response %d %v", code, body)
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	if code, body := probe(t, ReadinessHandler(nil)); code != http.StatusOK || body["status"] != "ready" {
+		t.Errorf("expected ready without a check, got %d %v", code, body)
+	}
+
+	failing := ReadinessHandler(func(ctx context.Context) error {
+		return errors.New("unexpected status code: 401")
+	})
+	code, body := probe(t, failing)
+	if code != http.StatusServiceUnavailable || body["status"] != "not_ready" || body["error"] != "unexpected status code: 401" {
+		t.Errorf("expected not ready, got %d %v", code, body)
+	}
+}
+
+func TestCheckAPIConnectivity(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/accounts" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Token good" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	original := GlobalResponseCache
+	GlobalResponseCache = NewResponseCache(time.Minute)
+	defer func() { GlobalResponseCache = original }()
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	ctx := WithNetbirdAPIKey(context.Background(), "good")
+	for i := 0; i < 2; i++ {
+		if err := checkAPIConnectivity(ctx, client); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("expected every check to reach the API, got %d requests", requests)
+	}
+
+	var apiErr *APIError
+	err := checkAPIConnectivity(WithNetbirdAPIKey(context.Background(), "bad"), client)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 API error, got %v", err)
+	}
+}
+
+func TestCheckAPIConnectivity_NoToken(t *testing.T) {
+	original := os.Getenv(netbirdAPIEnvVar)
+	defer os.Setenv(netbirdAPIEnvVar, original)
+	os.Setenv(netbirdAPIEnvVar, "")
+
+	originalLoader := GlobalConfigLoader
+	GlobalConfigLoader = NewConfigLoader("", "")
+	defer func() { GlobalConfigLoader = originalLoader }()
+
+	if err := CheckAPIConnectivity(context.Background()); err == nil {
+		t.Error("expected error without a configured token")
+	}
+}
@@ -0,0 +1,67 @@
+package mcpnetbird
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+const (
+	netbirdLogLevelEnvVar  = "NETBIRD_LOG_LEVEL"
+	netbirdLogFormatEnvVar = "NETBIRD_LOG_FORMAT"
+
+	defaultLogLevel  = "info"
+	defaultLogFormat = "text"
+)
+
+// LoadLogConfig returns the log level and format with priority: CLI > env var > default
+func LoadLogConfig(cliLevel, cliFormat string) (level, format string) {
+	level, format = cliLevel, cliFormat
+	if level == "" {
+		level = os.Getenv(netbirdLogLevelEnvVar)
+	}
+	if format == "" {
+		format = os.Getenv(netbirdLogFormatEnvVar)
+	}
+	if level == "" {
+		level = defaultLogLevel
+	}
+	if format == "" {
+		format = defaultLogFormat
+	}
+	return level, format
+}
+
+// NewLogger creates a logger writing to w at level ("debug", "info", "warn" or
+// "error") in format ("text" or "json")
+func NewLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level '%s': must be debug, info, warn or error", level)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format '%s': must be text or json", format)
+	}
+}
+
+// SetupLogging installs the default slog logger, which also receives output from
+// the standard log package. Logs always go to stderr: in stdio mode stdout
+// carries the MCP protocol and any other output would corrupt it.
+func SetupLogging(cliLevel, cliFormat string) error {
+	level, format := LoadLogConfig(cliLevel, cliFormat)
+	logger, err := NewLogger(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
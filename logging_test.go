@@ -0,0 +1,86 @@
+package mcpnetbird
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoadLogConfig(t *testing.T) {
+	originalLevel := os.Getenv(netbirdLogLevelEnvVar)
+	originalFormat := os.Getenv(netbirdLogFormatEnvVar)
+	defer os.Setenv(netbirdLogLevelEnvVar, originalLevel)
+	defer os.Setenv(netbirdLogFormatEnvVar, originalFormat)
+
+	os.Setenv(netbirdLogLevelEnvVar, "")
+	os.Setenv(netbirdLogFormatEnvVar, "")
+	if level, format := LoadLogConfig("", ""); level != "info" || format != "text" {
+		t.Errorf("expected defaults, got %s/%s", level, format)
+	}
+
+	os.Setenv(netbirdLogLevelEnvVar, "debug")
+	os.Setenv(netbirdLogFormatEnvVar, "json")
+	if level, format := LoadLogConfig("", ""); level != "debug" || format != "json" {
+		t.Errorf("expected env values, got %s/%s", level, format)
+	}
+	if level, format := LoadLogConfig("warn", "text"); level != "warn" || format != "text" {
+		t.Errorf("expected CLI values to take priority, got %s/%s", level, format)
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	if _, err := NewLogger(&bytes.Buffer{}, "verbose", "text"); err == nil {
+		t.Error("expected error for invalid level")
+	}
+	if _, err := NewLogger(&bytes.Buffer{}, "info", "xml"); err == nil {
+		t.Error("expected error for invalid format")
+	}
+
+	var buf bytes.Buffer
+	logger, err := NewLogger(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("expected a single JSON line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "shown" || entry["key"] != "value" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+}
+
+func TestNetbirdClient_LogsAPICallsAtDebug(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger, _ := NewLogger(&buf, "debug", "text")
+	original := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(original)
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	_ = client.Get(WithNetbirdAPIKey(context.Background(), "token"), "/peers/p1", nil)
+
+	out := buf.String()
+	for _, want := range []string{"method=GET", "path=/peers/p1", "status=404", "latency="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in log output, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "token") {
+		t.Errorf("expected API token not to be logged, got %q", out)
+	}
+}
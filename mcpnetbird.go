@@ -23,12 +23,17 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -160,10 +165,23 @@ func ValidateConfig(cfg *Config) error {
 	return nil
 }
 
+// APIError is returned by NetbirdClient when the API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
 // NetbirdClient provides methods to interact with the Netbird API
 type NetbirdClient struct {
 	baseURL string
 	client  *http.Client
+	cache   *ResponseCache
+	// uncached skips cache lookups; responses and writes still update the cache
+	uncached bool
 }
 
 // Single global variable for testing
@@ -172,6 +190,17 @@ var TestNetbirdClient *NetbirdClient
 // NewNetbirdClient creates a new NetbirdClient with configuration from context.
 // If context doesn't contain API host, falls back to environment variable for backward compatibility.
 func NewNetbirdClient(ctx context.Context) *NetbirdClient {
+	baseURL := "https://" + resolveAPIHost(ctx) + netbirdAPIPath
+	return &NetbirdClient{
+		baseURL: baseURL,
+		client:  &http.Client{},
+		cache:   GlobalResponseCache,
+	}
+}
+
+// resolveAPIHost returns the API host from the context, falling back to the
+// environment variable and then the default host
+func resolveAPIHost(ctx context.Context) string {
 	// Try to get host from context first
 	host := NetbirdAPIHostFromContext(ctx)
 	
@@ -184,28 +213,60 @@ func NewNetbirdClient(ctx context.Context) *NetbirdClient {
 	if host == "" {
 		host = defaultNetbirdHost
 	}
-
-	baseURL := "https://" + host + netbirdAPIPath
-	return &NetbirdClient{
-		baseURL: baseURL,
-		client:  &http.Client{},
-	}
+	return host
 }
 
 func NewNetbirdClientWithBaseURL(baseURL string) *NetbirdClient {
 	return &NetbirdClient{
 		baseURL: baseURL,
 		client:  &http.Client{},
+		cache:   GlobalResponseCache,
 	}
 }
 
-// do performs an HTTP request to the Netbird API
+// BaseURL returns the URL of the Netbird API the client talks to
+func (c *NetbirdClient) BaseURL() string {
+	return c.baseURL
+}
+
+// Uncached returns a copy of the client whose reads always reach the API, for
+// reads that must see the current state. Its responses still refresh the cache
+// and its writes still invalidate it.
+func (c *NetbirdClient) Uncached() *NetbirdClient {
+	uncached := *c
+	uncached.uncached = true
+	return &uncached
+}
+
+// do performs an HTTP request to the Netbird API within a client span
 func (c *NetbirdClient) do(ctx context.Context, method, path string, body, v any) error {
+	ctx, span := startAPISpan(ctx, method, path)
+	err := c.doRequest(ctx, method, path, body, v)
+	endAPISpan(span, err)
+	return err
+}
+
+// doRequest performs an HTTP request to the Netbird API
+func (c *NetbirdClient) doRequest(ctx context.Context, method, path string, body, v any) error {
+	span := trace.SpanFromContext(ctx)
 	token := NetbirdAPIKeyFromContext(ctx)
 	if token == "" {
 		return fmt.Errorf("netbird API token not found in context")
 	}
 
+	// Serve GETs from the cache when enabled; any write invalidates the affected collections
+	if c.cache != nil {
+		if method != http.MethodGet {
+			defer c.cache.invalidate(c.baseURL, path)
+		} else if !c.uncached {
+			if data, ok := c.cache.get(token, c.baseURL+path); ok {
+				slog.DebugContext(ctx, "Netbird API request served from cache", "method", method, "path", path)
+				span.SetAttributes(attribute.Bool("netbird.cache_hit", true))
+				return decodeResponse(data, v)
+			}
+		}
+	}
+
 	var bodyReader io.Reader
 	if body != nil {
 		bodyBytes, err := json.Marshal(body)
@@ -224,16 +285,33 @@ func (c *NetbirdClient) do(ctx context.Context, method, path string, body, v any
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
+	start := time.Now()
 	resp, err := c.client.Do(req)
+	latency := time.Since(start)
 	if err != nil {
+		slog.DebugContext(ctx, "Netbird API request failed", "method", method, "path", path, "latency", latency, "error", err)
+		GlobalMetrics.observeAPIRequest(method, path, 0, latency)
 		return fmt.Errorf("making request: %w", err)
 	}
 	defer resp.Body.Close()
+	slog.DebugContext(ctx, "Netbird API request", "method", method, "path", path, "status", resp.StatusCode, "latency", latency)
+	GlobalMetrics.observeAPIRequest(method, path, resp.StatusCode, latency)
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return &APIError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if c.cache != nil && method == http.MethodGet {
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("reading response: %w", err)
+		}
+		c.cache.set(token, c.baseURL+path, data)
+		return decodeResponse(data, v)
 	}
 
 	if v != nil && resp.StatusCode != http.StatusNoContent {
@@ -245,6 +323,17 @@ func (c *NetbirdClient) do(ctx context.Context, method, path string, body, v any
 	return nil
 }
 
+// decodeResponse decodes a buffered response body into v
+func decodeResponse(data []byte, v any) error {
+	if v == nil || len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	return nil
+}
+
 // Get performs a GET request to the Netbird API
 func (c *NetbirdClient) Get(ctx context.Context, path string, v any) error {
 	return c.do(ctx, http.MethodGet, path, nil, v)
@@ -273,7 +362,7 @@ type netbirdAPIHostKey struct{}
 var ExtractNetbirdInfoFromEnv server.StdioContextFunc = func(ctx context.Context) context.Context {
 	// Ensure GlobalConfigLoader is initialized
 	if GlobalConfigLoader == nil {
-		log.Printf("Warning: GlobalConfigLoader not initialized, using empty CLI arguments")
+		slog.Warn("GlobalConfigLoader not initialized, using empty CLI arguments", "transport", "stdio")
 		GlobalConfigLoader = NewConfigLoader("", "")
 	}
 
@@ -281,17 +370,17 @@ var ExtractNetbirdInfoFromEnv server.StdioContextFunc = func(ctx context.Context
 	// No HTTP headers in stdio mode (httpToken and httpHost are empty strings)
 	cfg, err := GlobalConfigLoader.LoadConfig("", "")
 	if err != nil {
-		log.Printf("Warning: Failed to load configuration: %v", err)
+		slog.Warn("Failed to load configuration", "transport", "stdio", "error", err)
 		return WithNetbirdConfig(ctx, "", "")
 	}
 
 	// Validate the configuration
 	if err := ValidateConfig(cfg); err != nil {
-		log.Printf("Warning: Configuration validation failed: %v", err)
+		slog.Warn("Configuration validation failed", "transport", "stdio", "error", err)
 		// Still inject the configuration even if validation fails, to maintain backward compatibility
 		// The actual API calls will fail with authentication errors if the token is invalid
 	} else {
-		log.Printf("Successfully loaded and validated Netbird configuration from CLI arguments and environment variables")
+		slog.Info("Loaded and validated Netbird configuration from CLI arguments and environment variables", "transport", "stdio")
 	}
 
 	// Inject validated configuration into context
@@ -303,7 +392,7 @@ var ExtractNetbirdInfoFromEnv server.StdioContextFunc = func(ctx context.Context
 var ExtractNetbirdInfoFromEnvSSE server.SSEContextFunc = func(ctx context.Context, req *http.Request) context.Context {
 	// Ensure GlobalConfigLoader is initialized
 	if GlobalConfigLoader == nil {
-		log.Printf("SSE MODE - Warning: GlobalConfigLoader not initialized, using empty CLI arguments")
+		slog.Warn("GlobalConfigLoader not initialized, using empty CLI arguments", "transport", "sse")
 		GlobalConfigLoader = NewConfigLoader("", "")
 	}
 
@@ -314,7 +403,7 @@ var ExtractNetbirdInfoFromEnvSSE server.SSEContextFunc = func(ctx context.Contex
 	// Load configuration from CLI arguments, HTTP headers, and environment variables
 	cfg, err := GlobalConfigLoader.LoadConfig(httpToken, httpHost)
 	if err != nil {
-		log.Printf("SSE MODE - Failed to load configuration: %v", err)
+		slog.Warn("Failed to load configuration", "transport", "sse", "error", err)
 		return WithNetbirdConfig(ctx, "", "")
 	}
 
@@ -322,16 +411,16 @@ var ExtractNetbirdInfoFromEnvSSE server.SSEContextFunc = func(ctx context.Contex
 	if err := ValidateConfig(cfg); err != nil {
 		// Return HTTP 401 for missing API token
 		if cfg.APIToken == "" || strings.TrimSpace(cfg.APIToken) == "" {
-			log.Printf("SSE MODE - Missing API token from all sources")
+			slog.Warn("Missing API token from all sources", "transport", "sse")
 			// Note: We can't directly return HTTP 401 from here, but we inject empty token
 			// which will cause authentication to fail at the API call level
 			return WithNetbirdConfig(ctx, "", "")
 		}
-		log.Printf("SSE MODE - Configuration validation failed: %v", err)
+		slog.Warn("Configuration validation failed", "transport", "sse", "error", err)
 		// For other validation errors, still inject the configuration
 		// The actual API calls will fail with appropriate errors
 	} else {
-		log.Printf("SSE MODE - Successfully loaded and validated Netbird configuration")
+		slog.Debug("Loaded and validated Netbird configuration", "transport", "sse")
 	}
 
 	// Inject validated configuration into context
@@ -394,5 +483,7 @@ var ComposedStdioContextFunc = ComposeStdioContextFuncs(
 
 // ComposedSSEContextFunc is an SSEContextFunc that comprises all predefined SSEContextFuncs.
 var ComposedSSEContextFunc = ComposeSSEContextFuncs(
+	ExtractTraceContextSSE,
 	ExtractNetbirdInfoFromEnvSSE,
+	ExtractCallerIdentitySSE,
 )
@@ -0,0 +1,183 @@
+package mcpnetbird
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "mcp_netbird"
+
+// apiPathSegments are the fixed segments of Netbird API paths. Any other
+// segment is an object ID and is replaced with {id} in metric labels.
+var apiPathSegments = map[string]bool{
+	"accounts": true, "current": true, "dns": true, "events": true, "groups": true,
+	"ingress": true, "invite": true, "nameservers": true, "networks": true, "peers": true,
+	"policies": true, "ports": true, "posture-checks": true, "resources": true, "routers": true,
+	"routes": true, "settings": true, "setup-keys": true, "tokens": true, "users": true,
+}
+
+// Metrics holds the Prometheus collectors served on /metrics in SSE mode
+type Metrics struct {
+	registry     *prometheus.Registry
+	toolCalls    *prometheus.CounterVec
+	toolDuration *prometheus.HistogramVec
+	apiRequests  *prometheus.CounterVec
+	apiDuration  *prometheus.HistogramVec
+	sseSessions  prometheus.Gauge
+}
+
+// GlobalMetrics records tool calls and API requests. It is nil (metrics disabled) unless enabled at startup.
+var GlobalMetrics *Metrics
+
+// NewMetrics creates and registers the server's collectors, including Go runtime
+// and process metrics and the hit ratio of GlobalResponseCache
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "tool_calls_total",
+			Help:      "Tool calls by tool and result status (ok or error).",
+		}, []string{"tool", "status"}),
+		toolDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "tool_call_duration_seconds",
+			Help:      "Tool call latency by tool.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"tool"}),
+		apiRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "api_requests_total",
+			Help:      "Netbird API requests by method, path template and status code (error when no response was received).",
+		}, []string{"method", "path", "status"}),
+		apiDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Netbird API request latency by method and path template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "path"}),
+		sseSessions: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "sse_sessions_active",
+			Help:      "Open SSE connections.",
+		}),
+	}
+
+	cacheStats := func() CacheStats {
+		if GlobalResponseCache == nil {
+			return CacheStats{}
+		}
+		return GlobalResponseCache.Stats()
+	}
+
+	m.registry.MustRegister(
+		m.toolCalls, m.toolDuration, m.apiRequests, m.apiDuration, m.sseSessions,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_hits_total",
+			Help:      "GET requests served from the response cache.",
+		}, func() float64 { return float64(cacheStats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_misses_total",
+			Help:      "GET requests not found in the response cache.",
+		}, func() float64 { return float64(cacheStats().Misses) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_hit_ratio",
+			Help:      "Share of cacheable GET requests served from the response cache since startup.",
+		}, func() float64 {
+			stats := cacheStats()
+			if stats.Hits+stats.Misses == 0 {
+				return 0
+			}
+			return float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "cache_entries",
+			Help:      "Responses currently held in the response cache.",
+		}, func() float64 { return float64(cacheStats().Entries) }),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// TrackSSESessions wraps the SSE endpoint handler so open connections are counted
+func (m *Metrics) TrackSSESessions(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.sseSessions.Inc()
+		defer m.sseSessions.Dec()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// observeToolCall records a tool call. It is a no-op on a nil Metrics.
+func (m *Metrics) observeToolCall(tool string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.toolCalls.WithLabelValues(tool, status).Inc()
+	m.toolDuration.WithLabelValues(tool).Observe(duration.Seconds())
+}
+
+// observeAPIRequest records a Netbird API request; status 0 means no response
+// was received. It is a no-op on a nil Metrics.
+func (m *Metrics) observeAPIRequest(method, path string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	template := apiPathTemplate(path)
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	m.apiRequests.WithLabelValues(method, template, statusLabel).Inc()
+	m.apiDuration.WithLabelValues(method, template).Observe(duration.Seconds())
+}
+
+// apiPathTemplate replaces object IDs in an API path with {id} and drops the
+// query string, so that label cardinality stays bounded
+func apiPathTemplate(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		if segment != "" && !apiPathSegments[segment] {
+			segments[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// instrumentToolHandler wraps a tool handler so each call is recorded in GlobalMetrics
+func instrumentToolHandler(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		metrics := GlobalMetrics
+		if metrics == nil {
+			return handler(ctx, request)
+		}
+		start := time.Now()
+		result, err := handler(ctx, request)
+		metrics.observeToolCall(name, time.Since(start), callError(result, err))
+		return result, err
+	}
+}
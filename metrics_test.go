@@ -0,0 +1,140 @@
+package mcpnetbird
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func scrapeMetrics(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestAPIPathTemplate(t *testing.T) {
+	cases := map[string]string{
+		"/peers":                          "/peers",
+		"/peers/cq3k2/ingress/ports/a1":   "/peers/{id}/ingress/ports/{id}",
+		"/networks/n1/resources/r1":       "/networks/{id}/resources/{id}",
+		"/dns/nameservers/ns1":            "/dns/nameservers/{id}",
+		"/users/current":                  "/users/current",
+		"/accounts/acc1?include=settings": "/accounts/{id}",
+		"/users/u1/tokens/t1":             "/users/{id}/tokens/{id}",
+		"/posture-checks/pc1":             "/posture-checks/{id}",
+		"/setup-keys/k1":                  "/setup-keys/{id}",
+	}
+	for path, want := range cases {
+		if got := apiPathTemplate(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestMetrics_RecordsToolCallsAndAPIRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/peers/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"p1"}`))
+	}))
+	defer server.Close()
+
+	GlobalMetrics = NewMetrics()
+	defer func() { GlobalMetrics = nil }()
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	tool := MustTool("get_thing", "test tool", ReadOnlyTool, func(ctx context.Context, args auditTestParams) (map[string]any, error) {
+		var out map[string]any
+		if err := client.Get(ctx, "/peers/"+args.Name, &out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	})
+
+	ctx := WithNetbirdAPIKey(context.Background(), "token")
+	for _, name := range []string{"p1", "p2", "missing"} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{"name": name}
+		_, _ = tool.Handler(ctx, request)
+	}
+
+	// Calls rejected with an error result count as errors too
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"name": 5}
+	_, _ = tool.Handler(ctx, request)
+
+	out := scrapeMetrics(t, GlobalMetrics)
+	for _, want := range []string{
+		`mcp_netbird_tool_calls_total{status="ok",tool="get_thing"} 2`,
+		`mcp_netbird_tool_calls_total{status="error",tool="get_thing"} 2`,
+		`mcp_netbird_tool_call_duration_seconds_count{tool="get_thing"} 4`,
+		`mcp_netbird_api_requests_total{method="GET",path="/peers/{id}",status="200"} 2`,
+		`mcp_netbird_api_requests_total{method="GET",path="/peers/{id}",status="404"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+}
+
+func TestMetrics_CacheAndSessions(t *testing.T) {
+	m := NewMetrics()
+
+	original := GlobalResponseCache
+	GlobalResponseCache = NewResponseCache(time.Minute)
+	defer func() { GlobalResponseCache = original }()
+
+	GlobalResponseCache.set("token", "/peers", []byte("[]"))
+	GlobalResponseCache.get("token", "/peers")
+	GlobalResponseCache.get("token", "/groups")
+	GlobalResponseCache.get("token", "/routes")
+	GlobalResponseCache.get("token", "/peers")
+
+	opened := make(chan struct{})
+	release := make(chan struct{})
+	handler := m.TrackSSESessions(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(opened)
+		<-release
+	}))
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/sse", nil))
+		close(done)
+	}()
+	<-opened
+
+	out := scrapeMetrics(t, m)
+	for _, want := range []string{
+		"mcp_netbird_cache_hit_ratio 0.5",
+		"mcp_netbird_cache_hits_total 2",
+		"mcp_netbird_sse_sessions_active 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in metrics output", want)
+		}
+	}
+
+	close(release)
+	<-done
+	if out := scrapeMetrics(t, m); !strings.Contains(out, "mcp_netbird_sse_sessions_active 0") {
+		t.Error("expected session gauge to drop when the connection closes")
+	}
+}
+
+func TestMetrics_NilIsNoop(t *testing.T) {
+	var m *Metrics
+	m.observeToolCall("tool", time.Second, errors.New("boom"))
+	m.observeAPIRequest("GET", "/peers", 200, time.Second)
+}
@@ -29,15 +29,64 @@ type Tool struct {
 // Tool's Tool and Handler fields, allowing you to add the tool in a single
 // statement:
 //
-//	mcpnetbird.MustTool(name, description, toolHandler).Register(server)
+//	mcpnetbird.MustTool(name, description, behavior, toolHandler).Register(server)
+//
+// If ToolFilter is set, the tool is registered as returned by the filter, or
+// skipped when the filter returns false.
 func (t *Tool) Register(mcp *server.MCPServer) {
-	mcp.AddTool(t.Tool, t.Handler)
+	tool := t.Tool
+	if ToolFilter != nil {
+		var ok bool
+		if tool, ok = ToolFilter(tool); !ok {
+			return
+		}
+	}
+	mcp.AddTool(tool, t.Handler)
+}
+
+// ToolFilter, when set, is applied to every tool by Register. It may amend the
+// tool definition, for example its description, or return false to hide the tool.
+var ToolFilter func(tool mcp.Tool) (mcp.Tool, bool)
+
+// ToolBehavior declares how a tool affects the Netbird account. It is advertised
+// to clients as MCP tool annotations so they can decide which calls need approval.
+type ToolBehavior struct {
+	ReadOnly    bool // the tool doesn't change anything
+	Destructive bool // the tool may overwrite or delete existing objects
+	Idempotent  bool // repeating a call with the same arguments has no further effect
+	OpenWorld   bool // the tool reaches beyond the Netbird account
+}
+
+// Behaviors of the Netbird tools
+var (
+	// ReadOnlyTool only reads from the Netbird API
+	ReadOnlyTool = ToolBehavior{ReadOnly: true, Idempotent: true}
+	// AdditiveTool creates new objects; repeating a call creates another one
+	AdditiveTool = ToolBehavior{}
+	// AppendTool adds to an existing object without removing anything; repeating
+	// a call has no further effect
+	AppendTool = ToolBehavior{Idempotent: true}
+	// UpdateTool overwrites an existing object with the given values
+	UpdateTool = ToolBehavior{Destructive: true, Idempotent: true}
+	// DeleteTool deletes an existing object
+	DeleteTool = ToolBehavior{Destructive: true, Idempotent: true}
+	// BulkTool changes many objects at once, with results depending on their current state
+	BulkTool = ToolBehavior{Destructive: true}
+)
+
+func (b ToolBehavior) annotation() mcp.ToolAnnotation {
+	return mcp.ToolAnnotation{
+		ReadOnlyHint:    mcp.ToBoolPtr(b.ReadOnly),
+		DestructiveHint: mcp.ToBoolPtr(b.Destructive),
+		IdempotentHint:  mcp.ToBoolPtr(b.Idempotent),
+		OpenWorldHint:   mcp.ToBoolPtr(b.OpenWorld),
+	}
 }
 
-// MustTool creates a new Tool from the given name, description, and toolHandler.
+// MustTool creates a new Tool from the given name, description, behavior and toolHandler.
 // It panics if the tool cannot be created.
-func MustTool[T any, R any](name, description string, toolHandler ToolHandlerFunc[T, R]) Tool {
-	tool, handler, err := ConvertTool(name, description, toolHandler)
+func MustTool[T any, R any](name, description string, behavior ToolBehavior, toolHandler ToolHandlerFunc[T, R]) Tool {
+	tool, handler, err := ConvertTool(name, description, behavior, toolHandler)
 	if err != nil {
 		panic(err)
 	}
@@ -53,7 +102,17 @@ type ToolHandlerFunc[T any, R any] = func(ctx context.Context, request T) (R, er
 // to be used as the parameters for the tool. The second argument must not be a pointer,
 // should be marshalable to JSON, and the fields should have a `jsonschema` tag with the
 // description of the parameter.
-func ConvertTool[T any, R any](name, description string, toolHandler ToolHandlerFunc[T, R]) (mcp.Tool, server.ToolHandlerFunc, error) {
+//
+// Arguments are validated against the schema generated from that struct before the
+// handler runs; a call with missing required fields, values of the wrong type or
+// outside an enum, or unknown properties fails with a result flagged IsError whose
+// structured content is an *ArgumentError listing every problem, so clients can
+// correct the call.
+//
+// The tool is annotated with behavior. When the handler returns a struct, or a
+// pointer to one, the tool also gets an output schema generated from that type and
+// its results carry the value as structured content.
+func ConvertTool[T any, R any](name, description string, behavior ToolBehavior, toolHandler ToolHandlerFunc[T, R]) (mcp.Tool, server.ToolHandlerFunc, error) {
 	zero := mcp.Tool{}
 	handlerValue := reflect.ValueOf(toolHandler)
 	handlerType := handlerValue.Type()
@@ -79,13 +138,30 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		return zero, nil, errors.New("tool handler second argument must be a struct")
 	}
 
+	jsonSchema := createJSONSchemaFromHandler(toolHandler)
+	outputSchema, hasOutputSchema := createOutputSchema(handlerType.Out(0))
+
 	handler := func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 
-		s, err := json.Marshal(request.Params.Arguments)
+		arguments := request.Params.Arguments
+		if arguments == nil {
+			arguments = map[string]any{}
+		}
+		s, err := json.Marshal(arguments)
 		if err != nil {
 			return nil, fmt.Errorf("marshal args: %w", err)
 		}
 
+		// Check the arguments against the schema first, so that every problem is
+		// reported at once instead of the first one encoding/json runs into
+		var decodedArgs any
+		if err := json.Unmarshal(s, &decodedArgs); err != nil {
+			return nil, fmt.Errorf("unmarshal args: %s", err)
+		}
+		if problems := validateArguments(jsonSchema, decodedArgs); len(problems) > 0 {
+			return argumentErrorResult(&ArgumentError{Tool: name, Problems: problems}), nil
+		}
+
 		unmarshaledArgs := reflect.New(argType).Interface()
 		if err := json.Unmarshal([]byte(s), unmarshaledArgs); err != nil {
 			return nil, fmt.Errorf("unmarshal args: %s", err)
@@ -169,10 +245,12 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 			return nil, fmt.Errorf("failed to marshal return value: %s", err)
 		}
 
+		if hasOutputSchema {
+			return mcp.NewToolResultStructured(returnVal, string(jsonBytes)), nil
+		}
 		return mcp.NewToolResultText(string(jsonBytes)), nil
 	}
 
-	jsonSchema := createJSONSchemaFromHandler(toolHandler)
 	properties := make(map[string]any, jsonSchema.Properties.Len())
 	for pair := jsonSchema.Properties.Oldest(); pair != nil; pair = pair.Next() {
 		properties[pair.Key] = pair.Value
@@ -183,11 +261,16 @@ func ConvertTool[T any, R any](name, description string, toolHandler ToolHandler
 		Required:   jsonSchema.Required,
 	}
 
-	return mcp.Tool{
+	tool := mcp.Tool{
 		Name:        name,
 		Description: description,
 		InputSchema: inputSchema,
-	}, handler, nil
+		Annotations: behavior.annotation(),
+	}
+	if hasOutputSchema {
+		tool.OutputSchema = outputSchema
+	}
+	return tool, traceToolHandler(name, auditHandler(name, instrumentToolHandler(name, handler))), nil
 }
 
 // Creates a full JSON schema from a user provided handler by introspecting the arguments
@@ -199,12 +282,61 @@ func createJSONSchemaFromHandler(handler any) *jsonschema.Schema {
 	return inputSchema
 }
 
+// createOutputSchema generates the output schema of a tool from its handler's
+// return type. Only structs and pointers to structs get one: MCP output schemas
+// must describe an object, and tools returning lists, maps or values of varying
+// shape have nothing more specific to declare.
+func createOutputSchema(returnType reflect.Type) (mcp.ToolOutputSchema, bool) {
+	for returnType.Kind() == reflect.Ptr {
+		returnType = returnType.Elem()
+	}
+	if returnType.Kind() != reflect.Struct || returnType == reflect.TypeOf(mcp.CallToolResult{}) {
+		return mcp.ToolOutputSchema{}, false
+	}
+
+	jsonSchema := outputSchemaReflector.ReflectFromType(returnType)
+	removeConstraints(jsonSchema)
+	properties := make(map[string]any, jsonSchema.Properties.Len())
+	for pair := jsonSchema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+		properties[pair.Key] = pair.Value
+	}
+	return mcp.ToolOutputSchema{
+		Type:       "object",
+		Properties: properties,
+		Required:   jsonSchema.Required,
+	}, true
+}
+
+// removeConstraints clears the enum, pattern and bound constraints of a schema and
+// its subschemas. Argument types are often reused for results, where the values come
+// from the Netbird API and a client validating them against the argument rules would
+// reject values the API is free to return.
+func removeConstraints(schema *jsonschema.Schema) {
+	if schema == nil {
+		return
+	}
+	schema.Enum = nil
+	schema.Pattern = ""
+	schema.Minimum, schema.Maximum = "", ""
+	schema.MinLength, schema.MaxLength = nil, nil
+	schema.MinItems, schema.MaxItems = nil, nil
+	removeConstraints(schema.Items)
+	if schema.AdditionalProperties != jsonschema.TrueSchema && schema.AdditionalProperties != jsonschema.FalseSchema {
+		removeConstraints(schema.AdditionalProperties)
+	}
+	if schema.Properties != nil {
+		for pair := schema.Properties.Oldest(); pair != nil; pair = pair.Next() {
+			removeConstraints(pair.Value)
+		}
+	}
+}
+
 var (
 	jsonSchemaReflector = jsonschema.Reflector{
 		BaseSchemaID:               "",
 		Anonymous:                  true,
 		AssignAnchor:               false,
-		AllowAdditionalProperties:  true,
+		AllowAdditionalProperties:  false,
 		RequiredFromJSONSchemaTags: true,
 		DoNotReference:             true,
 		ExpandedStruct:             true,
@@ -217,4 +349,29 @@ var (
 		AdditionalFields:           nil,
 		CommentMap:                 nil,
 	}
+
+	// outputSchemaReflector describes tool results. They aren't validated, so their
+	// schemas stay open to fields the Netbird API adds.
+	outputSchemaReflector = func() jsonschema.Reflector {
+		r := jsonSchemaReflector
+		r.AllowAdditionalProperties = true
+		return r
+	}()
 )
+
+// callError is the error of a tool call: err, or for a result flagged IsError
+// the error it reports
+func callError(result *mcp.CallToolResult, err error) error {
+	if err != nil || result == nil || !result.IsError {
+		return err
+	}
+	if argErr, ok := result.StructuredContent.(*ArgumentError); ok {
+		return argErr
+	}
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return errors.New(text.Text)
+		}
+	}
+	return errors.New("tool call failed")
+}
@@ -76,6 +76,7 @@ func getNetbirdAccount(ctx context.Context, args GetNetbirdAccountParams) (*Netb
 var GetNetbirdAccount = mcpnetbird.MustTool(
 	"get_netbird_account",
 	"Get Netbird account information",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdAccount,
 )
 
@@ -103,6 +104,7 @@ func updateNetbirdAccount(ctx context.Context, args UpdateNetbirdAccountParams)
 var UpdateNetbirdAccount = mcpnetbird.MustTool(
 	"update_netbird_account",
 	"Update Netbird account settings",
+	mcpnetbird.UpdateTool,
 	updateNetbirdAccount,
 )
 
@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"context"
+	"sync"
+)
+
+// BulkConcurrency bounds how many objects bulk helpers such as
+// ReplaceGroupInPolicies and DeleteGroupForce fetch and update at once.
+var BulkConcurrency = 8
+
+// runBounded calls fn for every index in [0, n) using at most limit concurrent
+// goroutines and waits for them to finish. Once ctx is cancelled no further calls
+// are started; the indexes that were never started are returned.
+func runBounded(ctx context.Context, n, limit int, fn func(ctx context.Context, i int)) []int {
+	if limit < 1 {
+		limit = 1
+	}
+
+	var skipped []int
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			skipped = append(skipped, i)
+			continue
+		case sem <- struct{}{}:
+		}
+		// Both cases may be ready at once; don't start work after cancellation
+		if ctx.Err() != nil {
+			<-sem
+			skipped = append(skipped, i)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(ctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+	return skipped
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func TestRunBounded_LimitsConcurrency(t *testing.T) {
+	var running, peak int32
+	var calls int32
+
+	skipped := runBounded(context.Background(), 50, 4, func(ctx context.Context, i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+	})
+
+	if len(skipped) != 0 {
+		t.Errorf("expected nothing skipped, got %v", skipped)
+	}
+	if calls != 50 {
+		t.Errorf("expected 50 calls, got %d", calls)
+	}
+	if peak > 4 {
+		t.Errorf("expected at most 4 concurrent calls, got %d", peak)
+	}
+}
+
+func TestRunBounded_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+
+	skipped := runBounded(ctx, 20, 1, func(ctx context.Context, i int) {
+		if atomic.AddInt32(&calls, 1) == 3 {
+			cancel()
+		}
+	})
+
+	if calls != 3 {
+		t.Errorf("expected 3 calls before cancellation, got %d", calls)
+	}
+	if len(skipped) != 17 {
+		t.Errorf("expected 17 skipped indexes, got %d", len(skipped))
+	}
+}
+
+// createSlowPolicyServer serves n policies referencing groupID and counts PUTs and group deletes
+func createSlowPolicyServer(n int, groupID string, delay time.Duration) (*httptest.Server, *int32, *int32) {
+	policies := make([]NetbirdPolicy, n)
+	for i := range policies {
+		policies[i] = NetbirdPolicy{
+			ID:      fmt.Sprintf("policy-%d", i),
+			Name:    fmt.Sprintf("Policy %d", i),
+			Enabled: true,
+			Rules: []NetbirdPolicyRule{{
+				ID:           fmt.Sprintf("rule-%d", i),
+				Name:         "rule",
+				Enabled:      true,
+				Action:       "accept",
+				Protocol:     "all",
+				Sources:      []NetbirdPeerGroup{{ID: groupID}, {ID: "other"}},
+				Destinations: []NetbirdPeerGroup{{ID: "dest"}},
+			}},
+		}
+	}
+	byID := make(map[string]NetbirdPolicy)
+	for _, p := range policies {
+		byID[p.ID] = p
+	}
+
+	var puts, groupDeletes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/policies":
+			_ = json.NewEncoder(w).Encode(policies)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/policies/"):
+			time.Sleep(delay)
+			_ = json.NewEncoder(w).Encode(byID[strings.TrimPrefix(r.URL.Path, "/policies/")])
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/policies/"):
+			time.Sleep(delay)
+			atomic.AddInt32(&puts, 1)
+			_, _ = w.Write([]byte("{}"))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/groups/"):
+			_ = json.NewEncoder(w).Encode(NetbirdGroup{ID: groupID, Name: groupID})
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/groups/"):
+			atomic.AddInt32(&groupDeletes, 1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, &puts, &groupDeletes
+}
+
+func TestReplaceGroupInPolicies_Concurrent(t *testing.T) {
+	server, puts, _ := createSlowPolicyServer(40, "old-group", 20*time.Millisecond)
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	start := time.Now()
+	result, err := ReplaceGroupInPolicies(ctx, "old-group", "new-group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if len(result.UpdatedPolicyIDs) != 40 || *puts != 40 {
+		t.Fatalf("expected 40 updated policies, got %d (%d PUTs)", len(result.UpdatedPolicyIDs), *puts)
+	}
+	// Results keep the order policies were discovered in
+	for i, id := range result.UpdatedPolicyIDs {
+		if id != fmt.Sprintf("policy-%d", i) {
+			t.Fatalf("expected policy-%d at position %d, got %s", i, i, id)
+		}
+	}
+	// 40 sequential GET+PUT pairs would take at least 1.6s
+	if elapsed > time.Second {
+		t.Errorf("expected concurrent processing, took %v", elapsed)
+	}
+}
+
+func TestDeleteGroupForce_CancelledKeepsGroup(t *testing.T) {
+	server, _, groupDeletes := createSlowPolicyServer(20, "target-group", 20*time.Millisecond)
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx, cancel := context.WithTimeout(mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token"), 30*time.Millisecond)
+	defer cancel()
+
+	result, err := DeleteGroupForce(ctx, "target-group")
+	if err == nil {
+		t.Fatal("expected error after cancellation")
+	}
+	if result == nil || result.Deleted {
+		t.Fatalf("expected group not to be deleted, got %+v", result)
+	}
+	if len(result.Errors) == 0 {
+		t.Error("expected per-policy errors for skipped or cancelled policies")
+	}
+	if *groupDeletes != 0 {
+		t.Errorf("expected no group delete request, got %d", *groupDeletes)
+	}
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"context"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type ClearNetbirdCacheParams struct {
+	All bool `json:"all,omitempty" jsonschema:"description=Clear cached responses for every API token instead of only the current one"`
+}
+
+func clearNetbirdCache(ctx context.Context, args ClearNetbirdCacheParams) (map[string]interface{}, error) {
+	cache := mcpnetbird.GlobalResponseCache
+	if cache == nil {
+		return map[string]interface{}{"status": "disabled"}, nil
+	}
+
+	removed := 0
+	if args.All {
+		removed = cache.Stats().Entries
+		cache.ClearAll()
+	} else {
+		removed = cache.Clear(mcpnetbird.NetbirdAPIKeyFromContext(ctx))
+	}
+
+	return map[string]interface{}{
+		"status":          "cleared",
+		"entries_removed": removed,
+		"stats":           cache.Stats(),
+	}, nil
+}
+
+var ClearNetbirdCache = mcpnetbird.MustTool(
+	"clear_netbird_cache",
+	"Clear cached Netbird API responses so the next calls fetch fresh data. Only needed when changes were made outside this server; writes through this server invalidate the cache automatically.",
+	mcpnetbird.ToolBehavior{Idempotent: true},
+	clearNetbirdCache,
+)
+
+func AddNetbirdCacheTools(mcp *server.MCPServer) {
+	ClearNetbirdCache.Register(mcp)
+}
@@ -0,0 +1,183 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+// RequireConfirmation makes destructive tools return an impact summary and a
+// confirmation token on the first call and only act on a second call carrying
+// that token. It can be turned off at startup for unattended automation.
+var RequireConfirmation = true
+
+// ConfirmationTTL is how long a confirmation token stays valid
+var ConfirmationTTL = 2 * time.Minute
+
+// Confirmation holds the confirmation token argument shared by destructive
+// tools. Embed it in a tool's params struct and wrap the handler with
+// withConfirmation to enable the two-phase protocol.
+type Confirmation struct {
+	ConfirmationToken string `json:"confirmation_token,omitempty" jsonschema:"description=Token returned by a previous call to this tool. Omit it to get an impact summary and a token; repeat the call with the same arguments and the token to execute"`
+}
+
+func (c Confirmation) confirmationToken() string {
+	return c.ConfirmationToken
+}
+
+// confirmableParams is implemented by every params struct that embeds Confirmation
+type confirmableParams interface {
+	confirmationToken() string
+}
+
+// Impact describes what a destructive call is about to change
+type Impact struct {
+	Action   string   `json:"action"`
+	Target   any      `json:"target,omitempty"`
+	Affected any      `json:"affected,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ConfirmationRequired is returned by the first call to a destructive tool
+type ConfirmationRequired struct {
+	Status            string    `json:"status"`
+	Impact            *Impact   `json:"impact"`
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	Message           string    `json:"message"`
+}
+
+type pendingConfirmation struct {
+	binding string
+	expires time.Time
+}
+
+type confirmationStore struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+var confirmations = &confirmationStore{pending: make(map[string]pendingConfirmation)}
+
+// withConfirmation wraps a destructive handler in the two-phase protocol. Without
+// a token, describe is called and its impact is returned with a new token bound
+// to the tool, the arguments and the API token. With a valid token, run is called
+// and the token is consumed.
+func withConfirmation[P confirmableParams, R any](describe func(context.Context, P) (*Impact, error), run func(context.Context, P) (R, error)) func(context.Context, P) (any, error) {
+	return func(ctx context.Context, args P) (any, error) {
+		if !RequireConfirmation {
+			return run(ctx, args)
+		}
+
+		binding, err := confirmationBinding(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+
+		if token := args.confirmationToken(); token != "" {
+			if err := confirmations.consume(token, binding); err != nil {
+				return nil, err
+			}
+			return run(ctx, args)
+		}
+
+		impact, err := describe(ctx, args)
+		if err != nil {
+			return nil, fmt.Errorf("assessing impact: %w", err)
+		}
+		token, expires := confirmations.issue(binding)
+		return &ConfirmationRequired{
+			Status:            "confirmation_required",
+			Impact:            impact,
+			ConfirmationToken: token,
+			ExpiresAt:         expires,
+			Message:           "Nothing was changed. Review the impact and call the tool again with the same arguments and this confirmation_token to proceed.",
+		}, nil
+	}
+}
+
+// confirmationBinding identifies a call by its params type, its arguments other
+// than the confirmation token, and the API token it is made with
+func confirmationBinding(ctx context.Context, args any) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", fmt.Errorf("marshaling arguments: %w", err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("unmarshaling arguments: %w", err)
+	}
+	delete(fields, "confirmation_token")
+	// Map keys are marshaled in sorted order, so equal arguments give equal bytes
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return "", fmt.Errorf("marshaling arguments: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T\n%s\n%s", args, apiTokenHash(ctx), canonical)))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// issue creates a token for binding and drops expired ones
+func (cs *confirmationStore) issue(binding string) (string, time.Time) {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	token := "ct-" + hex.EncodeToString(id)
+	expires := time.Now().Add(ConfirmationTTL).UTC()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := time.Now()
+	for t, p := range cs.pending {
+		if now.After(p.expires) {
+			delete(cs.pending, t)
+		}
+	}
+	cs.pending[token] = pendingConfirmation{binding: binding, expires: expires}
+	return token, expires
+}
+
+// consume checks token against binding and invalidates it. A token that does
+// not match the arguments stays valid for the call it was issued for.
+func (cs *confirmationStore) consume(token, binding string) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	pending, ok := cs.pending[token]
+	if !ok || time.Now().After(pending.expires) {
+		delete(cs.pending, token)
+		return fmt.Errorf("confirmation token is invalid or expired; call the tool again without confirmation_token to get a new one")
+	}
+	if pending.binding != binding {
+		return fmt.Errorf("confirmation token was issued for different arguments; call the tool again without confirmation_token to review the impact of these arguments")
+	}
+	delete(cs.pending, token)
+	return nil
+}
+
+// describeDeletion returns a describe function for tools that delete the single
+// object at the API path built from their arguments
+func describeDeletion[P any](path func(P) string) func(context.Context, P) (*Impact, error) {
+	return func(ctx context.Context, args P) (*Impact, error) {
+		var client *mcpnetbird.NetbirdClient
+		if mcpnetbird.TestNetbirdClient != nil {
+			client = mcpnetbird.TestNetbirdClient
+		} else {
+			client = mcpnetbird.NewNetbirdClient(ctx)
+		}
+
+		var target map[string]any
+		if err := client.Get(ctx, path(args), &target); err != nil {
+			return nil, err
+		}
+		return &Impact{Action: "delete " + path(args), Target: target}, nil
+	}
+}
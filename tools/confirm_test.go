@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+// createMockPeerServer serves a single peer and counts DELETE requests
+func createMockPeerServer() (*httptest.Server, *int32) {
+	var deletes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/peers/"):
+			_, _ = w.Write([]byte(`{"id":"` + strings.TrimPrefix(r.URL.Path, "/peers/") + `","name":"laptop"}`))
+		case r.Method == http.MethodDelete:
+			atomic.AddInt32(&deletes, 1)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server, &deletes
+}
+
+var confirmedDeletePeer = withConfirmation(
+	describeDeletion(func(args DeleteNetbirdPeerParams) string { return "/peers/" + args.PeerID }),
+	deleteNetbirdPeer,
+)
+
+func TestWithConfirmation_TwoPhase(t *testing.T) {
+	server, deletes := createMockPeerServer()
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	first, err := confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	required, ok := first.(*ConfirmationRequired)
+	if !ok {
+		t.Fatalf("expected confirmation request, got %T", first)
+	}
+	if *deletes != 0 {
+		t.Fatal("expected nothing to be deleted on the first call")
+	}
+	if target, _ := required.Impact.Target.(map[string]any); target["name"] != "laptop" {
+		t.Errorf("expected impact to describe the peer, got %+v", required.Impact)
+	}
+
+	// A token is bound to the arguments it was issued for
+	_, err = confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-2", Confirmation: Confirmation{required.ConfirmationToken}})
+	if err == nil || *deletes != 0 {
+		t.Fatalf("expected token for peer-1 to be rejected for peer-2, got %v", err)
+	}
+	// and to the API token
+	other := mcpnetbird.WithNetbirdAPIKey(context.Background(), "other-token")
+	if _, err := confirmedDeletePeer(other, DeleteNetbirdPeerParams{PeerID: "peer-1", Confirmation: Confirmation{required.ConfirmationToken}}); err == nil {
+		t.Fatal("expected token to be rejected for another API token")
+	}
+
+	second, err := confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-1", Confirmation: Confirmation{required.ConfirmationToken}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result, _ := second.(map[string]string); result["status"] != "deleted" || *deletes != 1 {
+		t.Fatalf("expected peer to be deleted, got %v (%d deletes)", second, *deletes)
+	}
+
+	// Tokens are single use
+	if _, err := confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-1", Confirmation: Confirmation{required.ConfirmationToken}}); err == nil {
+		t.Error("expected reused token to be rejected")
+	}
+}
+
+func TestWithConfirmation_Expiry(t *testing.T) {
+	server, deletes := createMockPeerServer()
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	original := ConfirmationTTL
+	ConfirmationTTL = 10 * time.Millisecond
+	defer func() { ConfirmationTTL = original }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	first, err := confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	token := first.(*ConfirmationRequired).ConfirmationToken
+	if _, err := confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-1", Confirmation: Confirmation{token}}); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expected expired token error, got %v", err)
+	}
+	if *deletes != 0 {
+		t.Error("expected nothing to be deleted with an expired token")
+	}
+}
+
+func TestWithConfirmation_Disabled(t *testing.T) {
+	server, deletes := createMockPeerServer()
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	RequireConfirmation = false
+	defer func() { RequireConfirmation = true }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	if _, err := confirmedDeletePeer(ctx, DeleteNetbirdPeerParams{PeerID: "peer-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *deletes != 1 {
+		t.Errorf("expected immediate delete, got %d deletes", *deletes)
+	}
+}
+
+func TestDestructiveToolsRequireConfirmation(t *testing.T) {
+	destructive := []mcpnetbird.Tool{
+		DeleteNetbirdGroup, ReplaceGroupInPoliciesTool, MergeNetbirdGroups, RollbackNetbirdOperation,
+		DeleteNetbirdPeer, DeleteNetbirdPolicy, DeleteNetbirdNetwork, DeleteNetbirdNetworkResource,
+		DeleteNetbirdNetworkRouter, DeleteNetbirdPostureCheck, DeleteNetbirdPortAllocation,
+		DeleteNetbirdNameserver, DeleteNetbirdRoute, DeleteNetbirdSetupKey, DeleteNetbirdUser,
+		AddPeerToGroups, RemovePeerFromGroups, AttachPostureCheckToPolicies, DetachPostureCheckFromPolicies,
+	}
+	for _, tool := range destructive {
+		if _, ok := tool.Tool.InputSchema.Properties["confirmation_token"]; !ok {
+			t.Errorf("%s: expected confirmation_token argument", tool.Tool.Name)
+		}
+	}
+}
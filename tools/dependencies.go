@@ -0,0 +1,352 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// DependencyReference is an object that refers to the object whose dependencies
+// were requested. Field locates the reference, e.g. rules[0].sources.
+type DependencyReference struct {
+	Kind  string `json:"kind"`
+	ID    string `json:"id"`
+	Name  string `json:"name,omitempty"`
+	Field string `json:"field"`
+}
+
+// NetbirdDependencies lists every object that refers to an object
+type NetbirdDependencies struct {
+	Kind       string                `json:"kind"`
+	ID         string                `json:"id"`
+	Name       string                `json:"name,omitempty"`
+	References []DependencyReference `json:"references"`
+}
+
+// add records a reference from an object to the one being scanned
+func (d *NetbirdDependencies) add(kind, id, name, field string) {
+	d.References = append(d.References, DependencyReference{Kind: kind, ID: id, Name: name, Field: field})
+}
+
+// addIfContains records a reference when ids contain the scanned object's ID
+func (d *NetbirdDependencies) addIfContains(ids []string, kind, id, name, field string) {
+	if slices.Contains(ids, d.ID) {
+		d.add(kind, id, name, field)
+	}
+}
+
+// networkChildren is a network with its resources and routers
+type networkChildren struct {
+	network   NetbirdNetwork
+	resources []NetbirdNetworkResource
+	routers   []NetbirdNetworkRouter
+}
+
+// listNetworkChildren fetches every network with its resources and routers
+func listNetworkChildren(ctx context.Context, client *mcpnetbird.NetbirdClient) ([]networkChildren, error) {
+	var networks []NetbirdNetwork
+	if err := client.Get(ctx, "/networks", &networks); err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	children := make([]networkChildren, len(networks))
+	for i, network := range networks {
+		children[i].network = network
+		if err := client.Get(ctx, "/networks/"+network.ID+"/resources", &children[i].resources); err != nil {
+			return nil, fmt.Errorf("listing resources of network %s: %w", network.ID, err)
+		}
+		if err := client.Get(ctx, "/networks/"+network.ID+"/routers", &children[i].routers); err != nil {
+			return nil, fmt.Errorf("listing routers of network %s: %w", network.ID, err)
+		}
+	}
+	return children, nil
+}
+
+// groupDependencies finds the policies, routes, nameservers, network resources and
+// routers, setup keys, users, account settings and DNS settings referring to a group
+func groupDependencies(ctx context.Context, client *mcpnetbird.NetbirdClient, deps *NetbirdDependencies) error {
+	var group NetbirdGroup
+	if err := client.Get(ctx, "/groups/"+deps.ID, &group); err != nil {
+		return fmt.Errorf("fetching group %s: %w", deps.ID, err)
+	}
+	deps.Name = group.Name
+
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return fmt.Errorf("listing policies: %w", err)
+	}
+	for _, policy := range policies {
+		for i, rule := range policy.Rules {
+			if slices.ContainsFunc(rule.Sources, func(g NetbirdPeerGroup) bool { return g.ID == deps.ID }) {
+				deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].sources", i))
+			}
+			if slices.ContainsFunc(rule.Destinations, func(g NetbirdPeerGroup) bool { return g.ID == deps.ID }) {
+				deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].destinations", i))
+			}
+			if rule.AuthorizedGroups != nil {
+				if _, ok := (*rule.AuthorizedGroups)[deps.ID]; ok {
+					deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].authorized_groups", i))
+				}
+			}
+		}
+	}
+
+	var routes []NetbirdRoute
+	if err := client.Get(ctx, "/routes", &routes); err != nil {
+		return fmt.Errorf("listing routes: %w", err)
+	}
+	for _, route := range routes {
+		deps.addIfContains(route.Groups, journalKindRoute, route.ID, route.NetworkID, "groups")
+		deps.addIfContains(route.PeerGroups, journalKindRoute, route.ID, route.NetworkID, "peer_groups")
+		deps.addIfContains(route.AccessControlGroups, journalKindRoute, route.ID, route.NetworkID, "access_control_groups")
+	}
+
+	var nameservers []NetbirdNameservers
+	if err := client.Get(ctx, "/dns/nameservers", &nameservers); err != nil {
+		return fmt.Errorf("listing nameservers: %w", err)
+	}
+	for _, ns := range nameservers {
+		deps.addIfContains(ns.Groups, journalKindNameserver, ns.ID, ns.Name, "groups")
+	}
+
+	networks, err := listNetworkChildren(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		for _, resource := range network.resources {
+			if slices.ContainsFunc(resource.Groups, func(g NetbirdNetworkResourceGroup) bool { return g.ID == deps.ID }) {
+				deps.add(journalKindNetworkResource, resource.ID, resource.Name, "groups")
+			}
+		}
+		for _, router := range network.routers {
+			if router.PeerGroups != nil {
+				deps.addIfContains(*router.PeerGroups, journalKindNetworkRouter, router.ID, network.network.Name, "peer_groups")
+			}
+		}
+	}
+
+	var keys []NetbirdSetupKey
+	if err := client.Get(ctx, "/setup-keys", &keys); err != nil {
+		return fmt.Errorf("listing setup keys: %w", err)
+	}
+	for _, key := range keys {
+		deps.addIfContains(key.AutoGroups, journalKindSetupKey, key.ID, key.Name, "auto_groups")
+	}
+
+	var users []NetbirdUser
+	if err := client.Get(ctx, "/users", &users); err != nil {
+		return fmt.Errorf("listing users: %w", err)
+	}
+	for _, user := range users {
+		deps.addIfContains(user.AutoGroups, journalKindUser, user.ID, user.Email, "auto_groups")
+	}
+
+	var accounts []NetbirdAccount
+	if err := client.Get(ctx, "/accounts", &accounts); err != nil {
+		return fmt.Errorf("fetching account: %w", err)
+	}
+	for _, account := range accounts {
+		// JWT allow groups are matched against the group names in the token's claim
+		if slices.Contains(account.Settings.JWTAllowGroups, group.Name) || slices.Contains(account.Settings.JWTAllowGroups, deps.ID) {
+			deps.add(journalKindAccount, account.ID, "", "settings.jwt_allow_groups")
+		}
+		if account.Settings.Extra != nil {
+			deps.addIfContains(account.Settings.Extra.NetworkTrafficLogsGroups, journalKindAccount, account.ID, "", "settings.extra.network_traffic_logs_groups")
+		}
+	}
+
+	var dns netbirdDNSSettings
+	if err := client.Get(ctx, "/dns/settings", &dns); err != nil {
+		return fmt.Errorf("fetching DNS settings: %w", err)
+	}
+	deps.addIfContains(dns.DisabledManagementGroups, journalKindDNSSettings, "dns", "", "disabled_management_groups")
+	return nil
+}
+
+// peerDependencies finds the groups containing a peer and the policies, routes
+// and network routers referring to it
+func peerDependencies(ctx context.Context, client *mcpnetbird.NetbirdClient, deps *NetbirdDependencies) error {
+	var peer NetbirdPeer
+	if err := client.Get(ctx, "/peers/"+deps.ID, &peer); err != nil {
+		return fmt.Errorf("fetching peer %s: %w", deps.ID, err)
+	}
+	deps.Name = peer.Name
+	for _, group := range peer.Groups {
+		deps.add(journalKindGroup, group.ID, group.Name, "peers")
+	}
+
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return fmt.Errorf("listing policies: %w", err)
+	}
+	for _, policy := range policies {
+		for i, rule := range policy.Rules {
+			if rule.SourceResource != nil && rule.SourceResource.ID == deps.ID {
+				deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].sourceResource", i))
+			}
+			if rule.DestinationResource != nil && rule.DestinationResource.ID == deps.ID {
+				deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].destinationResource", i))
+			}
+		}
+	}
+
+	var routes []NetbirdRoute
+	if err := client.Get(ctx, "/routes", &routes); err != nil {
+		return fmt.Errorf("listing routes: %w", err)
+	}
+	for _, route := range routes {
+		if route.Peer == deps.ID {
+			deps.add(journalKindRoute, route.ID, route.NetworkID, "peer")
+		}
+	}
+
+	networks, err := listNetworkChildren(ctx, client)
+	if err != nil {
+		return err
+	}
+	for _, network := range networks {
+		for _, router := range network.routers {
+			if router.Peer != nil && *router.Peer == deps.ID {
+				deps.add(journalKindNetworkRouter, router.ID, network.network.Name, "peer")
+			}
+		}
+	}
+	return nil
+}
+
+// postureCheckDependencies finds the policies applying a posture check
+func postureCheckDependencies(ctx context.Context, client *mcpnetbird.NetbirdClient, deps *NetbirdDependencies) error {
+	var check NetbirdPostureCheck
+	if err := client.Get(ctx, "/posture-checks/"+deps.ID, &check); err != nil {
+		return fmt.Errorf("fetching posture check %s: %w", deps.ID, err)
+	}
+	deps.Name = check.Name
+
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return fmt.Errorf("listing policies: %w", err)
+	}
+	for _, policy := range policies {
+		deps.addIfContains(policy.SourcePostureChecks, journalKindPolicy, policy.ID, policy.Name, "source_posture_checks")
+	}
+	return nil
+}
+
+// networkDependencies finds the resources and routers of a network and the
+// policies referring to its resources
+func networkDependencies(ctx context.Context, client *mcpnetbird.NetbirdClient, deps *NetbirdDependencies) error {
+	var network NetbirdNetwork
+	if err := client.Get(ctx, "/networks/"+deps.ID, &network); err != nil {
+		return fmt.Errorf("fetching network %s: %w", deps.ID, err)
+	}
+	deps.Name = network.Name
+
+	var resources []NetbirdNetworkResource
+	if err := client.Get(ctx, "/networks/"+deps.ID+"/resources", &resources); err != nil {
+		return fmt.Errorf("listing resources of network %s: %w", deps.ID, err)
+	}
+	resourceIDs := make([]string, 0, len(resources))
+	for _, resource := range resources {
+		resourceIDs = append(resourceIDs, resource.ID)
+		deps.add(journalKindNetworkResource, resource.ID, resource.Name, "network")
+	}
+	var routers []NetbirdNetworkRouter
+	if err := client.Get(ctx, "/networks/"+deps.ID+"/routers", &routers); err != nil {
+		return fmt.Errorf("listing routers of network %s: %w", deps.ID, err)
+	}
+	for _, router := range routers {
+		deps.add(journalKindNetworkRouter, router.ID, "", "network")
+	}
+
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return fmt.Errorf("listing policies: %w", err)
+	}
+	for _, policy := range policies {
+		for i, rule := range policy.Rules {
+			if rule.SourceResource != nil && slices.Contains(resourceIDs, rule.SourceResource.ID) {
+				deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].sourceResource", i))
+			}
+			if rule.DestinationResource != nil && slices.Contains(resourceIDs, rule.DestinationResource.ID) {
+				deps.add(journalKindPolicy, policy.ID, policy.Name, fmt.Sprintf("rules[%d].destinationResource", i))
+			}
+		}
+	}
+	return nil
+}
+
+// userDependencies finds the peers a user added
+func userDependencies(ctx context.Context, client *mcpnetbird.NetbirdClient, deps *NetbirdDependencies) error {
+	var users []NetbirdUser
+	if err := client.Get(ctx, "/users", &users); err != nil {
+		return fmt.Errorf("listing users: %w", err)
+	}
+	index := slices.IndexFunc(users, func(u NetbirdUser) bool { return u.ID == deps.ID })
+	if index < 0 {
+		return fmt.Errorf("user %s not found", deps.ID)
+	}
+	deps.Name = users[index].Email
+
+	var peers []NetbirdPeer
+	if err := client.Get(ctx, "/peers", &peers); err != nil {
+		return fmt.Errorf("listing peers: %w", err)
+	}
+	for _, peer := range peers {
+		if peer.UserID == deps.ID {
+			deps.add("peer", peer.ID, peer.Name, "user_id")
+		}
+	}
+	return nil
+}
+
+// dependencyScanners find the references to each kind of object
+var dependencyScanners = map[string]func(context.Context, *mcpnetbird.NetbirdClient, *NetbirdDependencies) error{
+	"group":         groupDependencies,
+	"peer":          peerDependencies,
+	"posture_check": postureCheckDependencies,
+	"network":       networkDependencies,
+	"user":          userDependencies,
+}
+
+// FindDependencies returns every object that refers to the object of the given
+// kind and ID
+func FindDependencies(ctx context.Context, client *mcpnetbird.NetbirdClient, kind, id string) (*NetbirdDependencies, error) {
+	scan, ok := dependencyScanners[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %q", kind)
+	}
+	deps := &NetbirdDependencies{Kind: kind, ID: id, References: []DependencyReference{}}
+	if err := scan(ctx, client, deps); err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+type GetNetbirdDependenciesParams struct {
+	Kind string `json:"kind" jsonschema:"required,enum=group,enum=peer,enum=posture_check,enum=network,enum=user,description=Kind of the object"`
+	ID   string `json:"id" jsonschema:"required,description=ID of the object"`
+}
+
+func getNetbirdDependencies(ctx context.Context, args GetNetbirdDependenciesParams) (*NetbirdDependencies, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+	return FindDependencies(ctx, client, args.Kind, args.ID)
+}
+
+var GetNetbirdDependencies = mcpnetbird.MustTool(
+	"get_netbird_dependencies",
+	"List every object that refers to a group, peer, posture check, network or user, with the field holding the reference. Groups are looked up in policies, routes, nameservers, network resources and routers, setup key and user auto groups, account settings and DNS settings; peers in groups, policy rule resources, routes and network routers; posture checks in policies; networks in their resources, routers and the policies using those resources; users in the peers they added.",
+	mcpnetbird.ReadOnlyTool,
+	getNetbirdDependencies,
+)
+
+func AddNetbirdDependencyTools(mcp *server.MCPServer) {
+	GetNetbirdDependencies.Register(mcp)
+}
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func dependencyTestObjects() map[string]any {
+	objects := mergeTestObjects()
+	objects["/policies/pol3"] = NetbirdPolicy{ID: "pol3", Name: "db access", Enabled: true, SourcePostureChecks: []string{"pc1"}, Rules: []NetbirdPolicyRule{{
+		Name: "db", Action: "accept", Protocol: "tcp", Enabled: true,
+		SourceResource:      &ResourceReference{ID: "p1", Type: "peer"},
+		Destinations:        []NetbirdPeerGroup{},
+		DestinationResource: &ResourceReference{ID: "res1", Type: "host"},
+	}}}
+	objects["/routes/rt2"] = NetbirdRoute{ID: "rt2", NetworkID: "exit", Network: "0.0.0.0/0", Peer: "p1", Groups: []string{"g4"}}
+	objects["/networks/n1/routers/rtr2"] = NetbirdNetworkRouter{ID: "rtr2", Peer: &[]string{"p1"}[0], Enabled: true}
+	objects["/posture-checks/pc1"] = NetbirdPostureCheck{ID: "pc1", Name: "min version"}
+	objects["/accounts/acc1"] = NetbirdAccount{ID: "acc1", Settings: NetbirdAccountSettings{
+		JWTAllowGroups: []string{"developers"},
+		Extra:          &NetbirdAccountExtra{NetworkTrafficLogsGroups: []string{"g2"}},
+	}}
+	return objects
+}
+
+// referenceKeys returns the references as kind/id:field
+func referenceKeys(deps *NetbirdDependencies) []string {
+	keys := make([]string, len(deps.References))
+	for i, ref := range deps.References {
+		keys[i] = ref.Kind + "/" + ref.ID + ":" + ref.Field
+	}
+	return keys
+}
+
+func TestGetNetbirdDependencies(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	store.fakeGroupStore.mu.Lock()
+	store.fakeGroupStore.peers = []NetbirdPeer{{ID: "p1", Name: "laptop", UserID: "u1"}, {ID: "p2", Name: "server"}}
+	store.fakeGroupStore.mu.Unlock()
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	tests := []struct {
+		kind, id string
+		name     string
+		want     []string
+	}{
+		{"group", "g2", "developers", []string{
+			"policy/pol1:rules[0].sources",
+			"policy/pol1:rules[0].authorized_groups",
+			"route/rt1:groups",
+			"nameserver/ns1:groups",
+			"network_router/rtr1:peer_groups",
+			"setup_key/k1:auto_groups",
+			"account/acc1:settings.jwt_allow_groups",
+			"account/acc1:settings.extra.network_traffic_logs_groups",
+			"dns_settings/dns:disabled_management_groups",
+		}},
+		{"peer", "p1", "laptop", []string{
+			"group/g1:peers",
+			"group/g3:peers",
+			"policy/pol3:rules[0].sourceResource",
+			"route/rt2:peer",
+			"network_router/rtr2:peer",
+		}},
+		{"posture_check", "pc1", "min version", []string{"policy/pol3:source_posture_checks"}},
+		{"network", "n1", "lab", []string{
+			"network_resource/res1:network",
+			"network_router/rtr1:network",
+			"network_router/rtr2:network",
+			"policy/pol3:rules[0].destinationResource",
+		}},
+		{"user", "u1", "alice@example.com", []string{"peer/p1:user_id"}},
+	}
+	for _, tt := range tests {
+		deps, err := getNetbirdDependencies(ctx, GetNetbirdDependenciesParams{Kind: tt.kind, ID: tt.id})
+		if err != nil {
+			t.Fatalf("%s %s: unexpected error: %v", tt.kind, tt.id, err)
+		}
+		if got := referenceKeys(deps); !slices.Equal(got, tt.want) {
+			t.Errorf("%s %s: expected references\n%v\ngot\n%v", tt.kind, tt.id, tt.want, got)
+		}
+		if deps.Name != tt.name {
+			t.Errorf("%s %s: expected name %q, got %q", tt.kind, tt.id, tt.name, deps.Name)
+		}
+	}
+
+	if _, err := getNetbirdDependencies(ctx, GetNetbirdDependenciesParams{Kind: "user", ID: "u9"}); err == nil || !strings.Contains(err.Error(), "user u9 not found") {
+		t.Errorf("expected unknown user error, got %v", err)
+	}
+	if _, err := getNetbirdDependencies(ctx, GetNetbirdDependenciesParams{Kind: "route", ID: "rt1"}); err == nil || !strings.Contains(err.Error(), "unsupported kind") {
+		t.Errorf("expected unsupported kind error, got %v", err)
+	}
+}
+
+func TestDeleteNetbirdGroup_RefusesReferencesOtherThanPolicies(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	for _, force := range []bool{false, true} {
+		_, err := deleteNetbirdGroup(ctx, DeleteNetbirdGroupParams{GroupID: "g2", Force: force})
+		if err == nil || !strings.Contains(err.Error(), "route rt1 (groups)") || !strings.Contains(err.Error(), "setup_key k1 (auto_groups)") {
+			t.Errorf("force=%v: expected the references to be listed, got %v", force, err)
+		}
+	}
+	if !store.hasGroup("g2") || len(store.writes) != 0 {
+		t.Error("expected nothing to be changed")
+	}
+
+	impact, err := describeGroupDeletion(ctx, DeleteNetbirdGroupParams{GroupID: "g2", Force: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if refs := impact.Affected.([]DependencyReference); len(refs) != 9 || len(impact.Warnings) != 2 {
+		t.Errorf("expected every reference and both warnings, got %+v", impact)
+	}
+}
+
+func TestDeleteNetbirdPostureCheck_Force(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	_, err := deleteNetbirdPostureCheck(ctx, DeleteNetbirdPostureCheckParams{PostureCheckID: "pc1"})
+	if err == nil || !strings.Contains(err.Error(), "[pol3]") {
+		t.Fatalf("expected the policies using the posture check to be listed, got %v", err)
+	}
+	if len(store.deleted) != 0 || len(store.writes) != 0 {
+		t.Fatal("expected nothing to be changed")
+	}
+
+	impact, err := describePostureCheckDeletion(ctx, DeleteNetbirdPostureCheckParams{PostureCheckID: "pc1", Force: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(impact.Affected.([]DependencyReference)) != 1 || len(impact.Warnings) != 1 {
+		t.Errorf("expected the policy and a warning, got %+v", impact)
+	}
+
+	result, err := deleteNetbirdPostureCheck(ctx, DeleteNetbirdPostureCheckParams{PostureCheckID: "pc1", Force: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(store.deleted, []string{"/posture-checks/pc1"}) {
+		t.Errorf("expected the posture check to be deleted, got %v", store.deleted)
+	}
+	policy := store.lastWrite(t, "/policies/pol3")
+	if checks := bodyIDs(policy, "source_posture_checks"); len(checks) != 0 {
+		t.Errorf("expected the posture check to be removed from pol3, got %v", checks)
+	}
+	if rules, _ := policy["rules"].([]any); len(rules) != 1 {
+		t.Errorf("expected the rules of pol3 to be kept, got %v", policy["rules"])
+	}
+
+	// Rolling back recreates the posture check and puts it back into the policy
+	rollback, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result["operation_id"].(string)})
+	if err != nil || len(rollback.Errors) != 0 {
+		t.Fatalf("unexpected rollback errors: %v %+v", err, rollback)
+	}
+	if created := store.lastWrite(t, "/posture-checks"); created["name"] != "min version" {
+		t.Errorf("expected the posture check to be recreated, got %v", created)
+	}
+	if checks := bodyIDs(store.lastWrite(t, "/policies/pol3"), "source_posture_checks"); !slices.Equal(checks, []string{rollback.Recreated["pc1"]}) {
+		t.Errorf("expected pol3 to use the recreated posture check %s, got %v", rollback.Recreated["pc1"], checks)
+	}
+}
+
+func TestDeleteNetbirdPostureCheck_ForceRollsBackOnFailure(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	store.failPut["/policies/pol3"] = true
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	if _, err := deleteNetbirdPostureCheck(ctx, DeleteNetbirdPostureCheckParams{PostureCheckID: "pc1", Force: true}); err == nil {
+		t.Fatal("expected error when a policy cannot be updated")
+	}
+	if len(store.deleted) != 0 {
+		t.Errorf("expected the posture check to be kept, got deletes %v", store.deleted)
+	}
+}
+
+func TestDeleteNetbirdNetwork_Force(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	_, err := deleteNetbirdNetwork(ctx, DeleteNetbirdNetworkParams{NetworkID: "n1"})
+	if err == nil || !strings.Contains(err.Error(), "[pol3]") {
+		t.Fatalf("expected the policies using the network's resources to be listed, got %v", err)
+	}
+	if len(store.deleted) != 0 {
+		t.Fatal("expected nothing to be deleted")
+	}
+
+	impact, err := describeNetworkDeletion(ctx, DeleteNetbirdNetworkParams{NetworkID: "n1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(impact.Affected.([]DependencyReference)) != 4 || len(impact.Warnings) != 2 {
+		t.Errorf("expected the resources, routers and policy with two warnings, got %+v", impact)
+	}
+
+	// pol3's only rule loses its destination, so the policy goes too
+	result, err := deleteNetbirdNetwork(ctx, DeleteNetbirdNetworkParams{NetworkID: "n1", Force: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(store.deleted, []string{"/policies/pol3", "/networks/n1"}) {
+		t.Errorf("expected pol3 and then the network to be deleted, got %v", store.deleted)
+	}
+	if modified := result["policies_modified"].([]string); !slices.Equal(modified, []string{"pol3"}) {
+		t.Errorf("expected pol3 to be modified, got %v", modified)
+	}
+}
+
+func TestDeleteNetbirdPostureCheck_IncompleteRollbackCanBeRetried(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	// Detaching from pol3 succeeds, deleting the posture check and restoring pol3 fail
+	store.failDelete["/posture-checks/pc1"] = true
+	store.putLimit["/policies/pol3"] = 1
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	client := mcpnetbird.TestNetbirdClient
+	deps, err := FindDependencies(ctx, client, "posture_check", "pc1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policyIDs, _ := splitPolicyDependencies(deps.References)
+	op := startOperation(ctx, "test")
+	result, err := forceDelete(ctx, client, op, "posture check pc1", policyIDs, func(policy *NetbirdPolicy) {
+		policy.SourcePostureChecks = nil
+	}, func() error { return client.Delete(ctx, "/posture-checks/pc1") })
+	if err == nil || !strings.Contains(err.Error(), "1 changes could not be rolled back") {
+		t.Fatalf("expected the failed restore to be reported, got %v", err)
+	}
+	if result.RolledBack || op.RolledBack {
+		t.Fatalf("expected the operation not to be marked rolled back, got %+v", result)
+	}
+
+	// describe lists the change left to undo, and a retry restores it
+	impact, err := describeRollback(ctx, RollbackNetbirdOperationParams{OperationID: op.ID})
+	if err != nil || len(impact.Affected.([]JournalEntry)) != 1 {
+		t.Fatalf("expected one change left to undo, got %+v, %v", impact, err)
+	}
+	delete(store.putLimit, "/policies/pol3")
+	rollback, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: op.ID})
+	if err != nil || len(rollback.Errors) != 0 || !slices.Equal(rollback.Restored, []string{"policy/pol3"}) {
+		t.Fatalf("expected pol3 to be restored, got %+v, %v", rollback, err)
+	}
+	if checks := bodyIDs(store.lastWrite(t, "/policies/pol3"), "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected pol3 to get its posture check back, got %v", checks)
+	}
+	if _, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: op.ID}); err == nil {
+		t.Error("expected the completed rollback not to run again")
+	}
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+// forceDeleteOutcome is the result of detaching an object from a single policy
+type forceDeleteOutcome struct {
+	modified bool          // policy was updated or left without valid rules
+	empty    bool          // policy has no valid rules left and should be deleted
+	preImage NetbirdPolicy // policy as fetched, before the object was detached
+	errs     []string
+}
+
+// detachFromPolicy fetches a policy, applies detach to it, drops the rules left
+// without a source or destination and writes the policy back. A policy left
+// without rules is reported as empty instead. The update is recorded in op.
+func detachFromPolicy(ctx context.Context, client *mcpnetbird.NetbirdClient, op *Operation, policyID string, detach func(*NetbirdPolicy)) forceDeleteOutcome {
+	var outcome forceDeleteOutcome
+
+	// Fetch the current policy
+	var policy NetbirdPolicy
+	if err := client.Get(ctx, "/policies/"+policyID, &policy); err != nil {
+		outcome.errs = append(outcome.errs, fmt.Sprintf("policy %s: fetching: %v", policyID, err))
+		return outcome
+	}
+	outcome.preImage = remapPolicyGroups(policy, nil)
+	detach(&policy)
+
+	// Keep the rules that still have at least one source and one destination
+	validRules := make([]NetbirdPolicyRule, 0)
+	for _, rule := range policy.Rules {
+		hasSource := len(rule.Sources) > 0 || rule.SourceResource != nil
+		hasDestination := len(rule.Destinations) > 0 || rule.DestinationResource != nil
+		if hasSource && hasDestination {
+			validRules = append(validRules, rule)
+		}
+	}
+
+	// If policy has no valid rules, mark it for deletion
+	if len(validRules) == 0 {
+		outcome.empty = true
+		outcome.modified = true
+		return outcome
+	}
+
+	// Update the policy with cleaned rules
+	policy.Rules = validRules
+	updateBody, errs := policyUpdateBody(policy)
+	for _, err := range errs {
+		outcome.errs = append(outcome.errs, fmt.Sprintf("policy %s: %v", policyID, err))
+	}
+
+	entry := op.record(journalKindPolicy, journalActionUpdate, policyID, outcome.preImage)
+	var updatedPolicy NetbirdPolicy
+	if err := client.Put(ctx, "/policies/"+policyID, updateBody, &updatedPolicy); err != nil {
+		outcome.errs = append(outcome.errs, fmt.Sprintf("policy %s: updating: %v", policyID, err))
+		return outcome
+	}
+	op.applied(entry)
+
+	outcome.modified = true
+	return outcome
+}
+
+// forceDelete detaches an object from the policies in policyIDs and then
+// deletes it with remove, as the single journaled operation op. Policies are
+// processed concurrently, at most BulkConcurrency at a time; policies left
+// without valid rules are deleted. If a policy cannot be changed, ctx is
+// cancelled or remove fails, the changes already made are rolled back. what
+// names the object in errors, such as "group g1".
+func forceDelete(ctx context.Context, client *mcpnetbird.NetbirdClient, op *Operation, what string, policyIDs []string, detach func(*NetbirdPolicy), remove func() error) (*ForceDeleteResult, error) {
+	result := &ForceDeleteResult{
+		OperationID:      op.ID,
+		PoliciesModified: []string{},
+		Deleted:          false,
+		Errors:           []string{},
+	}
+
+	// rollback undoes the changes made so far. It must run even if ctx was cancelled.
+	rollback := func(cause error) (*ForceDeleteResult, error) {
+		restored, err := op.Rollback(context.WithoutCancel(ctx), client)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("rollback: %v", err))
+			return result, fmt.Errorf("deleting %s: %w", what, cause)
+		}
+		for _, msg := range restored.Errors {
+			result.Errors = append(result.Errors, "rollback: "+msg)
+		}
+		if len(restored.Errors) > 0 {
+			return result, fmt.Errorf("deleting %s: %w; %d changes could not be rolled back, retry with rollback_netbird_operation (operation %s)", what, cause, len(restored.Errors), op.ID)
+		}
+		result.RolledBack = true
+		return result, fmt.Errorf("deleting %s: %w; changes were rolled back (operation %s)", what, cause, op.ID)
+	}
+
+	// Detach the object from each policy
+	outcomes := make([]forceDeleteOutcome, len(policyIDs))
+	skipped := runBounded(ctx, len(policyIDs), BulkConcurrency, func(ctx context.Context, i int) {
+		outcomes[i] = detachFromPolicy(ctx, client, op, policyIDs[i], detach)
+	})
+	for _, i := range skipped {
+		outcomes[i].errs = []string{fmt.Sprintf("policy %s: skipped: %v", policyIDs[i], ctx.Err())}
+	}
+
+	policiesToDelete := make([]NetbirdPolicy, 0)
+	for i, policyID := range policyIDs {
+		result.Errors = append(result.Errors, outcomes[i].errs...)
+		if outcomes[i].empty {
+			policiesToDelete = append(policiesToDelete, outcomes[i].preImage)
+		}
+		if outcomes[i].modified {
+			result.PoliciesModified = append(result.PoliciesModified, policyID)
+		}
+	}
+
+	// Don't go any further if we were interrupted or failed to update a policy
+	if err := ctx.Err(); err != nil {
+		return rollback(err)
+	}
+	if len(result.Errors) > 0 {
+		return rollback(fmt.Errorf("%d policy dependencies could not be resolved", len(result.Errors)))
+	}
+
+	// Delete empty policies
+	deleteErrs := make([]string, len(policiesToDelete))
+	skipped = runBounded(ctx, len(policiesToDelete), BulkConcurrency, func(ctx context.Context, i int) {
+		policy := policiesToDelete[i]
+		entry := op.record(journalKindPolicy, journalActionDelete, policy.ID, policy)
+		if err := client.Delete(ctx, "/policies/"+policy.ID); err != nil {
+			deleteErrs[i] = fmt.Sprintf("policy %s: deleting: %v", policy.ID, err)
+			return
+		}
+		op.applied(entry)
+	})
+	for _, i := range skipped {
+		deleteErrs[i] = fmt.Sprintf("policy %s: deleting: skipped: %v", policiesToDelete[i].ID, ctx.Err())
+	}
+	for _, msg := range deleteErrs {
+		if msg != "" {
+			result.Errors = append(result.Errors, msg)
+		}
+	}
+
+	// Don't delete the object unless every empty policy is gone
+	if err := ctx.Err(); err != nil {
+		return rollback(err)
+	}
+	if len(result.Errors) > 0 {
+		return rollback(fmt.Errorf("%d empty policies could not be deleted", len(result.Errors)))
+	}
+
+	// After all dependencies resolved, delete the object
+	if err := remove(); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("deleting %s: %v", what, err))
+		return rollback(err)
+	}
+
+	result.Deleted = true
+	return result, nil
+}
@@ -0,0 +1,216 @@
+package tools
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Diagnostic check statuses, from best to worst
+const (
+	diagnosticOK      = "ok"
+	diagnosticSkipped = "skipped"
+	diagnosticWarn    = "warn"
+	diagnosticFail    = "fail"
+)
+
+const (
+	diagnosticTimeout = 5 * time.Second
+	// certExpiryWarning is how close to expiry the API certificate triggers a warning
+	certExpiryWarning = 14 * 24 * time.Hour
+)
+
+// DiagnosticCheck is the result of a single diagnostics step
+type DiagnosticCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// DiagnosticsReport is the result of netbird_diagnostics and `mcp-netbird doctor`
+type DiagnosticsReport struct {
+	Status     string              `json:"status"`
+	APIURL     string              `json:"api_url"`
+	Checks     []DiagnosticCheck   `json:"checks"`
+	User       *NetbirdCurrentUser `json:"user,omitempty"`
+	Categories []CategoryAccess    `json:"categories,omitempty"`
+}
+
+func (r *DiagnosticsReport) add(name, status, format string, a ...any) {
+	r.Checks = append(r.Checks, DiagnosticCheck{Name: name, Status: status, Detail: fmt.Sprintf(format, a...)})
+	if diagnosticSeverity(status) > diagnosticSeverity(r.Status) {
+		r.Status = status
+	}
+}
+
+func diagnosticSeverity(status string) int {
+	switch status {
+	case diagnosticWarn:
+		return 1
+	case diagnosticFail:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// RunNetbirdDiagnostics checks DNS resolution and TLS reachability of the API host,
+// whether the API accepts the token, the role and permissions of its user and which
+// tool categories that user can use. Later checks are skipped when an earlier one fails.
+func RunNetbirdDiagnostics(ctx context.Context) *DiagnosticsReport {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+	report := &DiagnosticsReport{Status: diagnosticOK, APIURL: client.BaseURL()}
+
+	apiURL, err := url.Parse(client.BaseURL())
+	if err != nil {
+		report.add("dns", diagnosticFail, "invalid API URL %s: %v", client.BaseURL(), err)
+		return report
+	}
+	host := apiURL.Hostname()
+
+	lookupCtx, cancel := context.WithTimeout(ctx, diagnosticTimeout)
+	addrs, err := net.DefaultResolver.LookupHost(lookupCtx, host)
+	cancel()
+	if err != nil {
+		report.add("dns", diagnosticFail, "cannot resolve %s: %v. Check the API host setting", host, err)
+		return report
+	}
+	report.add("dns", diagnosticOK, "%s resolves to %s", host, strings.Join(addrs, ", "))
+
+	if apiURL.Scheme == "https" {
+		if !checkTLS(ctx, report, apiURL) {
+			return report
+		}
+	} else {
+		report.add("tls", diagnosticSkipped, "API URL does not use HTTPS")
+	}
+
+	if mcpnetbird.NetbirdAPIKeyFromContext(ctx) == "" {
+		report.add("token", diagnosticFail, "no API token configured. Set NETBIRD_API_TOKEN, -api-token or the X-Netbird-API-Token header")
+		return report
+	}
+	user, err := fetchCurrentUser(ctx, client)
+	if err != nil {
+		var apiErr *mcpnetbird.APIError
+		switch {
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized:
+			report.add("token", diagnosticFail, "the API rejected the token (401). It may be mistyped, expired or revoked")
+		case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+			report.add("token", diagnosticFail, "%s has no /users/current endpoint (404). Check that the API host points at the Netbird management API", host)
+		default:
+			report.add("token", diagnosticFail, "requesting the current user: %v", err)
+		}
+		return report
+	}
+	report.add("token", diagnosticOK, "token is valid for %s", describeUser(user))
+	report.User = user
+
+	switch {
+	case user.IsBlocked:
+		report.add("role", diagnosticFail, "user is blocked; every request will be refused")
+	case user.Permissions != nil && user.Permissions.IsRestricted:
+		report.add("role", diagnosticWarn, "role %s with restricted permissions; some tools will be refused", user.Role)
+	case (user.Permissions == nil || len(user.Permissions.Modules) == 0) && roleModuleAccess[user.Role] == nil:
+		report.add("role", diagnosticWarn, "unknown role %s and no permissions returned; tool access can't be determined", user.Role)
+	default:
+		report.add("role", diagnosticOK, "role %s", user.Role)
+	}
+
+	report.Categories = categoryAccess(user)
+	var readOnly, denied []string
+	for _, a := range report.Categories {
+		switch {
+		case !a.Read:
+			denied = append(denied, a.Category)
+		case !a.Write:
+			readOnly = append(readOnly, a.Category)
+		}
+	}
+	switch {
+	case len(denied) == len(report.Categories):
+		report.add("permissions", diagnosticFail, "no tool category is usable with role %s", user.Role)
+	case len(denied) > 0 || len(readOnly) > 0:
+		report.add("permissions", diagnosticWarn, "read-only: %s; unavailable: %s", joinOrNone(readOnly), joinOrNone(denied))
+	default:
+		report.add("permissions", diagnosticOK, "all tool categories can read and write")
+	}
+	return report
+}
+
+// checkTLS connects to the API host and reports the negotiated TLS version and
+// certificate expiry. It returns false when the handshake fails.
+func checkTLS(ctx context.Context, report *DiagnosticsReport, apiURL *url.URL) bool {
+	address := apiURL.Host
+	if apiURL.Port() == "" {
+		address = net.JoinHostPort(apiURL.Hostname(), "443")
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: diagnosticTimeout},
+		Config:    &tls.Config{ServerName: apiURL.Hostname()},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		report.add("tls", diagnosticFail, "cannot establish a TLS connection to %s: %v", address, err)
+		return false
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	cert := state.PeerCertificates[0]
+	remaining := time.Until(cert.NotAfter)
+	if remaining < certExpiryWarning {
+		report.add("tls", diagnosticWarn, "%s to %s; certificate expires %s", tls.VersionName(state.Version), address, cert.NotAfter.Format(time.RFC3339))
+	} else {
+		report.add("tls", diagnosticOK, "%s to %s; certificate valid until %s", tls.VersionName(state.Version), address, cert.NotAfter.Format(time.RFC3339))
+	}
+	return true
+}
+
+func describeUser(user *NetbirdCurrentUser) string {
+	name := user.Email
+	if name == "" {
+		name = user.Name
+	}
+	if user.IsServiceUser {
+		return fmt.Sprintf("service user %s", name)
+	}
+	return fmt.Sprintf("user %s", name)
+}
+
+func joinOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
+
+type NetbirdDiagnosticsParams struct{}
+
+func netbirdDiagnostics(ctx context.Context, args NetbirdDiagnosticsParams) (*DiagnosticsReport, error) {
+	return RunNetbirdDiagnostics(ctx), nil
+}
+
+var NetbirdDiagnostics = mcpnetbird.MustTool(
+	"netbird_diagnostics",
+	"Check the connection to the Netbird API: DNS and TLS reachability of the API host, whether the token is valid, the role and permissions of its user, and which tool categories can read or write with that role. Run this first when tools fail with connection or permission errors.",
+	mcpnetbird.ReadOnlyTool,
+	netbirdDiagnostics,
+)
+
+func AddNetbirdDiagnosticsTools(mcp *server.MCPServer) {
+	NetbirdDiagnostics.Register(mcp)
+}
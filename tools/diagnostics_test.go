@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func diagnosticCheck(report *DiagnosticsReport, name string) *DiagnosticCheck {
+	for i := range report.Checks {
+		if report.Checks[i].Name == name {
+			return &report.Checks[i]
+		}
+	}
+	return nil
+}
+
+func categoryByName(report *DiagnosticsReport, name string) CategoryAccess {
+	for _, a := range report.Categories {
+		if a.Category == name {
+			return a
+		}
+	}
+	return CategoryAccess{}
+}
+
+func runDiagnosticsAgainst(t *testing.T, currentUser string, status int) *DiagnosticsReport {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/current" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(currentUser))
+	}))
+	t.Cleanup(server.Close)
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	t.Cleanup(func() { mcpnetbird.TestNetbirdClient = nil })
+
+	return RunNetbirdDiagnostics(mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token"))
+}
+
+func TestNetbirdDiagnostics_Permissions(t *testing.T) {
+	report := runDiagnosticsAgainst(t, `{
+		"id": "u1", "email": "ops@example.com", "role": "network_admin", "is_service_user": false,
+		"permissions": {"is_restricted": true, "modules": {
+			"peers": {"read": true, "create": true, "update": true, "delete": true},
+			"policies": {"read": true},
+			"users": {"read": false}
+		}}
+	}`, http.StatusOK)
+
+	if report.Status != diagnosticWarn {
+		t.Errorf("expected warn status, got %s: %+v", report.Status, report.Checks)
+	}
+	for name, want := range map[string]string{
+		"dns":         diagnosticOK,
+		"tls":         diagnosticSkipped,
+		"token":       diagnosticOK,
+		"role":        diagnosticWarn,
+		"permissions": diagnosticWarn,
+	} {
+		if check := diagnosticCheck(report, name); check == nil || check.Status != want {
+			t.Errorf("expected %s check to be %s, got %+v", name, want, check)
+		}
+	}
+	if report.User == nil || report.User.Role != "network_admin" {
+		t.Errorf("expected current user in report, got %+v", report.User)
+	}
+
+	if a := categoryByName(report, "peers"); !a.Read || !a.Write {
+		t.Errorf("expected read/write on peers, got %+v", a)
+	}
+	if a := categoryByName(report, "posture_checks"); !a.Read || a.Write {
+		t.Errorf("expected read-only posture checks from the policies module, got %+v", a)
+	}
+	if a := categoryByName(report, "users"); a.Read || a.Write {
+		t.Errorf("expected no access to users, got %+v", a)
+	}
+}
+
+func TestNetbirdDiagnostics_RoleFallback(t *testing.T) {
+	report := runDiagnosticsAgainst(t, `{"id": "svc", "name": "automation", "role": "admin", "is_service_user": true}`, http.StatusOK)
+
+	if report.Status != diagnosticOK {
+		t.Errorf("expected ok status, got %s: %+v", report.Status, report.Checks)
+	}
+	if check := diagnosticCheck(report, "token"); check == nil || !strings.Contains(check.Detail, "service user automation") {
+		t.Errorf("expected service user in token detail, got %+v", check)
+	}
+	for _, a := range report.Categories {
+		if !a.Read || !a.Write {
+			t.Errorf("expected admin to have full access to %s, got %+v", a.Category, a)
+		}
+	}
+
+	report = runDiagnosticsAgainst(t, `{"id": "a1", "role": "auditor"}`, http.StatusOK)
+	if a := categoryByName(report, "policies"); !a.Read || a.Write {
+		t.Errorf("expected auditor to be read-only, got %+v", a)
+	}
+}
+
+func TestNetbirdDiagnostics_InvalidToken(t *testing.T) {
+	report := runDiagnosticsAgainst(t, `{"message":"token invalid"}`, http.StatusUnauthorized)
+
+	if report.Status != diagnosticFail {
+		t.Errorf("expected fail status, got %s", report.Status)
+	}
+	check := diagnosticCheck(report, "token")
+	if check == nil || check.Status != diagnosticFail || !strings.Contains(check.Detail, "401") {
+		t.Errorf("expected failed token check, got %+v", check)
+	}
+	if diagnosticCheck(report, "role") != nil || report.Categories != nil {
+		t.Error("expected role and permission checks to be skipped")
+	}
+}
+
+func TestNetbirdDiagnostics_MissingToken(t *testing.T) {
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL("http://127.0.0.1:1")
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	report := RunNetbirdDiagnostics(context.Background())
+	if check := diagnosticCheck(report, "token"); check == nil || check.Status != diagnosticFail {
+		t.Errorf("expected failed token check, got %+v", check)
+	}
+}
+
+func TestNetbirdDiagnostics_UntrustedCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected no API request after a failed TLS check, got %s", r.URL.Path)
+	}))
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	report := RunNetbirdDiagnostics(mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token"))
+	check := diagnosticCheck(report, "tls")
+	if check == nil || check.Status != diagnosticFail || !strings.Contains(check.Detail, "certificate") {
+		t.Errorf("expected failed TLS check, got %+v", check)
+	}
+	if diagnosticCheck(report, "token") != nil {
+		t.Error("expected token check to be skipped")
+	}
+}
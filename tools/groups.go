@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
 	"github.com/mark3labs/mcp-go/server"
@@ -28,10 +30,17 @@ type NetbirdGroup struct {
 	ResourcesCount int                  `json:"resources_count"`
 }
 
-type ListNetbirdGroupsParams struct{}
+type ListNetbirdGroupsParams struct {
+	ListOptions
+}
 
 func listNetbirdGroups(ctx context.Context, args ListNetbirdGroupsParams) ([]NetbirdGroup, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
 
 	var groups []NetbirdGroup
 	if err := client.Get(ctx, "/groups", &groups); err != nil {
@@ -44,7 +53,8 @@ func listNetbirdGroups(ctx context.Context, args ListNetbirdGroupsParams) ([]Net
 var ListNetbirdGroups = mcpnetbird.MustTool(
 	"list_netbird_groups",
 	"List all Netbird groups",
-	listNetbirdGroups,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdGroups),
 )
 
 type GetNetbirdGroupParams struct {
@@ -52,7 +62,12 @@ type GetNetbirdGroupParams struct {
 }
 
 func getNetbirdGroup(ctx context.Context, args GetNetbirdGroupParams) (*NetbirdGroup, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
 	var group NetbirdGroup
 	if err := client.Get(ctx, "/groups/"+args.GroupID, &group); err != nil {
 		return nil, err
@@ -63,6 +78,7 @@ func getNetbirdGroup(ctx context.Context, args GetNetbirdGroupParams) (*NetbirdG
 var GetNetbirdGroup = mcpnetbird.MustTool(
 	"get_netbird_group",
 	"Get a specific Netbird group by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdGroup,
 )
 
@@ -84,6 +100,7 @@ func createNetbirdGroup(ctx context.Context, args CreateNetbirdGroupParams) (*Ne
 var CreateNetbirdGroup = mcpnetbird.MustTool(
 	"create_netbird_group",
 	"Create a new Netbird group",
+	mcpnetbird.AdditiveTool,
 	createNetbirdGroup,
 )
 
@@ -105,20 +122,27 @@ func updateNetbirdGroup(ctx context.Context, args UpdateNetbirdGroupParams) (*Ne
 
 var UpdateNetbirdGroup = mcpnetbird.MustTool(
 	"update_netbird_group",
-	"Update an existing Netbird group",
+	"Update an existing Netbird group. peers and resources replace the current members; use add_netbird_group_members or remove_netbird_group_members to change some of them",
+	mcpnetbird.UpdateTool,
 	updateNetbirdGroup,
 )
 
 // ForceDeleteResult contains the result of a force delete operation
 type ForceDeleteResult struct {
 	GroupID          string   `json:"group_id"`
+	OperationID      string   `json:"operation_id"`
 	PoliciesModified []string `json:"policies_modified"`
 	Deleted          bool     `json:"deleted"`
+	RolledBack       bool     `json:"rolled_back,omitempty"`
 	Errors           []string `json:"errors,omitempty"`
 }
 
 // DeleteGroupForce deletes a group after removing it from all dependent policies.
-// It handles cleanup of invalid rules and empty policies.
+// It handles cleanup of invalid rules and empty policies. Policies are processed
+// concurrently, at most BulkConcurrency at a time. Every change is journaled: if a
+// policy cannot be updated, ctx is cancelled or the group cannot be deleted, the
+// changes already made are rolled back and the group is kept. A successful
+// operation can still be undone later with rollback_netbird_operation.
 func DeleteGroupForce(ctx context.Context, groupID string) (*ForceDeleteResult, error) {
 	var client *mcpnetbird.NetbirdClient
 	if mcpnetbird.TestNetbirdClient != nil {
@@ -126,42 +150,40 @@ func DeleteGroupForce(ctx context.Context, groupID string) (*ForceDeleteResult,
 	} else {
 		client = mcpnetbird.NewNetbirdClient(ctx)
 	}
-	
-	result := &ForceDeleteResult{
-		GroupID:          groupID,
-		PoliciesModified: []string{},
-		Deleted:          false,
-		Errors:           []string{},
+
+	// Keep the group itself so the operation can recreate it on rollback
+	var group groupPreImage
+	if err := client.Get(ctx, "/groups/"+groupID, &group); err != nil {
+		return nil, fmt.Errorf("fetching group %s: %w", groupID, err)
 	}
-	
+
 	// Find all policies that reference this group
 	references, err := ListPoliciesByGroup(ctx, groupID)
 	if err != nil {
 		return nil, fmt.Errorf("finding policies with group %s: %w", groupID, err)
 	}
-	
-	// Track which policies we've already processed
-	processedPolicies := make(map[string]bool)
-	policiesToDelete := make([]string, 0)
-	
-	// Process each policy
-	for _, ref := range references {
-		// Skip if we've already processed this policy
-		if processedPolicies[ref.PolicyID] {
-			continue
-		}
-		processedPolicies[ref.PolicyID] = true
-		
-		// Fetch the current policy
-		var policy NetbirdPolicy
-		if err := client.Get(ctx, "/policies/"+ref.PolicyID, &policy); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("policy %s: fetching: %v", ref.PolicyID, err))
-			continue
+	policyIDs := uniquePolicyIDs(references)
+
+	op := startOperation(ctx, "delete_group_force "+groupID)
+	result, err := forceDelete(ctx, client, op, "group "+groupID, policyIDs, removeGroupFromPolicy(groupID), func() error {
+		entry := op.record(journalKindGroup, journalActionDelete, groupID, group)
+		if err := client.Delete(ctx, "/groups/"+groupID); err != nil {
+			return err
 		}
-		
-		// Remove group from all rules
-		validRules := make([]NetbirdPolicyRule, 0)
-		for _, rule := range policy.Rules {
+		op.applied(entry)
+		return nil
+	})
+	result.GroupID = groupID
+	return result, err
+}
+
+// removeGroupFromPolicy returns a detach function that removes groupID from the
+// sources, destinations and authorized_groups of every rule in a policy
+func removeGroupFromPolicy(groupID string) func(*NetbirdPolicy) {
+	return func(policy *NetbirdPolicy) {
+		for i := range policy.Rules {
+			rule := &policy.Rules[i]
+
 			// Remove from sources
 			newSources := make([]NetbirdPeerGroup, 0)
 			for _, source := range rule.Sources {
@@ -170,7 +192,7 @@ func DeleteGroupForce(ctx context.Context, groupID string) (*ForceDeleteResult,
 				}
 			}
 			rule.Sources = newSources
-			
+
 			// Remove from destinations
 			newDestinations := make([]NetbirdPeerGroup, 0)
 			for _, dest := range rule.Destinations {
@@ -179,86 +201,76 @@ func DeleteGroupForce(ctx context.Context, groupID string) (*ForceDeleteResult,
 				}
 			}
 			rule.Destinations = newDestinations
-			
+
 			// Remove from authorized_groups
 			if rule.AuthorizedGroups != nil {
 				delete(*rule.AuthorizedGroups, groupID)
 			}
-			
-			// Check if rule is still valid (has at least one source and one destination)
-			hasSource := len(rule.Sources) > 0 || rule.SourceResource != nil
-			hasDestination := len(rule.Destinations) > 0 || rule.DestinationResource != nil
-			
-			if hasSource && hasDestination {
-				validRules = append(validRules, rule)
-			}
-		}
-		
-		// If policy has no valid rules, mark it for deletion
-		if len(validRules) == 0 {
-			policiesToDelete = append(policiesToDelete, ref.PolicyID)
-			result.PoliciesModified = append(result.PoliciesModified, ref.PolicyID)
-			continue
-		}
-		
-		// Update the policy with cleaned rules
-		policy.Rules = validRules
-		
-		// Convert rules to map format for API
-		rulesMap := make([]map[string]interface{}, len(policy.Rules))
-		for i, rule := range policy.Rules {
-			ruleMap, err := structToMap(rule)
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("policy %s: converting rule to map: %v", ref.PolicyID, err))
-				continue
-			}
-			
-			// Format rule for API
-			formatted, err := FormatRuleForAPI(ruleMap)
-			if err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("policy %s: formatting rule: %v", ref.PolicyID, err))
-				continue
-			}
-			rulesMap[i] = formatted
-		}
-		
-		// Update the policy
-		updateBody := map[string]interface{}{
-			"name":        policy.Name,
-			"description": policy.Description,
-			"enabled":     policy.Enabled,
-			"rules":       rulesMap,
-		}
-		
-		var updatedPolicy NetbirdPolicy
-		if err := client.Put(ctx, "/policies/"+ref.PolicyID, updateBody, &updatedPolicy); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("policy %s: updating: %v", ref.PolicyID, err))
-			continue
-		}
-		
-		result.PoliciesModified = append(result.PoliciesModified, ref.PolicyID)
-	}
-	
-	// Delete empty policies
-	for _, policyID := range policiesToDelete {
-		if err := client.Delete(ctx, "/policies/"+policyID); err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("policy %s: deleting: %v", policyID, err))
 		}
 	}
-	
-	// After all dependencies resolved, delete the group
-	if err := client.Delete(ctx, "/groups/"+groupID); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("deleting group: %v", err))
-		return result, fmt.Errorf("deleting group: %w", err)
-	}
-	
-	result.Deleted = true
-	return result, nil
 }
 
 type DeleteNetbirdGroupParams struct {
 	GroupID string `json:"group_id" jsonschema:"required,description=The ID of the group to delete"`
 	Force   bool   `json:"force,omitempty" jsonschema:"description=Force delete by removing all dependencies first"`
+	Confirmation
+}
+
+// describeGroupDeletion reports the group and every object that references it
+func describeGroupDeletion(ctx context.Context, args DeleteNetbirdGroupParams) (*Impact, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	var group NetbirdGroup
+	if err := client.Get(ctx, "/groups/"+args.GroupID, &group); err != nil {
+		return nil, err
+	}
+	deps, err := FindDependencies(ctx, client, "group", args.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependencies: %w", err)
+	}
+
+	impact := &Impact{Action: "delete /groups/" + args.GroupID, Target: group, Affected: deps.References}
+	policies, others := splitPolicyDependencies(deps.References)
+	if len(others) > 0 {
+		impact.Warnings = append(impact.Warnings, fmt.Sprintf("the group is referenced by %d objects other than policies and will not be deleted; update them or use merge_netbird_groups", len(others)))
+	}
+	if len(policies) > 0 {
+		if args.Force {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("the group will be removed from %d policies; rules left without sources or destinations and policies left without rules will be deleted", len(policies)))
+		} else {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("the group is referenced by %d policies and will not be deleted unless force=true", len(policies)))
+		}
+	}
+	return impact, nil
+}
+
+// splitPolicyDependencies returns the IDs of the referencing policies, which a
+// force delete removes the group from, and the other references, which it doesn't
+func splitPolicyDependencies(references []DependencyReference) ([]string, []DependencyReference) {
+	var policies []string
+	var others []DependencyReference
+	for _, ref := range references {
+		if ref.Kind != journalKindPolicy {
+			others = append(others, ref)
+		} else if !slices.Contains(policies, ref.ID) {
+			policies = append(policies, ref.ID)
+		}
+	}
+	return policies, others
+}
+
+// describeDependencies lists references as kind id (field) for error messages
+func describeDependencies(references []DependencyReference) string {
+	described := make([]string, len(references))
+	for i, ref := range references {
+		described[i] = fmt.Sprintf("%s %s (%s)", ref.Kind, ref.ID, ref.Field)
+	}
+	return strings.Join(described, ", ")
 }
 
 func deleteNetbirdGroup(ctx context.Context, args DeleteNetbirdGroupParams) (map[string]interface{}, error) {
@@ -269,6 +281,19 @@ func deleteNetbirdGroup(ctx context.Context, args DeleteNetbirdGroupParams) (map
 		client = mcpnetbird.NewNetbirdClient(ctx)
 	}
 	
+	// Check for dependencies
+	deps, err := FindDependencies(ctx, client, "group", args.GroupID)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependencies: %w", err)
+	}
+	policyIDs, others := splitPolicyDependencies(deps.References)
+
+	// A force delete only removes the group from policies
+	if len(others) > 0 {
+		return nil, fmt.Errorf("cannot delete group '%s': referenced by %d objects other than policies: %s. Update them or use merge_netbird_groups first",
+			args.GroupID, len(others), describeDependencies(others))
+	}
+	
 	// If force is true, use DeleteGroupForce
 	if args.Force {
 		result, err := DeleteGroupForce(ctx, args.GroupID)
@@ -280,28 +305,14 @@ func deleteNetbirdGroup(ctx context.Context, args DeleteNetbirdGroupParams) (map
 			"status":            "deleted",
 			"group_id":          args.GroupID,
 			"force":             true,
+			"operation_id":      result.OperationID,
 			"policies_modified": result.PoliciesModified,
 			"errors":            result.Errors,
 		}, nil
 	}
 	
-	// Check for dependencies
-	references, err := ListPoliciesByGroup(ctx, args.GroupID)
-	if err != nil {
-		return nil, fmt.Errorf("checking dependencies: %w", err)
-	}
-	
 	// If dependencies exist and force is false, return error
-	if len(references) > 0 {
-		policyIDs := make([]string, 0, len(references))
-		policyNames := make(map[string]bool)
-		for _, ref := range references {
-			if !policyNames[ref.PolicyName] {
-				policyIDs = append(policyIDs, ref.PolicyID)
-				policyNames[ref.PolicyName] = true
-			}
-		}
-		
+	if len(policyIDs) > 0 {
 		return nil, fmt.Errorf("cannot delete group '%s': referenced by %d policies %v. Use force=true to remove dependencies first", 
 			args.GroupID, len(policyIDs), policyIDs)
 	}
@@ -320,8 +331,9 @@ func deleteNetbirdGroup(ctx context.Context, args DeleteNetbirdGroupParams) (map
 
 var DeleteNetbirdGroup = mcpnetbird.MustTool(
 	"delete_netbird_group",
-	"Delete a Netbird group. If force=true, removes the group from all dependent policies before deletion. If force=false (default) and dependencies exist, returns an error with the list of dependent policies.",
-	deleteNetbirdGroup,
+	"Delete a Netbird group. If force=true, removes the group from all dependent policies before deletion; if any policy cannot be updated the changes are rolled back and the group is kept. If force=false (default) and dependencies exist, returns an error with the list of dependent policies. Groups referenced by routes, nameservers, networks, setup keys, users, account or DNS settings are never deleted; the error lists those references. The first call returns an impact summary and a confirmation_token; call again with the token to delete.",
+	mcpnetbird.DeleteTool,
+	withConfirmation(describeGroupDeletion, deleteNetbirdGroup),
 )
 
 func AddNetbirdGroupTools(mcp *server.MCPServer) {
@@ -332,11 +344,13 @@ func AddNetbirdGroupTools(mcp *server.MCPServer) {
 	DeleteNetbirdGroup.Register(mcp)
 	ListPoliciesByGroupTool.Register(mcp)
 	ReplaceGroupInPoliciesTool.Register(mcp)
+	MergeNetbirdGroups.Register(mcp)
 }
 
 // ListPoliciesByGroupParams defines parameters for the list_policies_by_group tool
 type ListPoliciesByGroupParams struct {
 	GroupID string `json:"group_id" jsonschema:"required,description=The ID of the group to search for in policies"`
+	ListOptions
 }
 
 func listPoliciesByGroupTool(ctx context.Context, args ListPoliciesByGroupParams) ([]PolicyReference, error) {
@@ -346,13 +360,40 @@ func listPoliciesByGroupTool(ctx context.Context, args ListPoliciesByGroupParams
 var ListPoliciesByGroupTool = mcpnetbird.MustTool(
 	"list_policies_by_group",
 	"List all policies that reference a specific group. Returns policy ID, name, rule ID, rule name, and location (sources, destinations, or authorized_groups) for each reference.",
-	listPoliciesByGroupTool,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listPoliciesByGroupTool),
 )
 
 // ReplaceGroupInPoliciesParams defines parameters for the replace_group_in_policies tool
 type ReplaceGroupInPoliciesParams struct {
 	OldGroupID string `json:"old_group_id" jsonschema:"required,description=The ID of the group to replace"`
 	NewGroupID string `json:"new_group_id" jsonschema:"required,description=The ID of the group to replace with"`
+	Confirmation
+}
+
+// describeGroupReplacement reports the policies that reference the old group
+func describeGroupReplacement(ctx context.Context, args ReplaceGroupInPoliciesParams) (*Impact, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	references, err := ListPoliciesByGroup(ctx, args.OldGroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	impact := &Impact{
+		Action:   fmt.Sprintf("replace group %s with %s in %d policies", args.OldGroupID, args.NewGroupID, len(uniquePolicyIDs(references))),
+		Affected: references,
+	}
+	var group NetbirdGroup
+	if err := client.Get(ctx, "/groups/"+args.NewGroupID, &group); err != nil {
+		impact.Warnings = append(impact.Warnings, fmt.Sprintf("group %s could not be fetched: %v", args.NewGroupID, err))
+	}
+	return impact, nil
 }
 
 func replaceGroupInPoliciesTool(ctx context.Context, args ReplaceGroupInPoliciesParams) (*GroupReplacementResult, error) {
@@ -361,8 +402,9 @@ func replaceGroupInPoliciesTool(ctx context.Context, args ReplaceGroupInPolicies
 
 var ReplaceGroupInPoliciesTool = mcpnetbird.MustTool(
 	"replace_group_in_policies",
-	"Replace one group with another across all policies. Updates sources, destinations, and authorized_groups in all policy rules. Returns list of updated policy IDs and any errors encountered.",
-	replaceGroupInPoliciesTool,
+	"Replace one group with another across all policies. Updates sources, destinations, and authorized_groups in all policy rules. Returns list of updated policy IDs, any errors encountered, and an operation_id that rollback_netbird_operation can use to undo the changes. The first call returns an impact summary and a confirmation_token; call again with the token to apply the changes.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describeGroupReplacement, replaceGroupInPoliciesTool),
 )
 
 // PolicyReference represents a reference to a policy that uses a specific group
@@ -446,13 +488,16 @@ func ListPoliciesByGroup(ctx context.Context, groupID string) ([]PolicyReference
 
 // GroupReplacementResult contains the result of a group replacement operation
 type GroupReplacementResult struct {
+	OperationID      string            `json:"operation_id"`
 	UpdatedPolicyIDs []string          `json:"updated_policy_ids"`
 	Errors           map[string]string `json:"errors,omitempty"`
 }
 
 // ReplaceGroupInPolicies replaces oldGroupID with newGroupID in all policies.
 // It updates sources, destinations, and authorized_groups in all policy rules.
-// Returns a list of policy IDs that were updated and any errors encountered.
+// Policies are processed concurrently, at most BulkConcurrency at a time.
+// Returns a list of policy IDs that were updated and any errors encountered,
+// along with the operation ID under which the changes were journaled.
 func ReplaceGroupInPolicies(ctx context.Context, oldGroupID, newGroupID string) (*GroupReplacementResult, error) {
 	var client *mcpnetbird.NetbirdClient
 	if mcpnetbird.TestNetbirdClient != nil {
@@ -460,105 +505,153 @@ func ReplaceGroupInPolicies(ctx context.Context, oldGroupID, newGroupID string)
 	} else {
 		client = mcpnetbird.NewNetbirdClient(ctx)
 	}
-	
+
 	// Find all policies that reference the old group
 	references, err := ListPoliciesByGroup(ctx, oldGroupID)
 	if err != nil {
 		return nil, fmt.Errorf("finding policies with group %s: %w", oldGroupID, err)
 	}
-	
+
+	op := startOperation(ctx, "replace_group "+oldGroupID+" "+newGroupID)
 	result := &GroupReplacementResult{
+		OperationID:      op.ID,
 		UpdatedPolicyIDs: []string{},
 		Errors:           make(map[string]string),
 	}
-	
-	// Track which policies we've already updated (to avoid duplicates)
-	updatedPolicies := make(map[string]bool)
-	
-	// Update each policy
-	for _, ref := range references {
-		// Skip if we've already updated this policy
-		if updatedPolicies[ref.PolicyID] {
-			continue
+
+	// Update each policy once, even if it references the group in several rules
+	policyIDs := uniquePolicyIDs(references)
+	updated := make([]bool, len(policyIDs))
+	errs := make([]string, len(policyIDs))
+	skipped := runBounded(ctx, len(policyIDs), BulkConcurrency, func(ctx context.Context, i int) {
+		updated[i], errs[i] = replaceGroupInPolicy(ctx, client, op, policyIDs[i], oldGroupID, newGroupID)
+	})
+	for _, i := range skipped {
+		errs[i] = fmt.Sprintf("skipped: %v", ctx.Err())
+	}
+
+	for i, policyID := range policyIDs {
+		if errs[i] != "" {
+			result.Errors[policyID] = errs[i]
 		}
-		
-		// Fetch the current policy
-		var policy NetbirdPolicy
-		if err := client.Get(ctx, "/policies/"+ref.PolicyID, &policy); err != nil {
-			result.Errors[ref.PolicyID] = fmt.Sprintf("fetching policy: %v", err)
-			continue
+		if updated[i] {
+			result.UpdatedPolicyIDs = append(result.UpdatedPolicyIDs, policyID)
 		}
-		
-		// Replace group ID in all rules
-		modified := false
-		for i := range policy.Rules {
-			rule := &policy.Rules[i]
-			
-			// Replace in sources
-			for j := range rule.Sources {
-				if rule.Sources[j].ID == oldGroupID {
-					rule.Sources[j].ID = newGroupID
-					modified = true
-				}
-			}
-			
-			// Replace in destinations
-			for j := range rule.Destinations {
-				if rule.Destinations[j].ID == oldGroupID {
-					rule.Destinations[j].ID = newGroupID
-					modified = true
-				}
-			}
-			
-			// Replace in authorized_groups
-			if rule.AuthorizedGroups != nil {
-				if users, ok := (*rule.AuthorizedGroups)[oldGroupID]; ok {
-					delete(*rule.AuthorizedGroups, oldGroupID)
-					(*rule.AuthorizedGroups)[newGroupID] = users
-					modified = true
-				}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("replacing group %s: %w", oldGroupID, err)
+	}
+
+	return result, nil
+}
+
+// replaceGroupInPolicy replaces oldGroupID with newGroupID in a single policy.
+// It reports whether the policy was updated and the last error encountered.
+// The update is recorded in op.
+func replaceGroupInPolicy(ctx context.Context, client *mcpnetbird.NetbirdClient, op *Operation, policyID, oldGroupID, newGroupID string) (bool, string) {
+	// Fetch the current policy
+	var policy NetbirdPolicy
+	if err := client.Get(ctx, "/policies/"+policyID, &policy); err != nil {
+		return false, fmt.Sprintf("fetching policy: %v", err)
+	}
+	preImage := remapPolicyGroups(policy, nil)
+
+	// Replace group ID in all rules
+	modified := false
+	for i := range policy.Rules {
+		rule := &policy.Rules[i]
+
+		// Replace in sources
+		for j := range rule.Sources {
+			if rule.Sources[j].ID == oldGroupID {
+				rule.Sources[j].ID = newGroupID
+				modified = true
 			}
 		}
-		
-		// Only update if we made changes
-		if modified {
-			// Convert rules to map format for API
-			rulesMap := make([]map[string]interface{}, len(policy.Rules))
-			for i, rule := range policy.Rules {
-				ruleMap, err := structToMap(rule)
-				if err != nil {
-					result.Errors[ref.PolicyID] = fmt.Sprintf("converting rule to map: %v", err)
-					continue
-				}
-				
-				// Format rule for API (convert sources/destinations to string arrays)
-				formatted, err := FormatRuleForAPI(ruleMap)
-				if err != nil {
-					result.Errors[ref.PolicyID] = fmt.Sprintf("formatting rule: %v", err)
-					continue
-				}
-				rulesMap[i] = formatted
-			}
-			
-			// Update the policy
-			updateBody := map[string]interface{}{
-				"name":        policy.Name,
-				"description": policy.Description,
-				"enabled":     policy.Enabled,
-				"rules":       rulesMap,
+
+		// Replace in destinations
+		for j := range rule.Destinations {
+			if rule.Destinations[j].ID == oldGroupID {
+				rule.Destinations[j].ID = newGroupID
+				modified = true
 			}
-			
-			var updatedPolicy NetbirdPolicy
-			if err := client.Put(ctx, "/policies/"+ref.PolicyID, updateBody, &updatedPolicy); err != nil {
-				result.Errors[ref.PolicyID] = fmt.Sprintf("updating policy: %v", err)
-				continue
+		}
+
+		// Replace in authorized_groups
+		if rule.AuthorizedGroups != nil {
+			if users, ok := (*rule.AuthorizedGroups)[oldGroupID]; ok {
+				delete(*rule.AuthorizedGroups, oldGroupID)
+				(*rule.AuthorizedGroups)[newGroupID] = users
+				modified = true
 			}
-			
-			result.UpdatedPolicyIDs = append(result.UpdatedPolicyIDs, ref.PolicyID)
-			updatedPolicies[ref.PolicyID] = true
 		}
 	}
-	
-	return result, nil
+
+	// Only update if we made changes
+	if !modified {
+		return false, ""
+	}
+
+	var errMsg string
+	updateBody, errs := policyUpdateBody(policy)
+	if len(errs) > 0 {
+		errMsg = errs[len(errs)-1].Error()
+	}
+
+	entry := op.record(journalKindPolicy, journalActionUpdate, policyID, preImage)
+	var updatedPolicy NetbirdPolicy
+	if err := client.Put(ctx, "/policies/"+policyID, updateBody, &updatedPolicy); err != nil {
+		return false, fmt.Sprintf("updating policy: %v", err)
+	}
+	op.applied(entry)
+
+	return true, errMsg
 }
 
+// uniquePolicyIDs returns the IDs of the referenced policies in discovery order without duplicates
+func uniquePolicyIDs(references []PolicyReference) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(references))
+	for _, ref := range references {
+		if !seen[ref.PolicyID] {
+			seen[ref.PolicyID] = true
+			ids = append(ids, ref.PolicyID)
+		}
+	}
+	return ids
+}
+
+// policyUpdateBody builds the PUT body for a policy, converting its rules to the
+// API request format. Rules that cannot be converted are reported in the returned errors.
+func policyUpdateBody(policy NetbirdPolicy) (map[string]interface{}, []error) {
+	var errs []error
+
+	rulesMap := make([]map[string]interface{}, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		ruleMap, err := structToMap(rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("converting rule to map: %w", err))
+			continue
+		}
+
+		// Format rule for API (convert sources/destinations to string arrays)
+		formatted, err := FormatRuleForAPI(ruleMap)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("formatting rule: %w", err))
+			continue
+		}
+		rulesMap[i] = formatted
+	}
+
+	body := map[string]interface{}{
+		"name":        policy.Name,
+		"description": policy.Description,
+		"enabled":     policy.Enabled,
+		"rules":       rulesMap,
+	}
+	if policy.SourcePostureChecks != nil {
+		body["source_posture_checks"] = []string(policy.SourcePostureChecks)
+	}
+	return body, errs
+}
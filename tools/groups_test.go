@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
@@ -1085,7 +1086,13 @@ func createMockReplacementServer(policies []NetbirdPolicy, oldGroupID, newGroupI
 	// Store updated policies
 	updatedPolicies := make(map[string]NetbirdPolicy)
 	
+	// Bulk helpers update policies concurrently
+	var mu sync.Mutex
+	
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		
 		w.Header().Set("Content-Type", "application/json")
 		
 		// Handle GET /policies
@@ -1599,7 +1606,13 @@ func createMockForceDeleteServer(policies []NetbirdPolicy, groupID string) *http
 	updatedPolicies := make(map[string]NetbirdPolicy)
 	deletedPolicies := make(map[string]bool)
 	
+	// Bulk helpers update policies concurrently
+	var mu sync.Mutex
+	
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		
 		w.Header().Set("Content-Type", "application/json")
 		
 		// Handle GET /policies
@@ -1688,12 +1701,29 @@ func createMockForceDeleteServer(policies []NetbirdPolicy, groupID string) *http
 			return
 		}
 		
+		// Handle GET /groups/{id}
+		if r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/groups/") {
+			groupID := strings.TrimPrefix(r.URL.Path, "/groups/")
+			_ = json.NewEncoder(w).Encode(NetbirdGroup{ID: groupID, Name: groupID})
+			return
+		}
+		
 		// Handle DELETE /groups/{id}
 		if r.Method == "DELETE" && strings.HasPrefix(r.URL.Path, "/groups/") {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 		
+		// Nothing else references the group
+		if r.Method == "GET" && r.URL.Path == "/dns/settings" {
+			_ = json.NewEncoder(w).Encode(netbirdDNSSettings{})
+			return
+		}
+		if r.Method == "GET" {
+			_ = json.NewEncoder(w).Encode([]any{})
+			return
+		}
+		
 		http.NotFound(w, r)
 	}))
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/invopop/jsonschema"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -20,13 +21,18 @@ type PortRangeMapping struct {
 type IngressPortRange struct {
 	Start    int    `json:"start"`
 	End      int    `json:"end"`
-	Protocol string `json:"protocol"`
+	Protocol string `json:"protocol" jsonschema:"enum=tcp,enum=udp,enum=tcp/udp"`
+}
+
+// JSONSchemaExtend limits start and end to valid port numbers
+func (IngressPortRange) JSONSchemaExtend(schema *jsonschema.Schema) {
+	limitPorts(schema, "start", "end")
 }
 
 // DirectPort represents direct port configuration
 type DirectPort struct {
-	Count    int    `json:"count"`
-	Protocol string `json:"protocol"`
+	Count    int    `json:"count" jsonschema:"minimum=1"`
+	Protocol string `json:"protocol" jsonschema:"enum=tcp,enum=udp,enum=tcp/udp"`
 }
 
 // NetbirdPortAllocations represents an ingress port allocation
@@ -42,6 +48,7 @@ type NetbirdPortAllocations struct {
 
 type ListNetbirdPortAllocationsParams struct {
 	PeerID string `json:"peer_id" jsonschema:"required,description=The ID of the peer to get port allocations for"`
+	ListOptions
 }
 
 func listNetbirdPortAllocations(ctx context.Context, args ListNetbirdPortAllocationsParams) ([]NetbirdPortAllocations, error) {
@@ -58,7 +65,8 @@ func listNetbirdPortAllocations(ctx context.Context, args ListNetbirdPortAllocat
 var ListNetbirdPortAllocations = mcpnetbird.MustTool(
 	"list_netbird_port_allocations",
 	"List all Netbird port allocations",
-	listNetbirdPortAllocations,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdPortAllocations),
 )
 
 type CreateNetbirdPortAllocationParams struct {
@@ -95,6 +103,7 @@ func createNetbirdPortAllocation(ctx context.Context, args CreateNetbirdPortAllo
 var CreateNetbirdPortAllocation = mcpnetbird.MustTool(
 	"create_netbird_port_allocation",
 	"Create a new Netbird port allocation",
+	mcpnetbird.AdditiveTool,
 	createNetbirdPortAllocation,
 )
 
@@ -117,6 +126,7 @@ func getNetbirdPortAllocation(ctx context.Context, args GetNetbirdPortAllocation
 var GetNetbirdPortAllocation = mcpnetbird.MustTool(
 	"get_netbird_port_allocation",
 	"Get a specific Netbird port allocation by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdPortAllocation,
 )
 
@@ -158,12 +168,14 @@ func updateNetbirdPortAllocation(ctx context.Context, args UpdateNetbirdPortAllo
 var UpdateNetbirdPortAllocation = mcpnetbird.MustTool(
 	"update_netbird_port_allocation",
 	"Update an existing Netbird port allocation",
+	mcpnetbird.UpdateTool,
 	updateNetbirdPortAllocation,
 )
 
 type DeleteNetbirdPortAllocationParams struct {
 	PeerID       string `json:"peer_id" jsonschema:"required,description=The ID of the peer"`
 	AllocationID string `json:"allocation_id" jsonschema:"required,description=The ID of the port allocation to delete"`
+	Confirmation
 }
 
 func deleteNetbirdPortAllocation(ctx context.Context, args DeleteNetbirdPortAllocationParams) (map[string]string, error) {
@@ -178,8 +190,14 @@ func deleteNetbirdPortAllocation(ctx context.Context, args DeleteNetbirdPortAllo
 
 var DeleteNetbirdPortAllocation = mcpnetbird.MustTool(
 	"delete_netbird_port_allocation",
-	"Delete a Netbird port allocation",
-	deleteNetbirdPortAllocation,
+	"Delete a Netbird port allocation. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdPortAllocationParams) string {
+			return "/peers/" + args.PeerID + "/ingress/ports/" + args.AllocationID
+		}),
+		deleteNetbirdPortAllocation,
+	),
 )
 
 func AddNetbirdPortAllocationTools(mcp *server.MCPServer) {
@@ -0,0 +1,407 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxJournaledOperations bounds how many operations are kept for rollback.
+// The oldest operation is forgotten when a new one is started.
+var maxJournaledOperations = 100
+
+const (
+	journalKindPolicy          = "policy"
+	journalKindGroup           = "group"
+	journalKindRoute           = "route"
+	journalKindNameserver      = "nameserver"
+	journalKindNetworkResource = "network_resource"
+	journalKindNetworkRouter   = "network_router"
+	journalKindSetupKey        = "setup_key"
+	journalKindUser            = "user"
+	journalKindDNSSettings     = "dns_settings"
+	journalKindAccount         = "account"
+	journalKindPostureCheck    = "posture_check"
+
+	journalActionUpdate = "update"
+	journalActionDelete = "delete"
+)
+
+// JournalEntry records the state of one object before a bulk operation changed it
+type JournalEntry struct {
+	Kind     string `json:"kind"`   // "policy", "group" or another journalKind
+	Action   string `json:"action"` // "update" or "delete"
+	ID       string `json:"id"`
+	PreImage any    `json:"pre_image"`
+	applied  bool   // the write succeeded, so rollback must undo it
+	restored bool   // a rollback has undone the write
+}
+
+// Operation is the change journal of a single bulk operation. Entries are
+// recorded before each PUT or DELETE and replayed in reverse order on rollback.
+type Operation struct {
+	ID         string         `json:"operation_id"`
+	Name       string         `json:"name"`
+	CreatedAt  time.Time      `json:"created_at"`
+	Entries    []JournalEntry `json:"entries"`
+	RolledBack bool           `json:"rolled_back"`
+
+	mu        sync.Mutex
+	owner     string            // hash of the API token that started the operation
+	recreated map[string]string // old ID -> new ID of objects recreated by rollbacks
+}
+
+// groupPreImage is the part of a group needed to recreate it. Resources are
+// decoded with their type, which the create endpoint requires.
+type groupPreImage struct {
+	ID        string               `json:"id"`
+	Name      string               `json:"name"`
+	Peers     []NetbirdGroupMember `json:"peers"`
+	Resources []GroupResource      `json:"resources"`
+}
+
+// objectPreImage is the body that restores an object other than a policy or
+// group: an update of Path, or for a deleted object a create in the collection
+// above it. GroupFields name the body fields holding group ID lists, which are
+// remapped on rollback like policy groups are; nested fields are dotted paths
+// such as "settings.extra.network_traffic_logs_groups".
+type objectPreImage struct {
+	Path        string         `json:"path"`
+	Body        map[string]any `json:"body"`
+	GroupFields []string       `json:"group_fields,omitempty"`
+}
+
+// groupUpdateBody returns the request body that creates group, or sets an
+// existing group to it
+func groupUpdateBody(group groupPreImage) map[string]interface{} {
+	peers := make([]string, 0, len(group.Peers))
+	for _, peer := range group.Peers {
+		peers = append(peers, peer.ID)
+	}
+	resources := group.Resources
+	if resources == nil {
+		resources = []GroupResource{}
+	}
+	return map[string]interface{}{
+		"name":      group.Name,
+		"peers":     peers,
+		"resources": resources,
+	}
+}
+
+// RollbackResult reports what a rollback restored
+type RollbackResult struct {
+	OperationID string            `json:"operation_id"`
+	Restored    []string          `json:"restored"`
+	Recreated   map[string]string `json:"recreated,omitempty"` // old ID -> new ID of deleted objects
+	Errors      []string          `json:"errors,omitempty"`
+}
+
+type operationStore struct {
+	mu    sync.Mutex
+	ops   map[string]*Operation
+	order []string
+}
+
+var operations = &operationStore{ops: make(map[string]*Operation)}
+
+// apiTokenHash hashes the API token so the raw token is not kept in memory
+func apiTokenHash(ctx context.Context) string {
+	sum := sha256.Sum256([]byte(mcpnetbird.NetbirdAPIKeyFromContext(ctx)))
+	return hex.EncodeToString(sum[:])
+}
+
+// startOperation creates and stores an empty journal for a bulk operation
+func startOperation(ctx context.Context, name string) *Operation {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	op := &Operation{
+		ID:        "op-" + hex.EncodeToString(id),
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		Entries:   []JournalEntry{},
+		owner:     apiTokenHash(ctx),
+	}
+
+	operations.mu.Lock()
+	defer operations.mu.Unlock()
+
+	operations.ops[op.ID] = op
+	operations.order = append(operations.order, op.ID)
+	for len(operations.order) > maxJournaledOperations {
+		delete(operations.ops, operations.order[0])
+		operations.order = operations.order[1:]
+	}
+	return op
+}
+
+// lookupOperation returns the operation with id if it was started with the same API token
+func lookupOperation(ctx context.Context, id string) (*Operation, error) {
+	operations.mu.Lock()
+	op, ok := operations.ops[id]
+	operations.mu.Unlock()
+
+	if !ok || op.owner != apiTokenHash(ctx) {
+		return nil, fmt.Errorf("operation %s not found", id)
+	}
+	return op, nil
+}
+
+// record adds the pre-image of an object about to be written and returns its
+// index. Call applied with the index once the write has succeeded.
+func (op *Operation) record(kind, action, id string, preImage any) int {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.Entries = append(op.Entries, JournalEntry{Kind: kind, Action: action, ID: id, PreImage: preImage})
+	return len(op.Entries) - 1
+}
+
+// applied marks the write recorded at index i as done
+func (op *Operation) applied(i int) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.Entries[i].applied = true
+}
+
+// Rollback restores every applied entry in reverse order. Deleted objects are
+// recreated and get new IDs; policies restored afterwards refer to recreated
+// groups by their new IDs. Entries that can't be restored are reported in the
+// result's errors and the operation is only marked rolled back once every entry
+// is restored, so calling Rollback again retries the remaining entries.
+func (op *Operation) Rollback(ctx context.Context, client *mcpnetbird.NetbirdClient) (*RollbackResult, error) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	if op.RolledBack {
+		return nil, fmt.Errorf("operation %s was already rolled back", op.ID)
+	}
+	if op.recreated == nil {
+		op.recreated = make(map[string]string)
+	}
+
+	result := &RollbackResult{
+		OperationID: op.ID,
+		Restored:    []string{},
+		Recreated:   make(map[string]string),
+	}
+
+	for i := len(op.Entries) - 1; i >= 0; i-- {
+		entry := op.Entries[i]
+		if !entry.applied || entry.restored {
+			continue
+		}
+
+		newID, err := restoreEntry(ctx, client, entry, op.recreated)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s %s: %v", entry.Kind, entry.ID, err))
+			continue
+		}
+		op.Entries[i].restored = true
+		if newID != "" {
+			op.recreated[entry.ID] = newID
+			result.Recreated[entry.ID] = newID
+		}
+		result.Restored = append(result.Restored, entry.Kind+"/"+entry.ID)
+	}
+
+	op.RolledBack = len(result.Errors) == 0
+	return result, nil
+}
+
+// restoreEntry writes the pre-image of entry back and returns the new ID of a recreated object
+func restoreEntry(ctx context.Context, client *mcpnetbird.NetbirdClient, entry JournalEntry, remap map[string]string) (string, error) {
+	switch preImage := entry.PreImage.(type) {
+	case NetbirdPolicy:
+		body, errs := policyUpdateBody(remapPolicyGroups(preImage, remap))
+		if len(errs) > 0 {
+			return "", errs[0]
+		}
+		if entry.Action == journalActionUpdate {
+			if err := client.Put(ctx, "/policies/"+entry.ID, body, nil); err != nil {
+				return "", fmt.Errorf("restoring: %w", err)
+			}
+			return "", nil
+		}
+		var created NetbirdPolicy
+		if err := client.Post(ctx, "/policies", body, &created); err != nil {
+			return "", fmt.Errorf("recreating: %w", err)
+		}
+		return created.ID, nil
+
+	case groupPreImage:
+		body := groupUpdateBody(preImage)
+		if entry.Action == journalActionUpdate {
+			if err := client.Put(ctx, "/groups/"+entry.ID, body, nil); err != nil {
+				return "", fmt.Errorf("restoring: %w", err)
+			}
+			return "", nil
+		}
+		var created NetbirdGroup
+		if err := client.Post(ctx, "/groups", body, &created); err != nil {
+			return "", fmt.Errorf("recreating: %w", err)
+		}
+		return created.ID, nil
+
+	case objectPreImage:
+		body := make(map[string]any, len(preImage.Body))
+		for k, v := range preImage.Body {
+			body[k] = v
+		}
+		for _, field := range preImage.GroupFields {
+			remapGroupField(body, strings.Split(field, "."), remap)
+		}
+		if entry.Action == journalActionUpdate {
+			if err := client.Put(ctx, preImage.Path, body, nil); err != nil {
+				return "", fmt.Errorf("restoring: %w", err)
+			}
+			return "", nil
+		}
+		var created struct {
+			ID string `json:"id"`
+		}
+		if err := client.Post(ctx, path.Dir(preImage.Path), body, &created); err != nil {
+			return "", fmt.Errorf("recreating: %w", err)
+		}
+		return created.ID, nil
+	}
+
+	return "", fmt.Errorf("unsupported pre-image %T", entry.PreImage)
+}
+
+// remapGroupField remaps the group IDs at the dotted field path of body in
+// place. Nested maps along the path are copied so the pre-image is unchanged.
+func remapGroupField(body map[string]any, field []string, remap map[string]string) {
+	if len(field) == 1 {
+		if ids, ok := body[field[0]].([]string); ok {
+			body[field[0]] = remapIDs(ids, remap)
+		}
+		return
+	}
+	nested, ok := body[field[0]].(map[string]any)
+	if !ok {
+		return
+	}
+	nested = maps.Clone(nested)
+	body[field[0]] = nested
+	remapGroupField(nested, field[1:], remap)
+}
+
+// remapPolicyGroups returns a copy of policy whose rules and posture checks share
+// no slices or maps with the original, with group and posture check IDs replaced
+// according to remap. A nil remap makes a plain deep copy.
+func remapPolicyGroups(policy NetbirdPolicy, remap map[string]string) NetbirdPolicy {
+	remapGroups := func(groups []NetbirdPeerGroup) []NetbirdPeerGroup {
+		out := make([]NetbirdPeerGroup, len(groups))
+		for i, group := range groups {
+			if newID, ok := remap[group.ID]; ok {
+				group.ID = newID
+			}
+			out[i] = group
+		}
+		return out
+	}
+
+	rules := make([]NetbirdPolicyRule, len(policy.Rules))
+	for i, rule := range policy.Rules {
+		rule.Sources = remapGroups(rule.Sources)
+		rule.Destinations = remapGroups(rule.Destinations)
+		if rule.AuthorizedGroups != nil {
+			authorized := make(map[string][]string, len(*rule.AuthorizedGroups))
+			for groupID, users := range *rule.AuthorizedGroups {
+				if newID, ok := remap[groupID]; ok {
+					groupID = newID
+				}
+				authorized[groupID] = users
+			}
+			rule.AuthorizedGroups = &authorized
+		}
+		rules[i] = rule
+	}
+	policy.Rules = rules
+	if policy.SourcePostureChecks != nil {
+		policy.SourcePostureChecks = remapIDs(policy.SourcePostureChecks, remap)
+	}
+	return policy
+}
+
+// remapIDs returns a copy of ids with IDs replaced according to remap
+func remapIDs(ids []string, remap map[string]string) []string {
+	out := make([]string, len(ids))
+	for i, id := range ids {
+		if newID, ok := remap[id]; ok {
+			id = newID
+		}
+		out[i] = id
+	}
+	return out
+}
+
+type RollbackNetbirdOperationParams struct {
+	OperationID string `json:"operation_id" jsonschema:"required,description=The operation ID returned by a bulk operation such as replace_group_in_policies or merge_netbird_groups or delete_netbird_group with force=true"`
+	Confirmation
+}
+
+// describeRollback lists the changes a rollback would undo, most recent first
+func describeRollback(ctx context.Context, args RollbackNetbirdOperationParams) (*Impact, error) {
+	op, err := lookupOperation(ctx, args.OperationID)
+	if err != nil {
+		return nil, err
+	}
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	undo := make([]JournalEntry, 0, len(op.Entries))
+	for i := len(op.Entries) - 1; i >= 0; i-- {
+		if op.Entries[i].applied && !op.Entries[i].restored {
+			undo = append(undo, op.Entries[i])
+		}
+	}
+	impact := &Impact{Action: fmt.Sprintf("roll back %s (%s)", op.ID, op.Name), Affected: undo}
+	if op.RolledBack {
+		impact.Warnings = append(impact.Warnings, "the operation was already rolled back")
+	} else if slices.ContainsFunc(op.Entries, func(e JournalEntry) bool { return e.restored }) {
+		impact.Warnings = append(impact.Warnings, "an earlier rollback of the operation was incomplete; only the changes it couldn't undo are retried")
+	}
+	return impact, nil
+}
+
+func rollbackNetbirdOperation(ctx context.Context, args RollbackNetbirdOperationParams) (*RollbackResult, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	op, err := lookupOperation(ctx, args.OperationID)
+	if err != nil {
+		return nil, err
+	}
+	return op.Rollback(ctx, client)
+}
+
+var RollbackNetbirdOperation = mcpnetbird.MustTool(
+	"rollback_netbird_operation",
+	"Undo a bulk operation by restoring every object it changed to its previous state, in reverse order. Deleted objects are recreated with new IDs, which are reported in the result. Only the most recent operations started with the same API token can be rolled back. The first call returns an impact summary and a confirmation_token; call again with the token to roll back.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describeRollback, rollbackNetbirdOperation),
+)
+
+func AddNetbirdJournalTools(mcp *server.MCPServer) {
+	RollbackNetbirdOperation.Register(mcp)
+}
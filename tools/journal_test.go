@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+type journalRequest struct {
+	method string
+	path   string
+	body   map[string]interface{}
+}
+
+// createMockJournalServer serves the given policies and a "target-group" group,
+// records every write in order and fails PUTs to the policies in failPut
+func createMockJournalServer(policies []NetbirdPolicy, failPut map[string]bool) (*httptest.Server, func() []journalRequest) {
+	var mu sync.Mutex
+	var writes []journalRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodGet {
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			writes = append(writes, journalRequest{method: r.Method, path: r.URL.Path, body: body})
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/policies":
+			_ = json.NewEncoder(w).Encode(policies)
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/policies/"):
+			for _, policy := range policies {
+				if "/policies/"+policy.ID == r.URL.Path {
+					_ = json.NewEncoder(w).Encode(policy)
+					return
+				}
+			}
+			http.NotFound(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == "/groups/target-group":
+			_, _ = w.Write([]byte(`{"id":"target-group","name":"Target","peers":[{"id":"peer-1","name":"a"}],"resources":[{"id":"res-1","type":"host"}]}`))
+		case r.Method == http.MethodPut && failPut[strings.TrimPrefix(r.URL.Path, "/policies/")]:
+			http.Error(w, "conflict", http.StatusConflict)
+		case r.Method == http.MethodPost && r.URL.Path == "/groups":
+			_, _ = w.Write([]byte(`{"id":"recreated-group"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/policies":
+			_, _ = w.Write([]byte(`{"id":"recreated-policy"}`))
+		case r.Method == http.MethodPut || r.Method == http.MethodDelete:
+			_, _ = w.Write([]byte("{}"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	return server, func() []journalRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]journalRequest(nil), writes...)
+	}
+}
+
+func journalTestPolicies() []NetbirdPolicy {
+	rule := func(id string, sources ...string) NetbirdPolicyRule {
+		groups := make([]NetbirdPeerGroup, len(sources))
+		for i, source := range sources {
+			groups[i] = NetbirdPeerGroup{ID: source}
+		}
+		return NetbirdPolicyRule{
+			ID:           id,
+			Name:         id,
+			Enabled:      true,
+			Action:       "accept",
+			Protocol:     "all",
+			Sources:      groups,
+			Destinations: []NetbirdPeerGroup{{ID: "dest"}},
+		}
+	}
+	return []NetbirdPolicy{
+		{ID: "policy-1", Name: "shared", Enabled: true, Rules: []NetbirdPolicyRule{rule("rule-1", "target-group", "other")}},
+		{ID: "policy-2", Name: "only-target", Enabled: true, Rules: []NetbirdPolicyRule{rule("rule-2", "target-group")}},
+		{ID: "policy-3", Name: "failing", Enabled: true, Rules: []NetbirdPolicyRule{rule("rule-3", "target-group", "other")}},
+	}
+}
+
+// ruleSources returns the source group IDs of the first rule in a PUT or POST body
+func ruleSources(body map[string]interface{}) []string {
+	rules, _ := body["rules"].([]interface{})
+	if len(rules) == 0 {
+		return nil
+	}
+	rule, _ := rules[0].(map[string]interface{})
+	sources, _ := rule["sources"].([]interface{})
+	ids := make([]string, len(sources))
+	for i, source := range sources {
+		ids[i], _ = source.(string)
+	}
+	return ids
+}
+
+func TestDeleteGroupForce_RollsBackOnFailure(t *testing.T) {
+	server, writes := createMockJournalServer(journalTestPolicies(), map[string]bool{"policy-3": true})
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	result, err := DeleteGroupForce(ctx, "target-group")
+	if err == nil {
+		t.Fatal("expected error when a policy cannot be updated")
+	}
+	if result == nil || result.Deleted || !result.RolledBack {
+		t.Fatalf("expected rolled back result, got %+v", result)
+	}
+
+	// policy-1 is updated and then restored with its original sources; nothing
+	// is deleted once an update has failed
+	var restoredPolicy1 bool
+	var putsPolicy1 int
+	for _, req := range writes() {
+		switch {
+		case req.method == http.MethodDelete:
+			t.Errorf("unexpected delete of %s after a failed policy update", req.path)
+		case req.method == http.MethodPut && req.path == "/policies/policy-1":
+			putsPolicy1++
+			sources := ruleSources(req.body)
+			restoredPolicy1 = len(sources) == 2 && sources[0] == "target-group"
+		}
+	}
+	if putsPolicy1 != 2 || !restoredPolicy1 {
+		t.Errorf("expected policy-1 to be updated then restored, got %d PUTs (restored=%v)", putsPolicy1, restoredPolicy1)
+	}
+}
+
+func TestRollbackNetbirdOperation_AfterForceDelete(t *testing.T) {
+	policies := journalTestPolicies()[:2]
+	server, writes := createMockJournalServer(policies, nil)
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	result, err := DeleteGroupForce(ctx, "target-group")
+	if err != nil || !result.Deleted {
+		t.Fatalf("expected group to be deleted, got %+v, %v", result, err)
+	}
+	before := len(writes())
+
+	if _, err := rollbackNetbirdOperation(mcpnetbird.WithNetbirdAPIKey(context.Background(), "other-token"),
+		RollbackNetbirdOperationParams{OperationID: result.OperationID}); err == nil {
+		t.Error("expected operations of another token to be hidden")
+	}
+
+	rollback, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rollback.Errors) != 0 || len(rollback.Restored) != 3 {
+		t.Fatalf("expected 3 restored objects, got %+v", rollback)
+	}
+	if rollback.Recreated["target-group"] != "recreated-group" {
+		t.Errorf("expected group to be recreated, got %v", rollback.Recreated)
+	}
+
+	// The group is recreated first and policies refer to it by its new ID
+	restores := writes()[before:]
+	if restores[0].method != http.MethodPost || restores[0].path != "/groups" {
+		t.Fatalf("expected group to be recreated first, got %s %s", restores[0].method, restores[0].path)
+	}
+	if peers, _ := restores[0].body["peers"].([]interface{}); len(peers) != 1 || peers[0] != "peer-1" {
+		t.Errorf("expected group peers to be restored, got %v", restores[0].body["peers"])
+	}
+	for _, req := range restores[1:] {
+		if sources := ruleSources(req.body); len(sources) == 0 || sources[0] != "recreated-group" {
+			t.Errorf("%s %s: expected sources to use the recreated group, got %v", req.method, req.path, sources)
+		}
+	}
+
+	if _, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID}); err == nil {
+		t.Error("expected error when rolling back twice")
+	}
+	if _, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: "op-missing"}); err == nil {
+		t.Error("expected error for unknown operation")
+	}
+}
+
+func TestReplaceGroupInPolicies_Rollback(t *testing.T) {
+	server, writes := createMockJournalServer(journalTestPolicies(), map[string]bool{"policy-3": true})
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	result, err := ReplaceGroupInPolicies(ctx, "target-group", "new-group")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.UpdatedPolicyIDs) != 2 || result.Errors["policy-3"] == "" {
+		t.Fatalf("expected partial update, got %+v", result)
+	}
+	before := len(writes())
+
+	rollback, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The failed PUT to policy-3 was never applied and is not restored
+	if len(rollback.Restored) != 2 {
+		t.Errorf("expected 2 restored policies, got %v", rollback.Restored)
+	}
+	for _, req := range writes()[before:] {
+		if req.method != http.MethodPut || ruleSources(req.body)[0] != "target-group" {
+			t.Errorf("expected PUT restoring target-group, got %s %s %v", req.method, req.path, ruleSources(req.body))
+		}
+	}
+}
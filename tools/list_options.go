@@ -0,0 +1,389 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListOptions holds the filtering, projection, sorting and pagination arguments
+// shared by every list tool. Embed it in a list tool's params struct and wrap the
+// handler with withListOptions to enable it.
+type ListOptions struct {
+	Filter map[string]string `json:"filter,omitempty" jsonschema:"description=Filters keyed by JSON field name or dotted path (e.g. os\\, connected\\, groups.name). Values match case-insensitively by equality; prefix with ~ for a substring match or wrap in /.../ for a regular expression"`
+	Fields []string          `json:"fields,omitempty" jsonschema:"description=Only return these JSON fields or dotted paths"`
+	Sort   string            `json:"sort,omitempty" jsonschema:"description=Field to sort by; prefix with - for descending order"`
+	Limit  int               `json:"limit,omitempty" jsonschema:"description=Maximum number of items to return. When any list option is set the result is an object with items\\, total and next_cursor"`
+	Cursor string            `json:"cursor,omitempty" jsonschema:"description=The next_cursor value returned by a previous call"`
+}
+
+// ListResult is returned by list tools when any ListOptions field is set
+type ListResult struct {
+	Items      []map[string]any `json:"items"`
+	Total      int              `json:"total"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func (o ListOptions) listOptions() ListOptions {
+	return o
+}
+
+// IsZero reports whether no list option is set
+func (o ListOptions) IsZero() bool {
+	return len(o.Filter) == 0 && len(o.Fields) == 0 && o.Sort == "" && o.Limit == 0 && o.Cursor == ""
+}
+
+// listParams is implemented by every params struct that embeds ListOptions
+type listParams interface {
+	listOptions() ListOptions
+}
+
+// withListOptions wraps a typed list handler so that the embedded ListOptions are
+// applied to its result. Without options the typed slice is returned unchanged.
+func withListOptions[P listParams, T any](list func(context.Context, P) ([]T, error)) func(context.Context, P) (any, error) {
+	return func(ctx context.Context, args P) (any, error) {
+		items, err := list(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		opts := args.listOptions()
+		if opts.IsZero() {
+			return items, nil
+		}
+		return ApplyListOptions(items, opts)
+	}
+}
+
+// ApplyListOptions filters, sorts, paginates and projects items according to opts.
+// Field names are validated against the JSON tags of T.
+func ApplyListOptions[T any](items []T, opts ListOptions) (*ListResult, error) {
+	itemType := reflect.TypeOf((*T)(nil)).Elem()
+
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+	offset, err := decodeCursor(opts.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	matchers := make([]fieldMatcher, 0, len(opts.Filter))
+	for path, expr := range opts.Filter {
+		if err := validateFieldPath(itemType, path); err != nil {
+			return nil, fmt.Errorf("filter: %w", err)
+		}
+		m, err := newFieldMatcher(path, expr)
+		if err != nil {
+			return nil, fmt.Errorf("filter %s: %w", path, err)
+		}
+		matchers = append(matchers, m)
+	}
+	for _, field := range opts.Fields {
+		if err := validateFieldPath(itemType, field); err != nil {
+			return nil, fmt.Errorf("fields: %w", err)
+		}
+	}
+	sortField, descending := strings.TrimPrefix(opts.Sort, "-"), strings.HasPrefix(opts.Sort, "-")
+	if sortField != "" {
+		if err := validateFieldPath(itemType, sortField); err != nil {
+			return nil, fmt.Errorf("sort: %w", err)
+		}
+	}
+
+	filtered := make([]map[string]any, 0, len(items))
+	for i, item := range items {
+		m, err := structToMap(item)
+		if err != nil {
+			return nil, fmt.Errorf("converting item %d: %w", i, err)
+		}
+		matched := true
+		for _, matcher := range matchers {
+			if !matcher(m) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filtered = append(filtered, m)
+		}
+	}
+
+	if sortField != "" {
+		segs := strings.Split(sortField, ".")
+		sort.SliceStable(filtered, func(i, j int) bool {
+			a, b := firstValue(filtered[i], segs), firstValue(filtered[j], segs)
+			if descending {
+				return compareValues(b, a) < 0
+			}
+			return compareValues(a, b) < 0
+		})
+	}
+
+	result := &ListResult{Items: []map[string]any{}, Total: len(filtered)}
+	if offset > len(filtered) {
+		offset = len(filtered)
+	}
+	end := len(filtered)
+	if opts.Limit > 0 && offset+opts.Limit < end {
+		end = offset + opts.Limit
+		result.NextCursor = encodeCursor(end)
+	}
+
+	for _, m := range filtered[offset:end] {
+		if len(opts.Fields) > 0 {
+			m = projectFields(m, opts.Fields)
+		}
+		result.Items = append(result.Items, m)
+	}
+
+	return result, nil
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return offset, nil
+}
+
+// validateFieldPath checks that a dotted path of JSON field names exists on t
+func validateFieldPath(t reflect.Type, path string) error {
+	current := t
+	for _, seg := range strings.Split(path, ".") {
+		for current.Kind() == reflect.Ptr || current.Kind() == reflect.Slice || current.Kind() == reflect.Array {
+			current = current.Elem()
+		}
+		switch current.Kind() {
+		case reflect.Interface:
+			// Untyped values can hold anything
+			return nil
+		case reflect.Map:
+			current = current.Elem()
+			continue
+		case reflect.Struct:
+			field, ok := jsonField(current, seg)
+			if !ok {
+				return fmt.Errorf("unknown field '%s' in '%s'", seg, path)
+			}
+			current = field.Type
+		default:
+			return fmt.Errorf("field '%s' in '%s' has no sub-fields", seg, path)
+		}
+	}
+	return nil
+}
+
+// jsonField finds the struct field whose JSON name is name, including promoted fields
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			if inner, ok := jsonField(f.Type, name); ok {
+				return inner, true
+			}
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		if tag == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldMatcher matches the values found at a dotted path against a filter expression
+type fieldMatcher func(map[string]any) bool
+
+func newFieldMatcher(path, expr string) (fieldMatcher, error) {
+	segs := strings.Split(path, ".")
+	var matchValue func(string) bool
+
+	switch {
+	case len(expr) >= 2 && strings.HasPrefix(expr, "/") && strings.HasSuffix(expr, "/"):
+		re, err := regexp.Compile(expr[1 : len(expr)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		matchValue = re.MatchString
+	case strings.HasPrefix(expr, "~"):
+		needle := strings.ToLower(strings.TrimPrefix(expr, "~"))
+		matchValue = func(s string) bool {
+			return strings.Contains(strings.ToLower(s), needle)
+		}
+	default:
+		matchValue = func(s string) bool {
+			return strings.EqualFold(s, expr)
+		}
+	}
+
+	return func(item map[string]any) bool {
+		for _, v := range lookupPath(item, segs) {
+			// Nested objects such as peer groups match on their id or name
+			if obj, ok := v.(map[string]any); ok {
+				if matchValue(valueString(obj["id"])) || matchValue(valueString(obj["name"])) {
+					return true
+				}
+				continue
+			}
+			if matchValue(valueString(v)) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// lookupPath returns every value found at a dotted path, flattening arrays on the way
+func lookupPath(v any, segs []string) []any {
+	switch vv := v.(type) {
+	case []any:
+		var out []any
+		for _, el := range vv {
+			out = append(out, lookupPath(el, segs)...)
+		}
+		return out
+	case map[string]any:
+		if len(segs) == 0 {
+			return []any{vv}
+		}
+		child, ok := vv[segs[0]]
+		if !ok {
+			return nil
+		}
+		return lookupPath(child, segs[1:])
+	default:
+		if len(segs) == 0 {
+			return []any{vv}
+		}
+		return nil
+	}
+}
+
+func firstValue(item map[string]any, segs []string) any {
+	values := lookupPath(item, segs)
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// valueString renders a decoded JSON scalar for matching
+func valueString(v any) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return vv
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vv)
+	default:
+		return fmt.Sprint(vv)
+	}
+}
+
+// compareValues orders decoded JSON values; nil sorts last
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1
+			case av > bv:
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if bv, ok := b.(bool); ok {
+			switch {
+			case av == bv:
+				return 0
+			case !av:
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(strings.ToLower(valueString(a)), strings.ToLower(valueString(b)))
+}
+
+// projectFields copies only the requested dotted paths from item
+func projectFields(item map[string]any, fields []string) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, field := range fields {
+		copyPath(out, item, strings.Split(field, "."))
+	}
+	return out
+}
+
+func copyPath(dst, src map[string]any, segs []string) {
+	v, ok := src[segs[0]]
+	if !ok {
+		return
+	}
+	if len(segs) == 1 {
+		dst[segs[0]] = v
+		return
+	}
+	switch vv := v.(type) {
+	case map[string]any:
+		child, _ := dst[segs[0]].(map[string]any)
+		if child == nil {
+			child = make(map[string]any)
+			dst[segs[0]] = child
+		}
+		copyPath(child, vv, segs[1:])
+	case []any:
+		children, _ := dst[segs[0]].([]any)
+		if children == nil {
+			children = make([]any, len(vv))
+			dst[segs[0]] = children
+		}
+		for i, el := range vv {
+			obj, ok := el.(map[string]any)
+			if !ok {
+				continue
+			}
+			child, _ := children[i].(map[string]any)
+			if child == nil {
+				child = make(map[string]any)
+				children[i] = child
+			}
+			copyPath(child, obj, segs[1:])
+		}
+	}
+}
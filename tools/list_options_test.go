@@ -0,0 +1,204 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func testListPeers() []NetbirdPeer {
+	return []NetbirdPeer{
+		{ID: "peer-1", Name: "web-1", OS: "linux", Connected: true, IP: "100.64.0.1",
+			Groups: []NetbirdPeerGroup{{ID: "g-web", Name: "web"}}},
+		{ID: "peer-2", Name: "laptop", OS: "darwin", Connected: false, IP: "100.64.0.2",
+			Groups: []NetbirdPeerGroup{{ID: "g-dev", Name: "dev"}}},
+		{ID: "peer-3", Name: "web-2", OS: "linux", Connected: false, IP: "100.64.0.3",
+			Groups: []NetbirdPeerGroup{{ID: "g-web", Name: "web"}, {ID: "g-dev", Name: "dev"}}},
+	}
+}
+
+func resultIDs(result *ListResult) []string {
+	ids := make([]string, 0, len(result.Items))
+	for _, item := range result.Items {
+		id, _ := item["id"].(string)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestApplyListOptions_Filter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter map[string]string
+		want   []string
+	}{
+		{"equality", map[string]string{"os": "linux"}, []string{"peer-1", "peer-3"}},
+		{"equality is case-insensitive", map[string]string{"os": "LINUX"}, []string{"peer-1", "peer-3"}},
+		{"boolean", map[string]string{"connected": "true"}, []string{"peer-1"}},
+		{"substring", map[string]string{"name": "~web"}, []string{"peer-1", "peer-3"}},
+		{"regex", map[string]string{"ip": "/\\.[23]$/"}, []string{"peer-2", "peer-3"}},
+		{"nested path", map[string]string{"groups.name": "dev"}, []string{"peer-2", "peer-3"}},
+		{"nested object by name", map[string]string{"groups": "web"}, []string{"peer-1", "peer-3"}},
+		{"multiple filters", map[string]string{"os": "linux", "groups.id": "g-dev"}, []string{"peer-3"}},
+		{"no matches", map[string]string{"os": "windows"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ApplyListOptions(testListPeers(), ListOptions{Filter: tt.filter})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := resultIDs(result)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			}
+			if result.Total != len(tt.want) {
+				t.Errorf("expected total %d, got %d", len(tt.want), result.Total)
+			}
+		})
+	}
+}
+
+func TestApplyListOptions_UnknownField(t *testing.T) {
+	cases := []ListOptions{
+		{Filter: map[string]string{"operating_system": "linux"}},
+		{Filter: map[string]string{"groups.label": "web"}},
+		{Fields: []string{"id", "nope"}},
+		{Sort: "-nope"},
+		{Filter: map[string]string{"name.first": "x"}},
+	}
+	for _, opts := range cases {
+		if _, err := ApplyListOptions(testListPeers(), opts); err == nil {
+			t.Errorf("expected error for options %+v", opts)
+		}
+	}
+}
+
+func TestApplyListOptions_InvalidRegex(t *testing.T) {
+	_, err := ApplyListOptions(testListPeers(), ListOptions{Filter: map[string]string{"name": "/[/"}})
+	if err == nil {
+		t.Fatal("expected error for invalid regular expression")
+	}
+}
+
+func TestApplyListOptions_Sort(t *testing.T) {
+	result, err := ApplyListOptions(testListPeers(), ListOptions{Sort: "name"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := resultIDs(result)
+	want := []string{"peer-2", "peer-1", "peer-3"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+
+	result, err = ApplyListOptions(testListPeers(), ListOptions{Sort: "-connected"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resultIDs(result); got[0] != "peer-1" {
+		t.Errorf("expected connected peer first, got %v", got)
+	}
+}
+
+func TestApplyListOptions_Pagination(t *testing.T) {
+	opts := ListOptions{Sort: "id", Limit: 2}
+	first, err := ApplyListOptions(testListPeers(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resultIDs(first); len(got) != 2 || got[0] != "peer-1" || got[1] != "peer-2" {
+		t.Fatalf("unexpected first page: %v", got)
+	}
+	if first.Total != 3 {
+		t.Errorf("expected total 3, got %d", first.Total)
+	}
+	if first.NextCursor == "" {
+		t.Fatal("expected next_cursor on first page")
+	}
+
+	opts.Cursor = first.NextCursor
+	second, err := ApplyListOptions(testListPeers(), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resultIDs(second); len(got) != 1 || got[0] != "peer-3" {
+		t.Fatalf("unexpected second page: %v", got)
+	}
+	if second.NextCursor != "" {
+		t.Errorf("expected no next_cursor on last page, got %q", second.NextCursor)
+	}
+
+	if _, err := ApplyListOptions(testListPeers(), ListOptions{Cursor: "not a cursor"}); err == nil {
+		t.Error("expected error for invalid cursor")
+	}
+	if _, err := ApplyListOptions(testListPeers(), ListOptions{Limit: -1}); err == nil {
+		t.Error("expected error for negative limit")
+	}
+}
+
+func TestApplyListOptions_Fields(t *testing.T) {
+	result, err := ApplyListOptions(testListPeers(), ListOptions{Fields: []string{"id", "groups.name"}, Limit: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	item := result.Items[0]
+	if len(item) != 2 {
+		t.Fatalf("expected 2 fields, got %v", item)
+	}
+	groups, ok := item["groups"].([]any)
+	if !ok || len(groups) != 1 {
+		t.Fatalf("expected projected groups, got %v", item["groups"])
+	}
+	group := groups[0].(map[string]any)
+	if len(group) != 1 || group["name"] != "web" {
+		t.Errorf("expected only group name, got %v", group)
+	}
+}
+
+func TestListNetbirdPeersTool_WithListOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(testListPeers())
+	}))
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	handler := withListOptions(listNetbirdPeers)
+
+	// Without options the typed slice is returned unchanged
+	plain, err := handler(ctx, ListNetbirdPeersParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if peers, ok := plain.([]NetbirdPeer); !ok || len(peers) != 3 {
+		t.Fatalf("expected []NetbirdPeer with 3 items, got %T", plain)
+	}
+
+	filtered, err := handler(ctx, ListNetbirdPeersParams{ListOptions: ListOptions{Filter: map[string]string{"os": "darwin"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, ok := filtered.(*ListResult)
+	if !ok {
+		t.Fatalf("expected *ListResult, got %T", filtered)
+	}
+	if got := resultIDs(result); len(got) != 1 || got[0] != "peer-2" {
+		t.Errorf("unexpected filtered result: %v", got)
+	}
+}
@@ -0,0 +1,486 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// maxMembershipWrites bounds how often a membership change is written to a group
+// whose concurrent updates keep dropping it
+var maxMembershipWrites = 3
+
+// allGroupName is the built-in group that every peer belongs to
+const allGroupName = "All"
+
+// groupMembershipChange adds members to, or removes members from, a group
+type groupMembershipChange struct {
+	AddPeers        []string
+	RemovePeers     []string
+	AddResources    []GroupResource
+	RemoveResources []string
+}
+
+// apply returns group with the change applied, and whether its members changed
+func (c groupMembershipChange) apply(group groupPreImage) (groupPreImage, bool) {
+	changed := false
+	peers := make([]NetbirdGroupMember, 0, len(group.Peers)+len(c.AddPeers))
+	for _, peer := range group.Peers {
+		if slices.Contains(c.RemovePeers, peer.ID) {
+			changed = true
+			continue
+		}
+		peers = append(peers, peer)
+	}
+	for _, id := range c.AddPeers {
+		if !slices.ContainsFunc(peers, func(p NetbirdGroupMember) bool { return p.ID == id }) {
+			peers = append(peers, NetbirdGroupMember{ID: id})
+			changed = true
+		}
+	}
+
+	resources := make([]GroupResource, 0, len(group.Resources)+len(c.AddResources))
+	for _, resource := range group.Resources {
+		if slices.Contains(c.RemoveResources, resource.ID) {
+			changed = true
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	for _, add := range c.AddResources {
+		if !slices.ContainsFunc(resources, func(r GroupResource) bool { return r.ID == add.ID }) {
+			resources = append(resources, add)
+			changed = true
+		}
+	}
+
+	group.Peers = peers
+	group.Resources = resources
+	return group, changed
+}
+
+// changeGroupMembers applies change to a group by read-modify-write, keeping its
+// name and the members the change doesn't touch, and returns the members it ends
+// up with. The update is recorded in op.
+//
+// The Netbird API has no conditional updates, so another client can replace the
+// members between the read and the write. The group is therefore read again after
+// each write, and the change is written again from that state when a concurrent
+// update dropped it, at most maxMembershipWrites times.
+func changeGroupMembers(ctx context.Context, client *mcpnetbird.NetbirdClient, op *Operation, groupID string, change groupMembershipChange) (*groupPreImage, bool, error) {
+	reader := client.Uncached()
+	changed := false
+	for writes := 0; ; writes++ {
+		var current groupPreImage
+		if err := reader.Get(ctx, "/groups/"+groupID, &current); err != nil {
+			return nil, changed, fmt.Errorf("fetching group %s: %w", groupID, err)
+		}
+		updated, differs := change.apply(current)
+		if !differs {
+			return &current, changed, nil
+		}
+		if current.Name == allGroupName {
+			return nil, changed, fmt.Errorf("group %s: the All group always contains every peer and can't be changed", groupID)
+		}
+		if writes == maxMembershipWrites {
+			return nil, changed, fmt.Errorf("group %s: concurrent updates dropped the change %d times; try again later", groupID, writes)
+		}
+
+		entry := op.record(journalKindGroup, journalActionUpdate, groupID, current)
+		if err := client.Put(ctx, "/groups/"+groupID, groupUpdateBody(updated), nil); err != nil {
+			return nil, changed, fmt.Errorf("updating group %s: %w", groupID, err)
+		}
+		op.applied(entry)
+		changed = true
+	}
+}
+
+// resolveByIDOrName returns the item whose ID is ref or, failing that, the only
+// item one of names matches case-insensitively. names are tried in order.
+func resolveByIDOrName[T any](kind, ref string, items []T, id func(T) string, names ...func(T) string) (T, error) {
+	var zero T
+	for _, item := range items {
+		if id(item) == ref {
+			return item, nil
+		}
+	}
+	for _, name := range names {
+		var matches []string
+		var match T
+		for _, item := range items {
+			if strings.EqualFold(name(item), ref) {
+				matches = append(matches, id(item))
+				match = item
+			}
+		}
+		switch {
+		case len(matches) == 1:
+			return match, nil
+		case len(matches) > 1:
+			return zero, fmt.Errorf("%s %q is ambiguous, it matches %s; use an ID", kind, ref, strings.Join(matches, ", "))
+		}
+	}
+	return zero, fmt.Errorf("%s %q not found", kind, ref)
+}
+
+// resolvePeer finds a peer by ID, name or Netbird IP
+func resolvePeer(ctx context.Context, client *mcpnetbird.NetbirdClient, ref string) (NetbirdPeer, error) {
+	var peers []NetbirdPeer
+	if err := client.Get(ctx, "/peers", &peers); err != nil {
+		return NetbirdPeer{}, fmt.Errorf("listing peers: %w", err)
+	}
+	return resolveByIDOrName("peer", ref, peers,
+		func(p NetbirdPeer) string { return p.ID },
+		func(p NetbirdPeer) string { return p.Name },
+		func(p NetbirdPeer) string { return p.IP },
+	)
+}
+
+// resolvePeers finds peers by ID, name or Netbird IP, dropping duplicates. It
+// reports every reference that can't be resolved at once.
+func resolvePeers(ctx context.Context, client *mcpnetbird.NetbirdClient, refs []string) ([]NetbirdPeer, error) {
+	var peers []NetbirdPeer
+	if err := client.Get(ctx, "/peers", &peers); err != nil {
+		return nil, fmt.Errorf("listing peers: %w", err)
+	}
+
+	var resolved []NetbirdPeer
+	var problems []string
+	for _, ref := range refs {
+		peer, err := resolveByIDOrName("peer", ref, peers,
+			func(p NetbirdPeer) string { return p.ID },
+			func(p NetbirdPeer) string { return p.Name },
+			func(p NetbirdPeer) string { return p.IP },
+		)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if !slices.ContainsFunc(resolved, func(p NetbirdPeer) bool { return p.ID == peer.ID }) {
+			resolved = append(resolved, peer)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// resolveGroups finds groups by ID or name, dropping duplicates. It reports every
+// reference that can't be resolved at once.
+func resolveGroups(ctx context.Context, client *mcpnetbird.NetbirdClient, refs []string) ([]NetbirdGroup, error) {
+	var groups []NetbirdGroup
+	if err := client.Get(ctx, "/groups", &groups); err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+
+	var resolved []NetbirdGroup
+	var problems []string
+	for _, ref := range refs {
+		group, err := resolveByIDOrName("group", ref, groups,
+			func(g NetbirdGroup) string { return g.ID },
+			func(g NetbirdGroup) string { return g.Name },
+		)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if !slices.ContainsFunc(resolved, func(g NetbirdGroup) bool { return g.ID == group.ID }) {
+			resolved = append(resolved, group)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// PeerGroupsResult reports how add_peer_to_groups or remove_peer_from_groups
+// changed the groups of a peer
+type PeerGroupsResult struct {
+	PeerID          string               `json:"peer_id"`
+	PeerName        string               `json:"peer_name"`
+	OperationID     string               `json:"operation_id,omitempty"`
+	ChangedGroups   []NetbirdGroupMember `json:"changed_groups"`
+	UnchangedGroups []NetbirdGroupMember `json:"unchanged_groups"`
+	Errors          map[string]string    `json:"errors,omitempty"` // group ID -> error
+	Groups          []NetbirdPeerGroup   `json:"groups"`
+}
+
+type PeerGroupsParams struct {
+	Peer   string   `json:"peer" jsonschema:"required,description=Peer ID or name or Netbird IP"`
+	Groups []string `json:"groups" jsonschema:"required,minItems=1,description=Group IDs or names"`
+	Confirmation
+}
+
+// describePeerGroupsChange returns a describe function listing the groups
+// add_peer_to_groups or remove_peer_from_groups would change
+func describePeerGroupsChange(add bool) func(context.Context, PeerGroupsParams) (*Impact, error) {
+	return func(ctx context.Context, args PeerGroupsParams) (*Impact, error) {
+		var client *mcpnetbird.NetbirdClient
+		if mcpnetbird.TestNetbirdClient != nil {
+			client = mcpnetbird.TestNetbirdClient
+		} else {
+			client = mcpnetbird.NewNetbirdClient(ctx)
+		}
+
+		peer, err := resolvePeer(ctx, client, args.Peer)
+		if err != nil {
+			return nil, err
+		}
+		groups, err := resolveGroups(ctx, client, args.Groups)
+		if err != nil {
+			return nil, err
+		}
+
+		affected := make([]NetbirdGroupMember, 0, len(groups))
+		var unchanged []string
+		var warnings []string
+		for _, group := range groups {
+			if group.Name == allGroupName {
+				warnings = append(warnings, "the All group always contains every peer and can't be changed")
+				continue
+			}
+			isMember := slices.ContainsFunc(group.Peers, func(p NetbirdGroupMember) bool { return p.ID == peer.ID })
+			if isMember == add {
+				unchanged = append(unchanged, group.Name)
+				continue
+			}
+			affected = append(affected, NetbirdGroupMember{ID: group.ID, Name: group.Name})
+		}
+		if len(unchanged) > 0 {
+			warnings = append(warnings, fmt.Sprintf("%d groups are left as they are: %s", len(unchanged), strings.Join(unchanged, ", ")))
+		}
+
+		action := fmt.Sprintf("remove peer %s from %d groups", peer.ID, len(affected))
+		if add {
+			action = fmt.Sprintf("add peer %s to %d groups", peer.ID, len(affected))
+		}
+		return &Impact{
+			Action:   action,
+			Target:   NetbirdGroupMember{ID: peer.ID, Name: peer.Name},
+			Affected: affected,
+			Warnings: warnings,
+		}, nil
+	}
+}
+
+// changePeerGroups adds the peer to, or removes it from, every group in args.
+// Groups are updated concurrently, at most BulkConcurrency at a time, and the
+// changes are journaled so rollback_netbird_operation can undo them.
+func changePeerGroups(ctx context.Context, args PeerGroupsParams, add bool) (*PeerGroupsResult, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	peer, err := resolvePeer(ctx, client, args.Peer)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := resolveGroups(ctx, client, args.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	change := groupMembershipChange{RemovePeers: []string{peer.ID}}
+	opName := "remove_peer_from_groups " + peer.ID
+	if add {
+		change = groupMembershipChange{AddPeers: []string{peer.ID}}
+		opName = "add_peer_to_groups " + peer.ID
+	}
+	op := startOperation(ctx, opName)
+
+	changed := make([]bool, len(groups))
+	errs := make([]error, len(groups))
+	skipped := runBounded(ctx, len(groups), BulkConcurrency, func(ctx context.Context, i int) {
+		_, changed[i], errs[i] = changeGroupMembers(ctx, client, op, groups[i].ID, change)
+	})
+	for _, i := range skipped {
+		errs[i] = fmt.Errorf("skipped: %w", ctx.Err())
+	}
+
+	result := &PeerGroupsResult{
+		PeerID:          peer.ID,
+		PeerName:        peer.Name,
+		ChangedGroups:   []NetbirdGroupMember{},
+		UnchangedGroups: []NetbirdGroupMember{},
+		Errors:          make(map[string]string),
+	}
+	for i, group := range groups {
+		member := NetbirdGroupMember{ID: group.ID, Name: group.Name}
+		if changed[i] {
+			// A group can be changed and still fail, when a concurrent update dropped the change
+			result.OperationID = op.ID
+		}
+		switch {
+		case errs[i] != nil:
+			result.Errors[group.ID] = errs[i].Error()
+		case changed[i]:
+			result.ChangedGroups = append(result.ChangedGroups, member)
+		default:
+			result.UnchangedGroups = append(result.UnchangedGroups, member)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("changing groups of peer %s: %w", peer.ID, err)
+	}
+
+	// Report the membership the peer ended up with
+	var updated NetbirdPeer
+	if err := client.Uncached().Get(ctx, "/peers/"+peer.ID, &updated); err != nil {
+		return result, fmt.Errorf("fetching peer %s: %w", peer.ID, err)
+	}
+	result.Groups = updated.Groups
+	return result, nil
+}
+
+func addPeerToGroups(ctx context.Context, args PeerGroupsParams) (*PeerGroupsResult, error) {
+	return changePeerGroups(ctx, args, true)
+}
+
+var AddPeerToGroups = mcpnetbird.MustTool(
+	"add_peer_to_groups",
+	"Add a peer to one or more groups, keeping their other members. The peer and groups can be given by ID or name. Each group is re-read before and after it is updated so concurrent changes aren't lost. Returns the groups that changed, those the peer was already in, the peer's resulting groups and an operation_id that rollback_netbird_operation can use to undo the changes. The first call returns the groups that would change and a confirmation_token; call again with the token to apply.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describePeerGroupsChange(true), addPeerToGroups),
+)
+
+func removePeerFromGroups(ctx context.Context, args PeerGroupsParams) (*PeerGroupsResult, error) {
+	return changePeerGroups(ctx, args, false)
+}
+
+var RemovePeerFromGroups = mcpnetbird.MustTool(
+	"remove_peer_from_groups",
+	"Remove a peer from one or more groups, keeping their other members. The peer and groups can be given by ID or name. Each group is re-read before and after it is updated so concurrent changes aren't lost. Returns the groups that changed, those the peer wasn't in, the peer's resulting groups and an operation_id that rollback_netbird_operation can use to undo the changes. The first call returns the groups that would change and a confirmation_token; call again with the token to apply.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describePeerGroupsChange(false), removePeerFromGroups),
+)
+
+// GroupMembersResult reports the members a group has after
+// add_netbird_group_members or remove_netbird_group_members
+type GroupMembersResult struct {
+	GroupID     string               `json:"group_id"`
+	GroupName   string               `json:"group_name"`
+	Changed     bool                 `json:"changed"`
+	OperationID string               `json:"operation_id,omitempty"`
+	Peers       []NetbirdGroupMember `json:"peers"`
+	Resources   []GroupResource      `json:"resources"`
+}
+
+type AddNetbirdGroupMembersParams struct {
+	Group     string          `json:"group" jsonschema:"required,description=Group ID or name"`
+	Peers     []string        `json:"peers,omitempty" jsonschema:"description=Peer IDs or names or Netbird IPs to add"`
+	Resources []GroupResource `json:"resources,omitempty" jsonschema:"description=Resource references to add"`
+}
+
+type RemoveNetbirdGroupMembersParams struct {
+	Group     string   `json:"group" jsonschema:"required,description=Group ID or name"`
+	Peers     []string `json:"peers,omitempty" jsonschema:"description=Peer IDs or names or Netbird IPs to remove"`
+	Resources []string `json:"resources,omitempty" jsonschema:"description=Resource IDs to remove"`
+}
+
+// changeNetbirdGroupMembers adds the peers to, or removes them from, the group
+// along with the resource changes in change. The update is journaled so
+// rollback_netbird_operation can undo it.
+func changeNetbirdGroupMembers(ctx context.Context, tool, groupRef string, peerRefs []string, add bool, change groupMembershipChange) (*GroupMembersResult, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	groups, err := resolveGroups(ctx, client, []string{groupRef})
+	if err != nil {
+		return nil, err
+	}
+	group := groups[0]
+	if len(peerRefs) > 0 {
+		peers, err := resolvePeers(ctx, client, peerRefs)
+		if err != nil {
+			return nil, err
+		}
+		peerIDs := make([]string, len(peers))
+		for i, peer := range peers {
+			peerIDs[i] = peer.ID
+		}
+		if add {
+			change.AddPeers = peerIDs
+		} else {
+			change.RemovePeers = peerIDs
+		}
+	}
+
+	op := startOperation(ctx, tool+" "+group.ID)
+	updated, changed, err := changeGroupMembers(ctx, client, op, group.ID, change)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GroupMembersResult{
+		GroupID:   updated.ID,
+		GroupName: updated.Name,
+		Changed:   changed,
+		Peers:     updated.Peers,
+		Resources: updated.Resources,
+	}
+	if changed {
+		result.OperationID = op.ID
+	}
+	if result.Peers == nil {
+		result.Peers = []NetbirdGroupMember{}
+	}
+	if result.Resources == nil {
+		result.Resources = []GroupResource{}
+	}
+	return result, nil
+}
+
+func addNetbirdGroupMembers(ctx context.Context, args AddNetbirdGroupMembersParams) (*GroupMembersResult, error) {
+	if len(args.Peers) == 0 && len(args.Resources) == 0 {
+		return nil, fmt.Errorf("peers or resources must be set")
+	}
+	for _, resource := range args.Resources {
+		if resource.ID == "" || resource.Type == "" {
+			return nil, fmt.Errorf("resources need an id and a type")
+		}
+	}
+	change := groupMembershipChange{AddResources: args.Resources}
+	return changeNetbirdGroupMembers(ctx, "add_netbird_group_members", args.Group, args.Peers, true, change)
+}
+
+var AddNetbirdGroupMembers = mcpnetbird.MustTool(
+	"add_netbird_group_members",
+	"Add peers and resources to a group, keeping its name and existing members. Unlike update_netbird_group, which replaces the member lists, the merged lists are computed from the group's current state. The group and peers can be given by ID or name. Returns the group's resulting members and an operation_id that rollback_netbird_operation can use to undo the change.",
+	mcpnetbird.AppendTool,
+	addNetbirdGroupMembers,
+)
+
+func removeNetbirdGroupMembers(ctx context.Context, args RemoveNetbirdGroupMembersParams) (*GroupMembersResult, error) {
+	if len(args.Peers) == 0 && len(args.Resources) == 0 {
+		return nil, fmt.Errorf("peers or resources must be set")
+	}
+	change := groupMembershipChange{RemoveResources: args.Resources}
+	return changeNetbirdGroupMembers(ctx, "remove_netbird_group_members", args.Group, args.Peers, false, change)
+}
+
+var RemoveNetbirdGroupMembers = mcpnetbird.MustTool(
+	"remove_netbird_group_members",
+	"Remove peers and resources from a group, keeping its name and other members. The group and peers can be given by ID or name and resources by ID. Returns the group's resulting members and an operation_id that rollback_netbird_operation can use to undo the change.",
+	mcpnetbird.UpdateTool,
+	removeNetbirdGroupMembers,
+)
+
+func AddNetbirdMembershipTools(mcp *server.MCPServer) {
+	AddPeerToGroups.Register(mcp)
+	RemovePeerFromGroups.Register(mcp)
+	AddNetbirdGroupMembers.Register(mcp)
+	RemoveNetbirdGroupMembers.Register(mcp)
+}
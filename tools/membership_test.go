@@ -0,0 +1,419 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+// fakeGroupStore serves /groups and /peers from memory, computing peer groups
+// from group membership like the Netbird API does
+type fakeGroupStore struct {
+	mu     sync.Mutex
+	groups map[string]*groupPreImage
+	order  []string
+	peers  []NetbirdPeer
+	puts   map[string]int
+	// created counts groups created through the API, to give them new IDs
+	created int
+	// afterPut, when set, runs after every group update while the lock is held
+	afterPut func(group *groupPreImage)
+}
+
+func newFakeGroupStore(t *testing.T, peers []NetbirdPeer, groups ...groupPreImage) *fakeGroupStore {
+	t.Helper()
+	store := &fakeGroupStore{groups: make(map[string]*groupPreImage), peers: peers, puts: make(map[string]int)}
+	for i := range groups {
+		store.groups[groups[i].ID] = &groups[i]
+		store.order = append(store.order, groups[i].ID)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(store.serveHTTP))
+	t.Cleanup(server.Close)
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	t.Cleanup(func() { mcpnetbird.TestNetbirdClient = nil })
+	return store
+}
+
+func (s *fakeGroupStore) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/groups":
+		groups := make([]groupPreImage, 0, len(s.order))
+		for _, id := range s.order {
+			groups = append(groups, *s.groups[id])
+		}
+		_ = json.NewEncoder(w).Encode(groups)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/groups/"):
+		group, ok := s.groups[strings.TrimPrefix(r.URL.Path, "/groups/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(group)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/groups/"):
+		id := strings.TrimPrefix(r.URL.Path, "/groups/")
+		var body struct {
+			Name      string          `json:"name"`
+			Peers     []string        `json:"peers"`
+			Resources []GroupResource `json:"resources"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Name == "" || body.Resources == nil {
+			http.Error(w, "invalid group", http.StatusUnprocessableEntity)
+			return
+		}
+		group := &groupPreImage{ID: id, Name: body.Name, Resources: body.Resources}
+		for _, peerID := range body.Peers {
+			group.Peers = append(group.Peers, NetbirdGroupMember{ID: peerID, Name: s.peerName(peerID)})
+		}
+		s.groups[id] = group
+		s.puts[id]++
+		if s.afterPut != nil {
+			s.afterPut(group)
+		}
+		_ = json.NewEncoder(w).Encode(group)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/groups/"):
+		id := strings.TrimPrefix(r.URL.Path, "/groups/")
+		if _, ok := s.groups[id]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(s.groups, id)
+		s.order = slices.DeleteFunc(s.order, func(groupID string) bool { return groupID == id })
+	case r.Method == http.MethodPost && r.URL.Path == "/groups":
+		var body struct {
+			Name      string          `json:"name"`
+			Peers     []string        `json:"peers"`
+			Resources []GroupResource `json:"resources"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid group", http.StatusUnprocessableEntity)
+			return
+		}
+		s.created++
+		group := &groupPreImage{ID: fmt.Sprintf("g-new-%d", s.created), Name: body.Name, Resources: body.Resources}
+		for _, peerID := range body.Peers {
+			group.Peers = append(group.Peers, NetbirdGroupMember{ID: peerID, Name: s.peerName(peerID)})
+		}
+		s.groups[group.ID] = group
+		s.order = append(s.order, group.ID)
+		_ = json.NewEncoder(w).Encode(group)
+	case r.Method == http.MethodGet && r.URL.Path == "/peers":
+		_ = json.NewEncoder(w).Encode(s.peers)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/peers/"):
+		id := strings.TrimPrefix(r.URL.Path, "/peers/")
+		for _, peer := range s.peers {
+			if peer.ID == id {
+				peer.Groups = nil
+				for _, groupID := range s.order {
+					group := s.groups[groupID]
+					if slices.ContainsFunc(group.Peers, func(m NetbirdGroupMember) bool { return m.ID == id }) {
+						peer.Groups = append(peer.Groups, NetbirdPeerGroup{ID: group.ID, Name: group.Name})
+					}
+				}
+				_ = json.NewEncoder(w).Encode(peer)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+func (s *fakeGroupStore) peerName(id string) string {
+	for _, peer := range s.peers {
+		if peer.ID == id {
+			return peer.Name
+		}
+	}
+	return ""
+}
+
+func (s *fakeGroupStore) group(id string) groupPreImage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.groups[id]
+}
+
+func (s *fakeGroupStore) hasGroup(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.groups[id]
+	return ok
+}
+
+func groupPeerIDs(group groupPreImage) []string {
+	ids := make([]string, 0, len(group.Peers))
+	for _, peer := range group.Peers {
+		ids = append(ids, peer.ID)
+	}
+	return ids
+}
+
+var membershipTestPeers = []NetbirdPeer{
+	{ID: "p1", Name: "laptop", IP: "100.64.0.1"},
+	{ID: "p2", Name: "server", IP: "100.64.0.2"},
+	{ID: "p3", Name: "phone", IP: "100.64.0.3"},
+}
+
+func TestAddPeerToGroups(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p2", Name: "server"}}, Resources: []GroupResource{{ID: "r1", Type: "host"}}},
+		groupPreImage{ID: "g2", Name: "ops", Peers: []NetbirdGroupMember{{ID: "p1", Name: "laptop"}}},
+		groupPreImage{ID: "g3", Name: "untouched"},
+	)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := addPeerToGroups(ctx, PeerGroupsParams{Peer: "Laptop", Groups: []string{"devs", "g2", "DEVS"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.PeerID != "p1" || result.OperationID == "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if len(result.ChangedGroups) != 1 || result.ChangedGroups[0].ID != "g1" {
+		t.Errorf("expected only devs to change, got %+v", result.ChangedGroups)
+	}
+	if len(result.UnchangedGroups) != 1 || result.UnchangedGroups[0].ID != "g2" {
+		t.Errorf("expected ops to be unchanged, got %+v", result.UnchangedGroups)
+	}
+	if len(result.Groups) != 2 || result.Groups[0].ID != "g1" || result.Groups[1].ID != "g2" {
+		t.Errorf("expected resulting membership devs and ops, got %+v", result.Groups)
+	}
+
+	devs := store.group("g1")
+	if !slices.Equal(groupPeerIDs(devs), []string{"p2", "p1"}) || devs.Name != "devs" {
+		t.Errorf("expected existing members and name to be kept, got %+v", devs)
+	}
+	if len(devs.Resources) != 1 || devs.Resources[0].Type != "host" {
+		t.Errorf("expected resources to be kept with their type, got %+v", devs.Resources)
+	}
+	if store.puts["g2"] != 0 || store.puts["g3"] != 0 {
+		t.Errorf("expected only devs to be written, got %v", store.puts)
+	}
+
+	// Rolling back restores the previous members
+	if _, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID}); err != nil {
+		t.Fatalf("unexpected rollback error: %v", err)
+	}
+	if got := groupPeerIDs(store.group("g1")); !slices.Equal(got, []string{"p2"}) {
+		t.Errorf("expected rollback to restore devs, got %v", got)
+	}
+}
+
+func TestDescribePeerGroupsChange(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p2", Name: "server"}}},
+		groupPreImage{ID: "g2", Name: "ops", Peers: []NetbirdGroupMember{{ID: "p1", Name: "laptop"}}},
+	)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	impact, err := describePeerGroupsChange(true)(ctx, PeerGroupsParams{Peer: "laptop", Groups: []string{"devs", "ops"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if affected := impact.Affected.([]NetbirdGroupMember); len(affected) != 1 || affected[0].ID != "g1" {
+		t.Errorf("expected only devs to be affected, got %+v", impact.Affected)
+	}
+	if len(impact.Warnings) != 1 || !strings.Contains(impact.Warnings[0], "ops") {
+		t.Errorf("expected ops to be reported as unchanged, got %v", impact.Warnings)
+	}
+
+	impact, err = describePeerGroupsChange(false)(ctx, PeerGroupsParams{Peer: "laptop", Groups: []string{"devs", "ops"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if affected := impact.Affected.([]NetbirdGroupMember); len(affected) != 1 || affected[0].ID != "g2" {
+		t.Errorf("expected only ops to be affected, got %+v", impact.Affected)
+	}
+	if store.puts["g1"] != 0 || store.puts["g2"] != 0 {
+		t.Errorf("expected describe not to write, got %v", store.puts)
+	}
+}
+
+func TestRemovePeerFromGroups(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p1"}, {ID: "p2"}}},
+		groupPreImage{ID: "g2", Name: "ops", Peers: []NetbirdGroupMember{{ID: "p2"}}},
+	)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := removePeerFromGroups(ctx, PeerGroupsParams{Peer: "100.64.0.1", Groups: []string{"devs", "ops"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.ChangedGroups) != 1 || len(result.UnchangedGroups) != 1 || len(result.Groups) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if got := groupPeerIDs(store.group("g1")); !slices.Equal(got, []string{"p2"}) {
+		t.Errorf("expected only p1 to be removed, got %v", got)
+	}
+}
+
+func TestAddPeerToGroups_ReappliesChangeDroppedByConcurrentUpdate(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p2"}}},
+	)
+	// Another client that read the group before our update writes its own version
+	// right after it, adding p3 and dropping p1
+	store.afterPut = func(group *groupPreImage) {
+		if store.puts["g1"] == 1 {
+			group.Peers = []NetbirdGroupMember{{ID: "p2"}, {ID: "p3"}}
+		}
+	}
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := addPeerToGroups(ctx, PeerGroupsParams{Peer: "p1", Groups: []string{"g1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.Errors) != 0 || len(result.ChangedGroups) != 1 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if store.puts["g1"] != 2 {
+		t.Errorf("expected the change to be written again, got %d writes", store.puts["g1"])
+	}
+	if got := groupPeerIDs(store.group("g1")); !slices.Equal(got, []string{"p2", "p3", "p1"}) {
+		t.Errorf("expected both changes to be kept, got %v", got)
+	}
+
+	// A group whose change keeps being dropped is reported instead of retried forever
+	store.mu.Lock()
+	store.groups["g1"].Peers = nil
+	store.mu.Unlock()
+	store.afterPut = func(group *groupPreImage) { group.Peers = nil }
+	result, err = addPeerToGroups(ctx, PeerGroupsParams{Peer: "p1", Groups: []string{"g1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Errors["g1"], "concurrent updates") || result.OperationID == "" {
+		t.Errorf("expected a concurrent update error, got %+v", result)
+	}
+}
+
+func TestPeerGroups_ResolutionErrors(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g0", Name: allGroupName, Peers: []NetbirdGroupMember{{ID: "p1"}, {ID: "p2"}, {ID: "p3"}}},
+		groupPreImage{ID: "g1", Name: "devs"},
+		groupPreImage{ID: "g2", Name: "Devs"},
+	)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	_, err := addPeerToGroups(ctx, PeerGroupsParams{Peer: "p1", Groups: []string{"devs", "qa", "g1"}})
+	if err == nil || !strings.Contains(err.Error(), `group "devs" is ambiguous, it matches g1, g2`) || !strings.Contains(err.Error(), `group "qa" not found`) {
+		t.Errorf("expected every unresolved group to be reported, got %v", err)
+	}
+	if _, err := addPeerToGroups(ctx, PeerGroupsParams{Peer: "tablet", Groups: []string{"g1"}}); err == nil || !strings.Contains(err.Error(), `peer "tablet" not found`) {
+		t.Errorf("expected unknown peer error, got %v", err)
+	}
+	if len(store.puts) != 0 {
+		t.Errorf("expected no group to be written, got %v", store.puts)
+	}
+
+	result, err := removePeerFromGroups(ctx, PeerGroupsParams{Peer: "p1", Groups: []string{allGroupName}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Errors["g0"], "All group") {
+		t.Errorf("expected the All group to be refused, got %+v", result)
+	}
+}
+
+func TestAddNetbirdGroupMembers(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p2", Name: "server"}}, Resources: []GroupResource{{ID: "r1", Type: "host"}}},
+	)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := addNetbirdGroupMembers(ctx, AddNetbirdGroupMembersParams{
+		Group:     "Devs",
+		Peers:     []string{"laptop", "p2"},
+		Resources: []GroupResource{{ID: "r2", Type: "subnet"}, {ID: "r1", Type: "host"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Changed || result.OperationID == "" || result.GroupName != "devs" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	devs := store.group("g1")
+	if !slices.Equal(groupPeerIDs(devs), []string{"p2", "p1"}) || devs.Name != "devs" {
+		t.Errorf("expected p1 to be added to the existing members, got %+v", devs)
+	}
+	if !slices.Equal(devs.Resources, []GroupResource{{ID: "r1", Type: "host"}, {ID: "r2", Type: "subnet"}}) {
+		t.Errorf("expected r2 to be added to the existing resources, got %+v", devs.Resources)
+	}
+	if len(result.Peers) != 2 || len(result.Resources) != 2 {
+		t.Errorf("expected the resulting members in the result, got %+v", result)
+	}
+
+	// Adding members the group already has doesn't write it
+	result, err = addNetbirdGroupMembers(ctx, AddNetbirdGroupMembersParams{Group: "g1", Peers: []string{"p1"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Changed || result.OperationID != "" || store.puts["g1"] != 1 {
+		t.Errorf("expected no change, got %+v after %d writes", result, store.puts["g1"])
+	}
+
+	if _, err := addNetbirdGroupMembers(ctx, AddNetbirdGroupMembersParams{Group: "g1"}); err == nil {
+		t.Error("expected an error without peers or resources")
+	}
+	if _, err := addNetbirdGroupMembers(ctx, AddNetbirdGroupMembersParams{Group: "g1", Resources: []GroupResource{{ID: "r3"}}}); err == nil {
+		t.Error("expected an error for a resource without a type")
+	}
+}
+
+func TestRemoveNetbirdGroupMembers(t *testing.T) {
+	store := newFakeGroupStore(t, membershipTestPeers,
+		groupPreImage{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p1"}, {ID: "p2"}, {ID: "p3"}}, Resources: []GroupResource{{ID: "r1", Type: "host"}, {ID: "r2", Type: "subnet"}}},
+	)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := removeNetbirdGroupMembers(ctx, RemoveNetbirdGroupMembersParams{Group: "devs", Peers: []string{"phone", "p1"}, Resources: []string{"r1", "r9"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Changed || result.OperationID == "" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	devs := store.group("g1")
+	if !slices.Equal(groupPeerIDs(devs), []string{"p2"}) || !slices.Equal(devs.Resources, []GroupResource{{ID: "r2", Type: "subnet"}}) || devs.Name != "devs" {
+		t.Errorf("expected only the given members to be removed, got %+v", devs)
+	}
+
+	if _, err := removeNetbirdGroupMembers(ctx, RemoveNetbirdGroupMembersParams{Group: "devs", Peers: []string{"tablet"}}); err == nil || !strings.Contains(err.Error(), `peer "tablet" not found`) {
+		t.Errorf("expected unknown peer error, got %v", err)
+	}
+
+	// Rolling back restores the removed members
+	if _, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID}); err != nil {
+		t.Fatalf("unexpected rollback error: %v", err)
+	}
+	if got := store.group("g1"); len(got.Peers) != 3 || len(got.Resources) != 2 {
+		t.Errorf("expected rollback to restore the members, got %+v", got)
+	}
+}
+
+func TestGroupMembersToolAnnotations(t *testing.T) {
+	// Adding members never removes any, so only removing is destructive
+	add, remove := AddNetbirdGroupMembers.Tool.Annotations, RemoveNetbirdGroupMembers.Tool.Annotations
+	if *add.DestructiveHint || !*add.IdempotentHint || *add.ReadOnlyHint {
+		t.Errorf("unexpected add_netbird_group_members annotations %+v", add)
+	}
+	if !*remove.DestructiveHint || !*remove.IdempotentHint {
+		t.Errorf("unexpected remove_netbird_group_members annotations %+v", remove)
+	}
+}
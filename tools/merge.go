@@ -0,0 +1,614 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+// groupMerge rewrites references to the source groups of a merge into
+// references to the target group
+type groupMerge struct {
+	target      string
+	sources     map[string]bool
+	sourceNames map[string]bool
+}
+
+// ids returns ids with source groups replaced by the target, without duplicates.
+// field is added to fields when a reference was rewritten.
+func (m groupMerge) ids(ids []string, field string, fields *[]string) []string {
+	if ids == nil {
+		return nil
+	}
+	changed := false
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if m.sources[id] {
+			id = m.target
+			changed = true
+		}
+		if !slices.Contains(out, id) {
+			out = append(out, id)
+		}
+	}
+	if changed {
+		*fields = append(*fields, field)
+	}
+	return out
+}
+
+// peerGroups is ids for the group objects of policy rules
+func (m groupMerge) peerGroups(groups []NetbirdPeerGroup, field string, fields *[]string) []NetbirdPeerGroup {
+	changed := false
+	out := make([]NetbirdPeerGroup, 0, len(groups))
+	for _, group := range groups {
+		if m.sources[group.ID] {
+			group = NetbirdPeerGroup{ID: m.target}
+			changed = true
+		}
+		if !slices.ContainsFunc(out, func(g NetbirdPeerGroup) bool { return g.ID == group.ID }) {
+			out = append(out, group)
+		}
+	}
+	if changed {
+		*fields = append(*fields, field)
+	}
+	return out
+}
+
+// policy returns a copy of policy with its rules referring to the target instead
+// of the source groups. Users authorized through several merged groups are
+// authorized through the target.
+func (m groupMerge) policy(policy NetbirdPolicy) (NetbirdPolicy, []string) {
+	var fields []string
+	updated := remapPolicyGroups(policy, nil)
+	for i := range updated.Rules {
+		rule := &updated.Rules[i]
+		rule.Sources = m.peerGroups(rule.Sources, fmt.Sprintf("rules[%d].sources", i), &fields)
+		rule.Destinations = m.peerGroups(rule.Destinations, fmt.Sprintf("rules[%d].destinations", i), &fields)
+		if rule.AuthorizedGroups == nil {
+			continue
+		}
+
+		groupIDs := make([]string, 0, len(*rule.AuthorizedGroups))
+		for groupID := range *rule.AuthorizedGroups {
+			groupIDs = append(groupIDs, groupID)
+		}
+		sort.Strings(groupIDs)
+		changed := false
+		authorized := make(map[string][]string, len(groupIDs))
+		for _, groupID := range groupIDs {
+			users := (*rule.AuthorizedGroups)[groupID]
+			if m.sources[groupID] {
+				groupID = m.target
+				changed = true
+			}
+			for _, user := range users {
+				if !slices.Contains(authorized[groupID], user) {
+					authorized[groupID] = append(authorized[groupID], user)
+				}
+			}
+			if authorized[groupID] == nil {
+				authorized[groupID] = []string{}
+			}
+		}
+		if changed {
+			rule.AuthorizedGroups = &authorized
+			fields = append(fields, fmt.Sprintf("rules[%d].authorized_groups", i))
+		}
+	}
+	return updated, fields
+}
+
+// GroupReferenceUpdate is a planned update of an object that refers to a group
+// being merged. Fields lists the fields whose group references are rewritten.
+type GroupReferenceUpdate struct {
+	Kind   string   `json:"kind"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name,omitempty"`
+	Fields []string `json:"fields"`
+
+	path     string
+	body     any
+	preImage any
+}
+
+// objectUpdate plans the update of an object other than a policy, given its update
+// body as fetched and as rewritten. groupFields name every body field holding
+// group IDs, so a rollback can remap them to recreated groups.
+func objectUpdate(kind, id, name, path string, before, after map[string]any, groupFields, fields []string) GroupReferenceUpdate {
+	return GroupReferenceUpdate{
+		Kind:     kind,
+		ID:       id,
+		Name:     name,
+		Fields:   fields,
+		path:     path,
+		body:     after,
+		preImage: objectPreImage{Path: path, Body: before, GroupFields: groupFields},
+	}
+}
+
+func routeUpdateBody(route NetbirdRoute) map[string]any {
+	body := map[string]any{
+		"description":     route.Description,
+		"network_id":      route.NetworkID,
+		"enabled":         route.Enabled,
+		"metric":          route.Metric,
+		"masquerade":      route.Masquerade,
+		"groups":          route.Groups,
+		"keep_route":      route.KeepRoute,
+		"skip_auto_apply": route.SkipAutoApply,
+	}
+	if route.AccessControlGroups != nil {
+		body["access_control_groups"] = route.AccessControlGroups
+	}
+	if route.Peer != "" {
+		body["peer"] = route.Peer
+	} else {
+		body["peer_groups"] = route.PeerGroups
+	}
+	if len(route.Domains) > 0 {
+		body["domains"] = route.Domains
+	} else {
+		body["network"] = route.Network
+	}
+	return body
+}
+
+func nameserverUpdateBody(ns NetbirdNameservers) map[string]any {
+	return map[string]any{
+		"name":                   ns.Name,
+		"description":            ns.Description,
+		"nameservers":            ns.Nameservers,
+		"enabled":                ns.Enabled,
+		"groups":                 ns.Groups,
+		"primary":                ns.Primary,
+		"domains":                ns.Domains,
+		"search_domains_enabled": ns.SearchDomainsEnabled,
+	}
+}
+
+func networkResourceUpdateBody(resource NetbirdNetworkResource, groups []string) map[string]any {
+	description := ""
+	if resource.Description != nil {
+		description = *resource.Description
+	}
+	return map[string]any{
+		"name":        resource.Name,
+		"description": description,
+		"address":     resource.Address,
+		"enabled":     resource.Enabled,
+		"groups":      groups,
+	}
+}
+
+func networkRouterUpdateBody(router NetbirdNetworkRouter, peerGroups []string) map[string]any {
+	body := map[string]any{
+		"metric":     router.Metric,
+		"masquerade": router.Masquerade,
+		"enabled":    router.Enabled,
+	}
+	if router.Peer != nil && *router.Peer != "" {
+		body["peer"] = *router.Peer
+	} else {
+		body["peer_groups"] = peerGroups
+	}
+	return body
+}
+
+// accountUpdateBody is the settings update of an account with its network
+// traffic logs groups replaced by groups. Extra must not be nil.
+func accountUpdateBody(account NetbirdAccount, groups []string) (map[string]any, error) {
+	settings, err := structToMap(account.Settings)
+	if err != nil {
+		return nil, fmt.Errorf("account %s: %w", account.ID, err)
+	}
+	extra, _ := settings["extra"].(map[string]any)
+	if extra == nil {
+		return nil, fmt.Errorf("account %s: settings have no extra section", account.ID)
+	}
+	extra["network_traffic_logs_groups"] = groups
+	return map[string]any{"settings": settings}, nil
+}
+
+// netbirdDNSSettings are the account-wide DNS settings
+type netbirdDNSSettings struct {
+	DisabledManagementGroups []string `json:"disabled_management_groups"`
+}
+
+// planGroupMerge lists the objects that refer to a source group of m and plans
+// their updates: policies, routes, nameservers, network resources and routers,
+// setup keys, users, the account settings and the DNS settings. A merge is
+// refused when a source group is allowed to log in through the account's JWT
+// allow groups, since those are matched against the identity provider's claim.
+func planGroupMerge(ctx context.Context, client *mcpnetbird.NetbirdClient, m groupMerge) ([]GroupReferenceUpdate, error) {
+	updates := []GroupReferenceUpdate{}
+
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return nil, fmt.Errorf("listing policies: %w", err)
+	}
+	for _, policy := range policies {
+		updated, fields := m.policy(policy)
+		if len(fields) == 0 {
+			continue
+		}
+		body, errs := policyUpdateBody(updated)
+		if len(errs) > 0 {
+			return nil, fmt.Errorf("policy %s: %w", policy.ID, errs[0])
+		}
+		updates = append(updates, GroupReferenceUpdate{
+			Kind:     journalKindPolicy,
+			ID:       policy.ID,
+			Name:     policy.Name,
+			Fields:   fields,
+			path:     "/policies/" + policy.ID,
+			body:     body,
+			preImage: remapPolicyGroups(policy, nil),
+		})
+	}
+
+	var routes []NetbirdRoute
+	if err := client.Get(ctx, "/routes", &routes); err != nil {
+		return nil, fmt.Errorf("listing routes: %w", err)
+	}
+	for _, route := range routes {
+		var fields []string
+		updated := route
+		updated.Groups = m.ids(route.Groups, "groups", &fields)
+		updated.PeerGroups = m.ids(route.PeerGroups, "peer_groups", &fields)
+		updated.AccessControlGroups = m.ids(route.AccessControlGroups, "access_control_groups", &fields)
+		if len(fields) > 0 {
+			updates = append(updates, objectUpdate(journalKindRoute, route.ID, route.NetworkID, "/routes/"+route.ID,
+				routeUpdateBody(route), routeUpdateBody(updated), []string{"groups", "peer_groups", "access_control_groups"}, fields))
+		}
+	}
+
+	var nameservers []NetbirdNameservers
+	if err := client.Get(ctx, "/dns/nameservers", &nameservers); err != nil {
+		return nil, fmt.Errorf("listing nameservers: %w", err)
+	}
+	for _, ns := range nameservers {
+		var fields []string
+		updated := ns
+		updated.Groups = m.ids(ns.Groups, "groups", &fields)
+		if len(fields) > 0 {
+			updates = append(updates, objectUpdate(journalKindNameserver, ns.ID, ns.Name, "/dns/nameservers/"+ns.ID,
+				nameserverUpdateBody(ns), nameserverUpdateBody(updated), []string{"groups"}, fields))
+		}
+	}
+
+	networks, err := listNetworkChildren(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	for _, network := range networks {
+		for _, resource := range network.resources {
+			groups := make([]string, 0, len(resource.Groups))
+			for _, group := range resource.Groups {
+				groups = append(groups, group.ID)
+			}
+			var fields []string
+			merged := m.ids(groups, "groups", &fields)
+			if len(fields) > 0 {
+				path := "/networks/" + network.network.ID + "/resources/" + resource.ID
+				updates = append(updates, objectUpdate(journalKindNetworkResource, resource.ID, resource.Name, path,
+					networkResourceUpdateBody(resource, groups), networkResourceUpdateBody(resource, merged), []string{"groups"}, fields))
+			}
+		}
+
+		for _, router := range network.routers {
+			if router.PeerGroups == nil {
+				continue
+			}
+			var fields []string
+			merged := m.ids(*router.PeerGroups, "peer_groups", &fields)
+			if len(fields) > 0 {
+				path := "/networks/" + network.network.ID + "/routers/" + router.ID
+				updates = append(updates, objectUpdate(journalKindNetworkRouter, router.ID, network.network.Name, path,
+					networkRouterUpdateBody(router, *router.PeerGroups), networkRouterUpdateBody(router, merged), []string{"peer_groups"}, fields))
+			}
+		}
+	}
+
+	var keys []NetbirdSetupKey
+	if err := client.Get(ctx, "/setup-keys", &keys); err != nil {
+		return nil, fmt.Errorf("listing setup keys: %w", err)
+	}
+	for _, key := range keys {
+		var fields []string
+		merged := m.ids(key.AutoGroups, "auto_groups", &fields)
+		if len(fields) > 0 {
+			updates = append(updates, objectUpdate(journalKindSetupKey, key.ID, key.Name, "/setup-keys/"+key.ID,
+				map[string]any{"revoked": key.Revoked, "auto_groups": key.AutoGroups},
+				map[string]any{"revoked": key.Revoked, "auto_groups": merged}, []string{"auto_groups"}, fields))
+		}
+	}
+
+	var users []NetbirdUser
+	if err := client.Get(ctx, "/users", &users); err != nil {
+		return nil, fmt.Errorf("listing users: %w", err)
+	}
+	for _, user := range users {
+		var fields []string
+		merged := m.ids(user.AutoGroups, "auto_groups", &fields)
+		if len(fields) > 0 {
+			name := user.Email
+			if name == "" {
+				name = user.Name
+			}
+			updates = append(updates, objectUpdate(journalKindUser, user.ID, name, "/users/"+user.ID,
+				map[string]any{"role": user.Role, "auto_groups": user.AutoGroups, "is_blocked": user.IsBlocked},
+				map[string]any{"role": user.Role, "auto_groups": merged, "is_blocked": user.IsBlocked}, []string{"auto_groups"}, fields))
+		}
+	}
+
+	var accounts []NetbirdAccount
+	if err := client.Get(ctx, "/accounts", &accounts); err != nil {
+		return nil, fmt.Errorf("fetching account: %w", err)
+	}
+	for _, account := range accounts {
+		for _, allowed := range account.Settings.JWTAllowGroups {
+			if m.sources[allowed] || m.sourceNames[allowed] {
+				return nil, fmt.Errorf("account %s: group %q is in settings.jwt_allow_groups, which is matched against the identity provider's group claim and can't be rewritten; remove it from the account settings before merging", account.ID, allowed)
+			}
+		}
+		if account.Settings.Extra == nil {
+			continue
+		}
+		var fields []string
+		groups := account.Settings.Extra.NetworkTrafficLogsGroups
+		merged := m.ids(groups, "settings.extra.network_traffic_logs_groups", &fields)
+		if len(fields) == 0 {
+			continue
+		}
+		before, err := accountUpdateBody(account, groups)
+		if err != nil {
+			return nil, err
+		}
+		after, err := accountUpdateBody(account, merged)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, objectUpdate(journalKindAccount, account.ID, "", "/accounts/"+account.ID,
+			before, after, []string{"settings.extra.network_traffic_logs_groups"}, fields))
+	}
+
+	var dns netbirdDNSSettings
+	if err := client.Get(ctx, "/dns/settings", &dns); err != nil {
+		return nil, fmt.Errorf("fetching DNS settings: %w", err)
+	}
+	var fields []string
+	merged := m.ids(dns.DisabledManagementGroups, "disabled_management_groups", &fields)
+	if len(fields) > 0 {
+		updates = append(updates, objectUpdate(journalKindDNSSettings, "dns", "", "/dns/settings",
+			map[string]any{"disabled_management_groups": dns.DisabledManagementGroups},
+			map[string]any{"disabled_management_groups": merged}, []string{"disabled_management_groups"}, fields))
+	}
+
+	return updates, nil
+}
+
+type MergeNetbirdGroupsParams struct {
+	Target  string   `json:"target" jsonschema:"required,description=ID or name of the group to merge into"`
+	Sources []string `json:"sources" jsonschema:"required,minItems=1,description=IDs or names of the groups to merge into the target and then delete"`
+	Confirmation
+}
+
+// resolveGroupMerge finds the target and source groups of a merge
+func resolveGroupMerge(ctx context.Context, client *mcpnetbird.NetbirdClient, args MergeNetbirdGroupsParams) (NetbirdGroup, []NetbirdGroup, error) {
+	targets, err := resolveGroups(ctx, client, []string{args.Target})
+	if err != nil {
+		return NetbirdGroup{}, nil, err
+	}
+	sources, err := resolveGroups(ctx, client, args.Sources)
+	if err != nil {
+		return NetbirdGroup{}, nil, err
+	}
+	target := targets[0]
+	for _, source := range sources {
+		if source.ID == target.ID {
+			return NetbirdGroup{}, nil, fmt.Errorf("group %s can't be merged into itself", source.ID)
+		}
+		if source.Name == allGroupName {
+			return NetbirdGroup{}, nil, fmt.Errorf("group %s: the All group can't be merged into another group", source.ID)
+		}
+	}
+	return target, sources, nil
+}
+
+func newGroupMerge(target NetbirdGroup, sources []NetbirdGroup) groupMerge {
+	m := groupMerge{
+		target:      target.ID,
+		sources:     make(map[string]bool, len(sources)),
+		sourceNames: make(map[string]bool, len(sources)),
+	}
+	for _, source := range sources {
+		m.sources[source.ID] = true
+		m.sourceNames[source.Name] = true
+	}
+	return m
+}
+
+// describeGroupMerge reports the groups to merge and the objects whose group
+// references will be rewritten
+func describeGroupMerge(ctx context.Context, args MergeNetbirdGroupsParams) (*Impact, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	target, sources, err := resolveGroupMerge(ctx, client, args)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := planGroupMerge(ctx, client, newGroupMerge(target, sources))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(sources))
+	for i, source := range sources {
+		names[i] = fmt.Sprintf("%s (%s)", source.Name, source.ID)
+	}
+	return &Impact{
+		Action:   fmt.Sprintf("merge %d groups into %s and rewrite %d references", len(sources), target.ID, len(updates)),
+		Target:   target,
+		Affected: updates,
+		Warnings: []string{"the groups " + strings.Join(names, ", ") + " will be deleted"},
+	}, nil
+}
+
+// GroupMergeResult reports what merge_netbird_groups changed
+type GroupMergeResult struct {
+	TargetGroupID string                 `json:"target_group_id"`
+	OperationID   string                 `json:"operation_id"`
+	Target        *groupPreImage         `json:"target,omitempty"`
+	DeletedGroups []NetbirdGroupMember   `json:"deleted_groups"`
+	Updated       []GroupReferenceUpdate `json:"updated"`
+	RolledBack    bool                   `json:"rolled_back,omitempty"`
+	Errors        []string               `json:"errors,omitempty"`
+}
+
+// mergeNetbirdGroups adds the peers and resources of the source groups to the
+// target, rewrites every reference to a source group into a reference to the
+// target and deletes the source groups. Every change is journaled: if any step
+// fails or ctx is cancelled, the changes already made are rolled back.
+func mergeNetbirdGroups(ctx context.Context, args MergeNetbirdGroupsParams) (*GroupMergeResult, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	target, sources, err := resolveGroupMerge(ctx, client, args)
+	if err != nil {
+		return nil, err
+	}
+	m := newGroupMerge(target, sources)
+
+	// Keep the source groups so the operation can recreate them on rollback
+	preImages := make([]groupPreImage, len(sources))
+	var change groupMembershipChange
+	for i, source := range sources {
+		if err := client.Uncached().Get(ctx, "/groups/"+source.ID, &preImages[i]); err != nil {
+			return nil, fmt.Errorf("fetching group %s: %w", source.ID, err)
+		}
+		for _, peer := range preImages[i].Peers {
+			change.AddPeers = append(change.AddPeers, peer.ID)
+		}
+		change.AddResources = append(change.AddResources, preImages[i].Resources...)
+	}
+
+	// Plan the reference updates first, so a merge that is refused changes nothing
+	updates, err := planGroupMerge(ctx, client, m)
+	if err != nil {
+		return nil, err
+	}
+
+	op := startOperation(ctx, "merge_netbird_groups "+target.ID)
+	result := &GroupMergeResult{
+		TargetGroupID: target.ID,
+		OperationID:   op.ID,
+		DeletedGroups: []NetbirdGroupMember{},
+		Updated:       []GroupReferenceUpdate{},
+		Errors:        []string{},
+	}
+
+	// rollback undoes the changes made so far. It must run even if ctx was cancelled.
+	rollback := func(cause error) (*GroupMergeResult, error) {
+		restored, err := op.Rollback(context.WithoutCancel(ctx), client)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("rollback: %v", err))
+			return result, fmt.Errorf("merging groups into %s: %w", target.ID, cause)
+		}
+		for _, msg := range restored.Errors {
+			result.Errors = append(result.Errors, "rollback: "+msg)
+		}
+		if len(restored.Errors) > 0 {
+			return result, fmt.Errorf("merging groups into %s: %w; %d changes could not be rolled back, retry with rollback_netbird_operation (operation %s)", target.ID, cause, len(restored.Errors), op.ID)
+		}
+		result.RolledBack = true
+		return result, fmt.Errorf("merging groups into %s: %w; changes were rolled back (operation %s)", target.ID, cause, op.ID)
+	}
+
+	// Add the members of the source groups to the target
+	merged, _, err := changeGroupMembers(ctx, client, op, target.ID, change)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return rollback(err)
+	}
+	result.Target = merged
+
+	// Point every reference to a source group at the target
+	errs := make([]string, len(updates))
+	skipped := runBounded(ctx, len(updates), BulkConcurrency, func(ctx context.Context, i int) {
+		update := updates[i]
+		entry := op.record(update.Kind, journalActionUpdate, update.ID, update.preImage)
+		if err := client.Put(ctx, update.path, update.body, nil); err != nil {
+			errs[i] = fmt.Sprintf("%s %s: updating: %v", update.Kind, update.ID, err)
+			return
+		}
+		op.applied(entry)
+	})
+	for _, i := range skipped {
+		errs[i] = fmt.Sprintf("%s %s: skipped: %v", updates[i].Kind, updates[i].ID, ctx.Err())
+	}
+	for i, msg := range errs {
+		if msg != "" {
+			result.Errors = append(result.Errors, msg)
+			continue
+		}
+		result.Updated = append(result.Updated, updates[i])
+	}
+	if err := ctx.Err(); err != nil {
+		return rollback(err)
+	}
+	if len(result.Errors) > 0 {
+		return rollback(fmt.Errorf("%d references could not be rewritten", len(result.Errors)))
+	}
+
+	// Delete the source groups, which nothing refers to anymore
+	errs = make([]string, len(preImages))
+	skipped = runBounded(ctx, len(preImages), BulkConcurrency, func(ctx context.Context, i int) {
+		group := preImages[i]
+		entry := op.record(journalKindGroup, journalActionDelete, group.ID, group)
+		if err := client.Delete(ctx, "/groups/"+group.ID); err != nil {
+			errs[i] = fmt.Sprintf("group %s: deleting: %v", group.ID, err)
+			return
+		}
+		op.applied(entry)
+	})
+	for _, i := range skipped {
+		errs[i] = fmt.Sprintf("group %s: deleting: skipped: %v", preImages[i].ID, ctx.Err())
+	}
+	for i, msg := range errs {
+		if msg != "" {
+			result.Errors = append(result.Errors, msg)
+			continue
+		}
+		result.DeletedGroups = append(result.DeletedGroups, NetbirdGroupMember{ID: preImages[i].ID, Name: preImages[i].Name})
+	}
+	if err := ctx.Err(); err != nil {
+		return rollback(err)
+	}
+	if len(result.Errors) > 0 {
+		return rollback(fmt.Errorf("%d groups could not be deleted", len(result.Errors)))
+	}
+
+	return result, nil
+}
+
+var MergeNetbirdGroups = mcpnetbird.MustTool(
+	"merge_netbird_groups",
+	"Merge groups into a target group: adds their peers and resources to the target, rewrites references to them in policies, routes, nameservers, network resources and routers, setup key and user auto groups, the account's traffic logs groups and DNS settings, then deletes them. Groups listed in the account's JWT allow groups can't be merged away. Groups can be given by ID or name. If any step fails the changes are rolled back; a successful merge returns an operation_id that rollback_netbird_operation can use to undo it. The first call returns an impact summary and a confirmation_token; call again with the token to merge.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describeGroupMerge, mergeNetbirdGroups),
+)
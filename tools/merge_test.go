@@ -0,0 +1,384 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+// fakeMergeStore serves groups and peers from a fakeGroupStore and every other
+// object from memory by path. Lists are the objects one path segment below.
+// Writes are recorded but not applied to the stored objects.
+// Deleting an object deletes the objects below it too.
+type fakeMergeStore struct {
+	*fakeGroupStore
+	mu      sync.Mutex
+	objects map[string]any
+	writes  map[string][]map[string]any
+	failPut map[string]bool
+	deleted []string
+	posts   int
+
+	// putLimit makes writes to a path fail once it has been written that many times
+	putLimit   map[string]int
+	failDelete map[string]bool
+}
+
+func newFakeMergeStore(t *testing.T, objects map[string]any, groups ...groupPreImage) *fakeMergeStore {
+	t.Helper()
+	store := &fakeMergeStore{
+		fakeGroupStore: newFakeGroupStore(t, membershipTestPeers, groups...),
+		objects:        objects,
+		writes:         make(map[string][]map[string]any),
+		failPut:        make(map[string]bool),
+		putLimit:       make(map[string]int),
+		failDelete:     make(map[string]bool),
+	}
+	server := httptest.NewServer(http.HandlerFunc(store.serveHTTP))
+	t.Cleanup(server.Close)
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	return store
+}
+
+func (s *fakeMergeStore) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/groups" || strings.HasPrefix(r.URL.Path, "/groups/") || strings.HasPrefix(r.URL.Path, "/peers") {
+		s.fakeGroupStore.serveHTTP(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		if object, ok := s.objects[r.URL.Path]; ok {
+			_ = json.NewEncoder(w).Encode(object)
+			return
+		}
+		var paths []string
+		for path := range s.objects {
+			if rest, ok := strings.CutPrefix(path, r.URL.Path+"/"); ok && !strings.Contains(rest, "/") {
+				paths = append(paths, path)
+			}
+		}
+		sort.Strings(paths)
+		list := make([]any, 0, len(paths))
+		for _, path := range paths {
+			list = append(list, s.objects[path])
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	case http.MethodPut:
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid body", http.StatusUnprocessableEntity)
+			return
+		}
+		if limit, ok := s.putLimit[r.URL.Path]; s.failPut[r.URL.Path] || ok && len(s.writes[r.URL.Path]) >= limit {
+			http.Error(w, "update failed", http.StatusInternalServerError)
+			return
+		}
+		s.writes[r.URL.Path] = append(s.writes[r.URL.Path], body)
+		if object, ok := s.objects[r.URL.Path]; ok {
+			// Respond in the object's own format, which request bodies don't always share
+			_ = json.NewEncoder(w).Encode(object)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(body)
+	case http.MethodPost:
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid body", http.StatusUnprocessableEntity)
+			return
+		}
+		s.posts++
+		body["id"] = fmt.Sprintf("new-%d", s.posts)
+		s.objects[r.URL.Path+"/"+body["id"].(string)] = body
+		s.writes[r.URL.Path] = append(s.writes[r.URL.Path], body)
+		_ = json.NewEncoder(w).Encode(body)
+	case http.MethodDelete:
+		if _, ok := s.objects[r.URL.Path]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if s.failDelete[r.URL.Path] {
+			http.Error(w, "delete failed", http.StatusInternalServerError)
+			return
+		}
+		for path := range s.objects {
+			if path == r.URL.Path || strings.HasPrefix(path, r.URL.Path+"/") {
+				delete(s.objects, path)
+			}
+		}
+		s.deleted = append(s.deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request "+r.Method+" "+r.URL.Path, http.StatusBadRequest)
+	}
+}
+
+// lastWrite returns the last body written to path
+func (s *fakeMergeStore) lastWrite(t *testing.T, path string) map[string]any {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writes := s.writes[path]
+	if len(writes) == 0 {
+		t.Fatalf("expected %s to be written", path)
+	}
+	return writes[len(writes)-1]
+}
+
+// bodyIDs returns the string list at key of a decoded JSON body
+func bodyIDs(body map[string]any, key string) []string {
+	values, _ := body[key].([]any)
+	ids := make([]string, 0, len(values))
+	for _, v := range values {
+		ids = append(ids, v.(string))
+	}
+	return ids
+}
+
+func mergeTestObjects() map[string]any {
+	return map[string]any{
+		"/policies/pol1": NetbirdPolicy{ID: "pol1", Name: "dev access", Enabled: true, Rules: []NetbirdPolicyRule{{
+			Name:             "ssh",
+			Action:           "accept",
+			Protocol:         "tcp",
+			Enabled:          true,
+			Sources:          []NetbirdPeerGroup{{ID: "g2", Name: "developers"}, {ID: "g1", Name: "devs"}},
+			Destinations:     []NetbirdPeerGroup{{ID: "g3", Name: "dev-team"}},
+			AuthorizedGroups: &map[string][]string{"g2": {"alice"}, "g1": {"bob", "alice"}},
+		}}},
+		"/policies/pol2": NetbirdPolicy{ID: "pol2", Name: "ops", Enabled: true, Rules: []NetbirdPolicyRule{{
+			Name: "all", Action: "accept", Protocol: "all", Enabled: true,
+			Sources:      []NetbirdPeerGroup{{ID: "g4"}},
+			Destinations: []NetbirdPeerGroup{{ID: "g4"}},
+		}}},
+		"/routes/rt1":                 NetbirdRoute{ID: "rt1", NetworkID: "office", Network: "10.0.0.0/24", Groups: []string{"g2"}, PeerGroups: []string{"g3"}, Metric: 100, Enabled: true},
+		"/dns/nameservers/ns1":        NetbirdNameservers{ID: "ns1", Name: "internal", Groups: []string{"g1", "g2"}, Enabled: true},
+		"/networks/n1":                NetbirdNetwork{ID: "n1", Name: "lab"},
+		"/networks/n1/resources/res1": NetbirdNetworkResource{ID: "res1", Name: "db", Address: "10.1.0.5/32", Enabled: true, Groups: []NetbirdNetworkResourceGroup{{ID: "g3"}}},
+		"/networks/n1/routers/rtr1":   NetbirdNetworkRouter{ID: "rtr1", PeerGroups: &[]string{"g2"}, Metric: 9999, Enabled: true},
+		"/setup-keys/k1":              NetbirdSetupKey{ID: "k1", Name: "dev laptops", AutoGroups: []string{"g2"}},
+		"/setup-keys/k2":              NetbirdSetupKey{ID: "k2", Name: "servers", AutoGroups: []string{"g4"}},
+		"/users/u1":                   NetbirdUser{ID: "u1", Email: "alice@example.com", Role: "user", AutoGroups: []string{"g3", "g1"}},
+		"/dns/settings":               netbirdDNSSettings{DisabledManagementGroups: []string{"g2"}},
+	}
+}
+
+func mergeTestGroups() []groupPreImage {
+	return []groupPreImage{
+		{ID: "g1", Name: "devs", Peers: []NetbirdGroupMember{{ID: "p1"}}},
+		{ID: "g2", Name: "developers", Peers: []NetbirdGroupMember{{ID: "p2"}}, Resources: []GroupResource{{ID: "res1", Type: "host"}}},
+		{ID: "g3", Name: "dev-team", Peers: []NetbirdGroupMember{{ID: "p1"}, {ID: "p3"}}},
+		{ID: "g4", Name: "ops"},
+	}
+}
+
+func TestMergeNetbirdGroups(t *testing.T) {
+	store := newFakeMergeStore(t, mergeTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "devs", Sources: []string{"g2", "Dev-Team"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.OperationID == "" || len(result.DeletedGroups) != 2 || len(result.Errors) != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	target := store.group("g1")
+	if !slices.Equal(groupPeerIDs(target), []string{"p1", "p2", "p3"}) || target.Name != "devs" {
+		t.Errorf("expected the members of the sources in the target, got %+v", target)
+	}
+	if len(target.Resources) != 1 || target.Resources[0].ID != "res1" {
+		t.Errorf("expected the resources of the sources in the target, got %+v", target.Resources)
+	}
+	if store.hasGroup("g2") || store.hasGroup("g3") {
+		t.Error("expected the source groups to be deleted")
+	}
+
+	updated := make([]string, 0, len(result.Updated))
+	for _, update := range result.Updated {
+		updated = append(updated, update.Kind+"/"+update.ID)
+	}
+	sort.Strings(updated)
+	want := []string{"dns_settings/dns", "nameserver/ns1", "network_resource/res1", "network_router/rtr1", "policy/pol1", "route/rt1", "setup_key/k1", "user/u1"}
+	if !slices.Equal(updated, want) {
+		t.Errorf("expected updates %v, got %v", want, updated)
+	}
+
+	rule := store.lastWrite(t, "/policies/pol1")["rules"].([]any)[0].(map[string]any)
+	if got := bodyIDs(rule, "sources"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("expected duplicate sources to collapse into the target, got %v", got)
+	}
+	if got := bodyIDs(rule, "destinations"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("expected destinations to refer to the target, got %v", got)
+	}
+	authorized := rule["authorized_groups"].(map[string]any)
+	if users := bodyIDs(authorized, "g1"); len(authorized) != 1 || !slices.Equal(users, []string{"bob", "alice"}) {
+		t.Errorf("expected authorized users to be merged, got %v", authorized)
+	}
+
+	route := store.lastWrite(t, "/routes/rt1")
+	if !slices.Equal(bodyIDs(route, "groups"), []string{"g1"}) || !slices.Equal(bodyIDs(route, "peer_groups"), []string{"g1"}) || route["network"] != "10.0.0.0/24" {
+		t.Errorf("unexpected route update %v", route)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/dns/nameservers/ns1"), "groups"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("unexpected nameserver groups %v", got)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/networks/n1/resources/res1"), "groups"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("unexpected network resource groups %v", got)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/networks/n1/routers/rtr1"), "peer_groups"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("unexpected network router groups %v", got)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/setup-keys/k1"), "auto_groups"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("unexpected setup key groups %v", got)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/users/u1"), "auto_groups"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("unexpected user groups %v", got)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/dns/settings"), "disabled_management_groups"); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("unexpected DNS settings %v", got)
+	}
+	if len(store.writes["/policies/pol2"]) != 0 || len(store.writes["/setup-keys/k2"]) != 0 {
+		t.Error("expected objects without references to the sources to be left alone")
+	}
+
+	// Rolling back recreates the source groups and points the references at them
+	rollback, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID})
+	if err != nil || len(rollback.Errors) != 0 {
+		t.Fatalf("unexpected rollback result %+v: %v", rollback, err)
+	}
+	developers, devTeam := rollback.Recreated["g2"], rollback.Recreated["g3"]
+	if developers == "" || devTeam == "" {
+		t.Fatalf("expected the source groups to be recreated, got %v", rollback.Recreated)
+	}
+	route = store.lastWrite(t, "/routes/rt1")
+	if !slices.Equal(bodyIDs(route, "groups"), []string{developers}) || !slices.Equal(bodyIDs(route, "peer_groups"), []string{devTeam}) {
+		t.Errorf("expected the route to refer to the recreated groups, got %v", route)
+	}
+	if got := groupPeerIDs(store.group("g1")); !slices.Equal(got, []string{"p1"}) {
+		t.Errorf("expected the target members to be restored, got %v", got)
+	}
+}
+
+func TestMergeNetbirdGroups_RollsBackWhenAReferenceCantBeRewritten(t *testing.T) {
+	store := newFakeMergeStore(t, mergeTestObjects(), mergeTestGroups()...)
+	store.failPut["/setup-keys/k1"] = true
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	result, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "g1", Sources: []string{"g2"}})
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("expected the merge to be rolled back, got %v", err)
+	}
+	if !result.RolledBack || !strings.Contains(strings.Join(result.Errors, "; "), "setup_key k1") {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if !store.hasGroup("g2") {
+		t.Error("expected the source group to be kept")
+	}
+	if got := groupPeerIDs(store.group("g1")); !slices.Equal(got, []string{"p1"}) {
+		t.Errorf("expected the target members to be restored, got %v", got)
+	}
+	if got := bodyIDs(store.lastWrite(t, "/routes/rt1"), "groups"); !slices.Equal(got, []string{"g2"}) {
+		t.Errorf("expected the route to be restored, got %v", got)
+	}
+}
+
+func TestMergeNetbirdGroups_AccountSettings(t *testing.T) {
+	objects := dependencyTestObjects()
+	objects["/accounts/acc1"] = NetbirdAccount{ID: "acc1", Settings: NetbirdAccountSettings{
+		JWTAllowGroups: []string{"admins"},
+		Extra:          &NetbirdAccountExtra{NetworkTrafficLogsEnabled: true, NetworkTrafficLogsGroups: []string{"g2", "g1"}},
+	}}
+	store := newFakeMergeStore(t, objects, mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	// Every reference to the source must be rewritten by the merge
+	deps, err := FindDependencies(ctx, mcpnetbird.TestNetbirdClient, "group", "g2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "g1", Sources: []string{"g2"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, ref := range deps.References {
+		if !slices.ContainsFunc(result.Updated, func(u GroupReferenceUpdate) bool {
+			return u.Kind == ref.Kind && u.ID == ref.ID && slices.Contains(u.Fields, ref.Field)
+		}) {
+			t.Errorf("expected %s %s %s to be rewritten, got %+v", ref.Kind, ref.ID, ref.Field, result.Updated)
+		}
+	}
+
+	accountGroups := func() []string {
+		settings := store.lastWrite(t, "/accounts/acc1")["settings"].(map[string]any)
+		if !slices.Equal(bodyIDs(settings, "jwt_allow_groups"), []string{"admins"}) {
+			t.Errorf("expected the other settings to be kept, got %v", settings)
+		}
+		return bodyIDs(settings["extra"].(map[string]any), "network_traffic_logs_groups")
+	}
+	if got := accountGroups(); !slices.Equal(got, []string{"g1"}) {
+		t.Errorf("expected the traffic logs groups to refer to the target, got %v", got)
+	}
+
+	rollback, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID})
+	if err != nil || len(rollback.Errors) != 0 {
+		t.Fatalf("unexpected rollback result %+v: %v", rollback, err)
+	}
+	if got := accountGroups(); !slices.Equal(got, []string{rollback.Recreated["g2"], "g1"}) {
+		t.Errorf("expected the traffic logs groups to refer to the recreated group, got %v", got)
+	}
+}
+
+func TestMergeNetbirdGroups_RefusesJWTAllowGroups(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	_, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "g1", Sources: []string{"g2"}})
+	if err == nil || !strings.Contains(err.Error(), "jwt_allow_groups") {
+		t.Fatalf("expected a source in the JWT allow groups to be refused, got %v", err)
+	}
+	if len(store.puts) != 0 || len(store.writes) != 0 || !store.hasGroup("g2") {
+		t.Error("expected nothing to be written")
+	}
+}
+
+func TestMergeNetbirdGroups_InvalidGroups(t *testing.T) {
+	groups := append(mergeTestGroups(), groupPreImage{ID: "g0", Name: allGroupName})
+	store := newFakeMergeStore(t, mergeTestObjects(), groups...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	if _, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "g1", Sources: []string{"g2", "devs"}}); err == nil || !strings.Contains(err.Error(), "into itself") {
+		t.Errorf("expected merging a group into itself to fail, got %v", err)
+	}
+	if _, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "g1", Sources: []string{allGroupName}}); err == nil || !strings.Contains(err.Error(), "All group") {
+		t.Errorf("expected merging the All group to fail, got %v", err)
+	}
+	if _, err := mergeNetbirdGroups(ctx, MergeNetbirdGroupsParams{Target: "qa", Sources: []string{"g2"}}); err == nil || !strings.Contains(err.Error(), `group "qa" not found`) {
+		t.Errorf("expected an unknown target to fail, got %v", err)
+	}
+	if len(store.puts) != 0 || len(store.writes) != 0 {
+		t.Error("expected nothing to be written")
+	}
+
+	impact, err := describeGroupMerge(ctx, MergeNetbirdGroupsParams{Target: "g1", Sources: []string{"g3"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updates := impact.Affected.([]GroupReferenceUpdate); len(updates) != 4 {
+		t.Errorf("expected the policy, route, network resource and user to be affected, got %+v", updates)
+	}
+}
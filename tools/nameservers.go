@@ -9,8 +9,8 @@ import (
 
 type Nameserver struct {
 	IP     string `json:"ip"`
-	NSType string `json:"ns_type"`
-	Port   int    `json:"port"`
+	NSType string `json:"ns_type" jsonschema:"enum=udp"`
+	Port   int    `json:"port" jsonschema:"minimum=1,maximum=65535"`
 }
 
 type NetbirdNameservers struct {
@@ -25,7 +25,9 @@ type NetbirdNameservers struct {
 	SearchDomainsEnabled bool         `json:"search_domains_enabled"`
 }
 
-type ListNetbirdNameserversParams struct{}
+type ListNetbirdNameserversParams struct {
+	ListOptions
+}
 
 func listNetbirdNameservers(ctx context.Context, args ListNetbirdNameserversParams) ([]NetbirdNameservers, error) {
 	var client *mcpnetbird.NetbirdClient
@@ -46,7 +48,8 @@ func listNetbirdNameservers(ctx context.Context, args ListNetbirdNameserversPara
 var ListNetbirdNameservers = mcpnetbird.MustTool(
 	"list_netbird_nameservers",
 	"List all Netbird nameservers",
-	listNetbirdNameservers,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdNameservers),
 )
 
 type GetNetbirdNameserverParams struct {
@@ -70,6 +73,7 @@ func getNetbirdNameserver(ctx context.Context, args GetNetbirdNameserverParams)
 var GetNetbirdNameserver = mcpnetbird.MustTool(
 	"get_netbird_nameserver",
 	"Get a specific Netbird nameserver by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdNameserver,
 )
 
@@ -101,6 +105,7 @@ func createNetbirdNameserver(ctx context.Context, args CreateNetbirdNameserverPa
 var CreateNetbirdNameserver = mcpnetbird.MustTool(
 	"create_netbird_nameserver",
 	"Create a new Netbird nameserver",
+	mcpnetbird.AdditiveTool,
 	createNetbirdNameserver,
 )
 
@@ -133,11 +138,13 @@ func updateNetbirdNameserver(ctx context.Context, args UpdateNetbirdNameserverPa
 var UpdateNetbirdNameserver = mcpnetbird.MustTool(
 	"update_netbird_nameserver",
 	"Update an existing Netbird nameserver",
+	mcpnetbird.UpdateTool,
 	updateNetbirdNameserver,
 )
 
 type DeleteNetbirdNameserverParams struct {
 	NameserverID string `json:"nameserver_id" jsonschema:"required,description=The ID of the nameserver to delete"`
+	Confirmation
 }
 
 func deleteNetbirdNameserver(ctx context.Context, args DeleteNetbirdNameserverParams) (map[string]string, error) {
@@ -155,8 +162,14 @@ func deleteNetbirdNameserver(ctx context.Context, args DeleteNetbirdNameserverPa
 
 var DeleteNetbirdNameserver = mcpnetbird.MustTool(
 	"delete_netbird_nameserver",
-	"Delete a Netbird nameserver",
-	deleteNetbirdNameserver,
+	"Delete a Netbird nameserver. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdNameserverParams) string {
+			return "/dns/nameservers/" + args.NameserverID
+		}),
+		deleteNetbirdNameserver,
+	),
 )
 
 func AddNetbirdNameserverTools(mcp *server.MCPServer) {
@@ -29,6 +29,7 @@ type NetbirdNetworkResource struct {
 
 type ListNetbirdNetworkResourcesParams struct {
 	NetworkID string `json:"network_id" jsonschema:"required,description=The ID of the network"`
+	ListOptions
 }
 
 func listNetbirdNetworkResources(ctx context.Context, args ListNetbirdNetworkResourcesParams) ([]NetbirdNetworkResource, error) {
@@ -50,7 +51,8 @@ func listNetbirdNetworkResources(ctx context.Context, args ListNetbirdNetworkRes
 var ListNetbirdNetworkResources = mcpnetbird.MustTool(
 	"list_netbird_network_resources",
 	"List all network resources in a Netbird network",
-	listNetbirdNetworkResources,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdNetworkResources),
 )
 
 type GetNetbirdNetworkResourceParams struct {
@@ -75,6 +77,7 @@ func getNetbirdNetworkResource(ctx context.Context, args GetNetbirdNetworkResour
 var GetNetbirdNetworkResource = mcpnetbird.MustTool(
 	"get_netbird_network_resource",
 	"Get a specific Netbird network resource by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdNetworkResource,
 )
 
@@ -116,6 +119,7 @@ func createNetbirdNetworkResource(ctx context.Context, args CreateNetbirdNetwork
 var CreateNetbirdNetworkResource = mcpnetbird.MustTool(
 	"create_netbird_network_resource",
 	"Create a new Netbird network resource",
+	mcpnetbird.AdditiveTool,
 	createNetbirdNetworkResource,
 )
 
@@ -165,12 +169,14 @@ func updateNetbirdNetworkResource(ctx context.Context, args UpdateNetbirdNetwork
 var UpdateNetbirdNetworkResource = mcpnetbird.MustTool(
 	"update_netbird_network_resource",
 	"Update an existing Netbird network resource",
+	mcpnetbird.UpdateTool,
 	updateNetbirdNetworkResource,
 )
 
 type DeleteNetbirdNetworkResourceParams struct {
 	NetworkID  string `json:"network_id" jsonschema:"required,description=The ID of the network"`
 	ResourceID string `json:"resource_id" jsonschema:"required,description=The ID of the network resource to delete"`
+	Confirmation
 }
 
 func deleteNetbirdNetworkResource(ctx context.Context, args DeleteNetbirdNetworkResourceParams) (map[string]string, error) {
@@ -188,8 +194,14 @@ func deleteNetbirdNetworkResource(ctx context.Context, args DeleteNetbirdNetwork
 
 var DeleteNetbirdNetworkResource = mcpnetbird.MustTool(
 	"delete_netbird_network_resource",
-	"Delete a Netbird network resource",
-	deleteNetbirdNetworkResource,
+	"Delete a Netbird network resource. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdNetworkResourceParams) string {
+			return "/networks/" + args.NetworkID + "/resources/" + args.ResourceID
+		}),
+		deleteNetbirdNetworkResource,
+	),
 )
 
 func AddNetbirdNetworkResourceTools(mcp *server.MCPServer) {
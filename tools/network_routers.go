@@ -19,6 +19,7 @@ type NetbirdNetworkRouter struct {
 
 type ListNetbirdNetworkRoutersParams struct {
 	NetworkID string `json:"network_id" jsonschema:"required,description=The ID of the network"`
+	ListOptions
 }
 
 func listNetbirdNetworkRouters(ctx context.Context, args ListNetbirdNetworkRoutersParams) ([]NetbirdNetworkRouter, error) {
@@ -40,7 +41,8 @@ func listNetbirdNetworkRouters(ctx context.Context, args ListNetbirdNetworkRoute
 var ListNetbirdNetworkRouters = mcpnetbird.MustTool(
 	"list_netbird_network_routers",
 	"List all network routers in a Netbird network",
-	listNetbirdNetworkRouters,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdNetworkRouters),
 )
 
 type GetNetbirdNetworkRouterParams struct {
@@ -65,6 +67,7 @@ func getNetbirdNetworkRouter(ctx context.Context, args GetNetbirdNetworkRouterPa
 var GetNetbirdNetworkRouter = mcpnetbird.MustTool(
 	"get_netbird_network_router",
 	"Get a specific Netbird network router by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdNetworkRouter,
 )
 
@@ -72,7 +75,7 @@ type CreateNetbirdNetworkRouterParams struct {
 	NetworkID  string    `json:"network_id" jsonschema:"required,description=The ID of the network"`
 	Peer       *string   `json:"peer,omitempty" jsonschema:"description=Peer ID (cannot be used with peer_groups)"`
 	PeerGroups *[]string `json:"peer_groups,omitempty" jsonschema:"description=Peer group IDs (cannot be used with peer)"`
-	Metric     int       `json:"metric" jsonschema:"required,description=Route metric (1-9999)"`
+	Metric     int       `json:"metric" jsonschema:"required,minimum=1,maximum=9999,description=Route metric (1-9999)"`
 	Masquerade bool      `json:"masquerade" jsonschema:"required,description=Enable masquerading"`
 	Enabled    bool      `json:"enabled" jsonschema:"required,description=Router status"`
 }
@@ -108,6 +111,7 @@ func createNetbirdNetworkRouter(ctx context.Context, args CreateNetbirdNetworkRo
 var CreateNetbirdNetworkRouter = mcpnetbird.MustTool(
 	"create_netbird_network_router",
 	"Create a new Netbird network router",
+	mcpnetbird.AdditiveTool,
 	createNetbirdNetworkRouter,
 )
 
@@ -116,7 +120,7 @@ type UpdateNetbirdNetworkRouterParams struct {
 	RouterID   string    `json:"router_id" jsonschema:"required,description=The ID of the network router to update"`
 	Peer       *string   `json:"peer,omitempty" jsonschema:"description=Peer ID (cannot be used with peer_groups)"`
 	PeerGroups *[]string `json:"peer_groups,omitempty" jsonschema:"description=Peer group IDs (cannot be used with peer)"`
-	Metric     *int      `json:"metric,omitempty" jsonschema:"description=Route metric (1-9999)"`
+	Metric     *int      `json:"metric,omitempty" jsonschema:"minimum=1,maximum=9999,description=Route metric (1-9999)"`
 	Masquerade *bool     `json:"masquerade,omitempty" jsonschema:"description=Enable masquerading"`
 	Enabled    *bool     `json:"enabled,omitempty" jsonschema:"description=Router status"`
 }
@@ -157,12 +161,14 @@ func updateNetbirdNetworkRouter(ctx context.Context, args UpdateNetbirdNetworkRo
 var UpdateNetbirdNetworkRouter = mcpnetbird.MustTool(
 	"update_netbird_network_router",
 	"Update an existing Netbird network router",
+	mcpnetbird.UpdateTool,
 	updateNetbirdNetworkRouter,
 )
 
 type DeleteNetbirdNetworkRouterParams struct {
 	NetworkID string `json:"network_id" jsonschema:"required,description=The ID of the network"`
 	RouterID  string `json:"router_id" jsonschema:"required,description=The ID of the network router to delete"`
+	Confirmation
 }
 
 func deleteNetbirdNetworkRouter(ctx context.Context, args DeleteNetbirdNetworkRouterParams) (map[string]string, error) {
@@ -180,8 +186,14 @@ func deleteNetbirdNetworkRouter(ctx context.Context, args DeleteNetbirdNetworkRo
 
 var DeleteNetbirdNetworkRouter = mcpnetbird.MustTool(
 	"delete_netbird_network_router",
-	"Delete a Netbird network router",
-	deleteNetbirdNetworkRouter,
+	"Delete a Netbird network router. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdNetworkRouterParams) string {
+			return "/networks/" + args.NetworkID + "/routers/" + args.RouterID
+		}),
+		deleteNetbirdNetworkRouter,
+	),
 )
 
 func AddNetbirdNetworkRouterTools(mcp *server.MCPServer) {
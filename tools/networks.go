@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"slices"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
 	"github.com/mark3labs/mcp-go/server"
@@ -18,7 +20,9 @@ type NetbirdNetwork struct {
 	Policies           []string `json:"policies"`
 }
 
-type ListNetbirdNetworksParams struct{}
+type ListNetbirdNetworksParams struct {
+	ListOptions
+}
 
 func listNetbirdNetworks(ctx context.Context, args ListNetbirdNetworksParams) ([]NetbirdNetwork, error) {
 	var client *mcpnetbird.NetbirdClient
@@ -39,7 +43,8 @@ func listNetbirdNetworks(ctx context.Context, args ListNetbirdNetworksParams) ([
 var ListNetbirdNetworks = mcpnetbird.MustTool(
 	"list_netbird_networks",
 	"List all Netbird networks",
-	listNetbirdNetworks,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdNetworks),
 )
 
 type GetNetbirdNetworkParams struct {
@@ -63,6 +68,7 @@ func getNetbirdNetwork(ctx context.Context, args GetNetbirdNetworkParams) (*Netb
 var GetNetbirdNetwork = mcpnetbird.MustTool(
 	"get_netbird_network",
 	"Get a specific Netbird network by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdNetwork,
 )
 
@@ -88,6 +94,7 @@ func createNetbirdNetwork(ctx context.Context, args CreateNetbirdNetworkParams)
 var CreateNetbirdNetwork = mcpnetbird.MustTool(
 	"create_netbird_network",
 	"Create a new Netbird network",
+	mcpnetbird.AdditiveTool,
 	createNetbirdNetwork,
 )
 
@@ -114,30 +121,124 @@ func updateNetbirdNetwork(ctx context.Context, args UpdateNetbirdNetworkParams)
 var UpdateNetbirdNetwork = mcpnetbird.MustTool(
 	"update_netbird_network",
 	"Update an existing Netbird network",
+	mcpnetbird.UpdateTool,
 	updateNetbirdNetwork,
 )
 
 type DeleteNetbirdNetworkParams struct {
 	NetworkID string `json:"network_id" jsonschema:"required,description=The ID of the network to delete"`
+	Force     bool   `json:"force,omitempty" jsonschema:"description=Force delete by removing the network's resources from the policies using them first"`
+	Confirmation
+}
+
+// networkResourceIDs returns the IDs of the resources among a network's dependencies
+func networkResourceIDs(references []DependencyReference) []string {
+	var ids []string
+	for _, ref := range references {
+		if ref.Kind == journalKindNetworkResource {
+			ids = append(ids, ref.ID)
+		}
+	}
+	return ids
 }
 
-func deleteNetbirdNetwork(ctx context.Context, args DeleteNetbirdNetworkParams) (map[string]string, error) {
+// describeNetworkDeletion reports the network, its resources and routers and
+// the policies using its resources
+func describeNetworkDeletion(ctx context.Context, args DeleteNetbirdNetworkParams) (*Impact, error) {
 	var client *mcpnetbird.NetbirdClient
 	if mcpnetbird.TestNetbirdClient != nil {
 		client = mcpnetbird.TestNetbirdClient
 	} else {
 		client = mcpnetbird.NewNetbirdClient(ctx)
 	}
-	if err := client.Delete(ctx, "/networks/"+args.NetworkID); err != nil {
+
+	var network NetbirdNetwork
+	if err := client.Get(ctx, "/networks/"+args.NetworkID, &network); err != nil {
+		return nil, err
+	}
+	deps, err := FindDependencies(ctx, client, "network", args.NetworkID)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependencies: %w", err)
+	}
+
+	impact := &Impact{Action: "delete /networks/" + args.NetworkID, Target: network, Affected: deps.References}
+	policies, children := splitPolicyDependencies(deps.References)
+	if len(children) > 0 {
+		impact.Warnings = append(impact.Warnings, fmt.Sprintf("%d resources and routers of the network will be deleted with it", len(children)))
+	}
+	if len(policies) > 0 {
+		if args.Force {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("the network's resources will be removed from %d policies; rules left without sources or destinations and policies left without rules will be deleted", len(policies)))
+		} else {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("the network's resources are used by %d policies and the network will not be deleted unless force=true", len(policies)))
+		}
+	}
+	return impact, nil
+}
+
+func deleteNetbirdNetwork(ctx context.Context, args DeleteNetbirdNetworkParams) (map[string]interface{}, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	// Resources and routers go with the network; only policies are in the way
+	deps, err := FindDependencies(ctx, client, "network", args.NetworkID)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependencies: %w", err)
+	}
+	policyIDs, _ := splitPolicyDependencies(deps.References)
+
+	if len(policyIDs) == 0 {
+		if err := client.Delete(ctx, "/networks/"+args.NetworkID); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"status": "deleted", "network_id": args.NetworkID, "force": false}, nil
+	}
+	if !args.Force {
+		return nil, fmt.Errorf("cannot delete network '%s': its resources are used by %d policies %v. Use force=true to remove them from those policies first",
+			args.NetworkID, len(policyIDs), policyIDs)
+	}
+
+	resourceIDs := networkResourceIDs(deps.References)
+	detach := func(policy *NetbirdPolicy) {
+		for i := range policy.Rules {
+			rule := &policy.Rules[i]
+			if rule.SourceResource != nil && slices.Contains(resourceIDs, rule.SourceResource.ID) {
+				rule.SourceResource = nil
+			}
+			if rule.DestinationResource != nil && slices.Contains(resourceIDs, rule.DestinationResource.ID) {
+				rule.DestinationResource = nil
+			}
+		}
+	}
+
+	// The network itself is not journaled: its resources and routers can't be
+	// recreated with it
+	op := startOperation(ctx, "delete_network_force "+args.NetworkID)
+	result, err := forceDelete(ctx, client, op, "network "+args.NetworkID, policyIDs, detach, func() error {
+		return client.Delete(ctx, "/networks/"+args.NetworkID)
+	})
+	if err != nil {
 		return nil, err
 	}
-	return map[string]string{"status": "deleted", "network_id": args.NetworkID}, nil
+	return map[string]interface{}{
+		"status":            "deleted",
+		"network_id":        args.NetworkID,
+		"force":             true,
+		"operation_id":      result.OperationID,
+		"policies_modified": result.PoliciesModified,
+		"errors":            result.Errors,
+	}, nil
 }
 
 var DeleteNetbirdNetwork = mcpnetbird.MustTool(
 	"delete_netbird_network",
-	"Delete a Netbird network",
-	deleteNetbirdNetwork,
+	"Delete a Netbird network along with its resources and routers. If policies use its resources, returns an error listing them unless force=true, which removes the resources from those policies first; if any policy cannot be updated the changes are rolled back and the network is kept. rollback_netbird_operation restores the policies but can't recreate the network. The first call returns an impact summary and a confirmation_token; call again with the token to delete.",
+	mcpnetbird.DeleteTool,
+	withConfirmation(describeNetworkDeletion, deleteNetbirdNetwork),
 )
 
 func AddNetbirdNetworkTools(mcp *server.MCPServer) {
@@ -389,6 +389,18 @@ func TestUpdateNetbirdNetwork_PartialUpdate(t *testing.T) {
 func TestDeleteNetbirdNetwork(t *testing.T) {
 	// Create mock HTTP server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The network's dependencies are looked up before it is deleted
+		if r.Method == http.MethodGet {
+			switch r.URL.Path {
+			case "/networks/net1":
+				_, _ = w.Write([]byte(`{"id": "net1", "name": "Test Network"}`))
+			case "/networks/net1/resources", "/networks/net1/routers", "/policies":
+				_, _ = w.Write([]byte(`[]`))
+			default:
+				http.NotFound(w, r)
+			}
+			return
+		}
 		if r.URL.Path != "/networks/net1" {
 			http.NotFound(w, r)
 			return
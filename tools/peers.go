@@ -61,7 +61,9 @@ type NetbirdPeer struct {
 	Version                     string             `json:"version"`
 }
 
-type ListNetbirdPeersParams struct{}
+type ListNetbirdPeersParams struct {
+	ListOptions
+}
 
 func listNetbirdPeers(ctx context.Context, args ListNetbirdPeersParams) ([]NetbirdPeer, error) {
 	var client *mcpnetbird.NetbirdClient
@@ -82,7 +84,8 @@ func listNetbirdPeers(ctx context.Context, args ListNetbirdPeersParams) ([]Netbi
 var ListNetbirdPeers = mcpnetbird.MustTool(
 	"list_netbird_peers",
 	"List all Netbird peers",
-	listNetbirdPeers,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdPeers),
 )
 
 type GetNetbirdPeerParams struct {
@@ -106,6 +109,7 @@ func getNetbirdPeer(ctx context.Context, args GetNetbirdPeerParams) (*NetbirdPee
 var GetNetbirdPeer = mcpnetbird.MustTool(
 	"get_netbird_peer",
 	"Get a specific Netbird peer by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdPeer,
 )
 
@@ -132,11 +136,13 @@ func updateNetbirdPeer(ctx context.Context, args UpdateNetbirdPeerParams) (*Netb
 var UpdateNetbirdPeer = mcpnetbird.MustTool(
 	"update_netbird_peer",
 	"Update an existing Netbird peer",
+	mcpnetbird.UpdateTool,
 	updateNetbirdPeer,
 )
 
 type DeleteNetbirdPeerParams struct {
 	PeerID string `json:"peer_id" jsonschema:"required,description=The ID of the peer to delete"`
+	Confirmation
 }
 
 func deleteNetbirdPeer(ctx context.Context, args DeleteNetbirdPeerParams) (map[string]string, error) {
@@ -154,8 +160,14 @@ func deleteNetbirdPeer(ctx context.Context, args DeleteNetbirdPeerParams) (map[s
 
 var DeleteNetbirdPeer = mcpnetbird.MustTool(
 	"delete_netbird_peer",
-	"Delete a Netbird peer",
-	deleteNetbirdPeer,
+	"Delete a Netbird peer. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdPeerParams) string {
+			return "/peers/" + args.PeerID
+		}),
+		deleteNetbirdPeer,
+	),
 )
 
 func AddNetbirdPeerTools(mcp *server.MCPServer) {
@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Modes of NewToolPermissionFilter for tools the API token can't use
+const (
+	ToolPermissionsOff      = "off"
+	ToolPermissionsAnnotate = "annotate"
+	ToolPermissionsHide     = "hide"
+)
+
+// toolCategory groups tools by the permission module the Netbird API checks for them
+type toolCategory struct {
+	Name   string
+	Module string
+}
+
+var toolCategories = []toolCategory{
+	{Name: "peers", Module: "peers"},
+	{Name: "ingress_ports", Module: "peers"},
+	{Name: "groups", Module: "groups"},
+	{Name: "policies", Module: "policies"},
+	{Name: "posture_checks", Module: "policies"},
+	{Name: "networks", Module: "networks"},
+	{Name: "routes", Module: "routes"},
+	{Name: "nameservers", Module: "nameservers"},
+	{Name: "setup_keys", Module: "setup_keys"},
+	{Name: "users", Module: "users"},
+	{Name: "account", Module: "accounts"},
+}
+
+// roleModuleAccess approximates the permissions of each built-in role for API
+// versions that don't return permissions with the current user. A module missing
+// from a role's map is not accessible; "*" applies to all other modules.
+var roleModuleAccess = map[string]map[string]CategoryAccess{
+	"owner": {"*": {Read: true, Write: true}},
+	"admin": {"*": {Read: true, Write: true}},
+	"network_admin": {
+		"*":        {Read: true, Write: true},
+		"users":    {Read: true},
+		"accounts": {Read: true},
+	},
+	"auditor":       {"*": {Read: true}},
+	"billing_admin": {"accounts": {Read: true}},
+	"user":          {"peers": {Read: true}},
+}
+
+// CategoryAccess reports whether the tools of a category can read and change resources
+type CategoryAccess struct {
+	Category string `json:"category,omitempty"`
+	Read     bool   `json:"read"`
+	Write    bool   `json:"write"`
+}
+
+// categoryAccess returns the access of user to every tool category, from its
+// permissions when the API returned them and from its role otherwise
+func categoryAccess(user *NetbirdCurrentUser) []CategoryAccess {
+	access := make([]CategoryAccess, 0, len(toolCategories))
+	for _, category := range toolCategories {
+		a := moduleAccess(user, category.Module)
+		a.Category = category.Name
+		access = append(access, a)
+	}
+	return access
+}
+
+func moduleAccess(user *NetbirdCurrentUser, module string) CategoryAccess {
+	if user.IsBlocked {
+		return CategoryAccess{}
+	}
+	if user.Permissions != nil && len(user.Permissions.Modules) > 0 {
+		ops := user.Permissions.Modules[module]
+		return CategoryAccess{Read: ops["read"], Write: ops["create"] || ops["update"] || ops["delete"]}
+	}
+	role := roleModuleAccess[user.Role]
+	if a, ok := role[module]; ok {
+		return a
+	}
+	return role["*"]
+}
+
+// readVerbs are tool name prefixes of tools that only read; any other verb writes
+var readVerbs = map[string]bool{"list": true, "get": true, "search": true}
+
+// toolNounCategories map the resource part of a tool name (after the verb and
+// "netbird_") to its category, by prefix. network_resource and network_router
+// tools belong to networks.
+var toolNounCategories = []struct{ prefix, category string }{
+	{"port_allocation", "ingress_ports"},
+	{"posture_check", "posture_checks"},
+	{"setup_key", "setup_keys"},
+	{"nameserver", "nameservers"},
+	{"network", "networks"},
+	{"route", "routes"},
+	{"peer", "peers"},
+	{"group", "groups"},
+	{"polic", "policies"},
+	{"user", "users"},
+	{"account", "account"},
+}
+
+// toolPermissionOverrides classify tools whose name doesn't follow verb_netbird_noun
+var toolPermissionOverrides = map[string]toolPermission{
+	"replace_group_in_policies":          {category: "policies", write: true},
+	"list_policies_by_group":             {category: "policies"},
+	"add_peer_to_groups":                 {category: "groups", write: true},
+	"remove_peer_from_groups":            {category: "groups", write: true},
+	"attach_posture_check_to_policies":   {category: "policies", write: true},
+	"detach_posture_check_from_policies": {category: "policies", write: true},
+}
+
+// ungatedTools don't need any Netbird permission, or report it themselves
+var ungatedTools = map[string]bool{
+	"get_netbird_current_user":   true,
+	"netbird_diagnostics":        true,
+	"clear_netbird_cache":        true,
+	"get_policy_template":        true,
+	"search_netbird":             true,
+	"get_netbird_dependencies":   true,
+	"render_netbird_topology":    true,
+	"rollback_netbird_operation": true,
+}
+
+type toolPermission struct {
+	category string
+	write    bool
+}
+
+// toolPermissionFor returns the category a tool belongs to and whether it writes.
+// It returns false for tools that aren't tied to a single category.
+func toolPermissionFor(name string) (toolPermission, bool) {
+	if ungatedTools[name] {
+		return toolPermission{}, false
+	}
+	if p, ok := toolPermissionOverrides[name]; ok {
+		return p, true
+	}
+	verb, noun, ok := strings.Cut(name, "_")
+	if !ok {
+		return toolPermission{}, false
+	}
+	noun = strings.TrimPrefix(noun, "netbird_")
+	for _, nc := range toolNounCategories {
+		if strings.HasPrefix(noun, nc.prefix) {
+			return toolPermission{category: nc.category, write: !readVerbs[verb]}, true
+		}
+	}
+	return toolPermission{}, false
+}
+
+// NewToolPermissionFilter returns a mcpnetbird.ToolFilter for tools user can't use:
+// "annotate" adds a note to their description and "hide" leaves them out. It
+// returns nil for "off".
+func NewToolPermissionFilter(user *NetbirdCurrentUser, mode string) (func(mcp.Tool) (mcp.Tool, bool), error) {
+	switch mode {
+	case ToolPermissionsOff:
+		return nil, nil
+	case ToolPermissionsAnnotate, ToolPermissionsHide:
+	default:
+		return nil, fmt.Errorf("invalid tool permissions mode '%s': must be off, annotate or hide", mode)
+	}
+
+	access := make(map[string]CategoryAccess, len(toolCategories))
+	for _, a := range categoryAccess(user) {
+		access[a.Category] = a
+	}
+
+	return func(tool mcp.Tool) (mcp.Tool, bool) {
+		p, ok := toolPermissionFor(tool.Name)
+		if !ok {
+			return tool, true
+		}
+		a := access[p.category]
+		missing := ""
+		switch {
+		case !a.Read:
+			missing = "read"
+		case p.write && !a.Write:
+			missing = "write"
+		default:
+			return tool, true
+		}
+		if mode == ToolPermissionsHide {
+			return tool, false
+		}
+		tool.Description = fmt.Sprintf("%s. Note: the configured API token (role %s) has no %s access to %s, so this tool is expected to fail", strings.TrimSuffix(tool.Description, "."), user.Role, missing, p.category)
+		return tool, true
+	}, nil
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// registeredTools returns the definitions of every tool the server registers
+func registeredTools(t *testing.T) []mcp.Tool {
+	t.Helper()
+	var tools []mcp.Tool
+	mcpnetbird.ToolFilter = func(tool mcp.Tool) (mcp.Tool, bool) {
+		tools = append(tools, tool)
+		return tool, true
+	}
+	defer func() { mcpnetbird.ToolFilter = nil }()
+
+	s := server.NewMCPServer("test", "0.0.0")
+	for _, add := range []func(*server.MCPServer){
+		AddNetbirdPeerTools, AddNetbirdGroupTools, AddNetbirdPolicyTools, AddNetbirdNetworkTools,
+		AddNetbirdNetworkResourceTools, AddNetbirdNetworkRouterTools, AddNetbirdPostureCheckTools,
+		AddNetbirdPortAllocationTools, AddNetbirdNameserverTools, AddNetbirdRouteTools,
+		AddNetbirdSetupKeyTools, AddNetbirdUserTools, AddNetbirdAccountTools, AddNetbirdSearchTools,
+		AddNetbirdCacheTools, AddNetbirdJournalTools, AddNetbirdDiagnosticsTools, AddNetbirdMembershipTools,
+		AddNetbirdDependencyTools, AddNetbirdTopologyTools,
+	} {
+		add(s)
+	}
+	return tools
+}
+
+func TestToolPermissionFor_CoversEveryTool(t *testing.T) {
+	categories := make(map[string]bool)
+	for _, c := range toolCategories {
+		categories[c.Name] = true
+	}
+	for _, tool := range registeredTools(t) {
+		p, ok := toolPermissionFor(tool.Name)
+		if !ok {
+			if !ungatedTools[tool.Name] {
+				t.Errorf("%s has no tool category", tool.Name)
+			}
+			continue
+		}
+		if !categories[p.category] {
+			t.Errorf("%s maps to unknown category %s", tool.Name, p.category)
+		}
+	}
+
+	cases := map[string]toolPermission{
+		"list_netbird_network_routers":   {category: "networks"},
+		"update_netbird_route":           {category: "routes", write: true},
+		"create_netbird_port_allocation": {category: "ingress_ports", write: true},
+		"get_netbird_posture_check":      {category: "posture_checks"},
+		"replace_group_in_policies":      {category: "policies", write: true},
+		"invite_netbird_user":            {category: "users", write: true},
+	}
+	for name, want := range cases {
+		if got, _ := toolPermissionFor(name); got != want {
+			t.Errorf("%s: expected %+v, got %+v", name, want, got)
+		}
+	}
+}
+
+func TestNewToolPermissionFilter(t *testing.T) {
+	auditor := &NetbirdCurrentUser{NetbirdUser: NetbirdUser{Role: "auditor"}}
+
+	if filter, err := NewToolPermissionFilter(auditor, ToolPermissionsOff); err != nil || filter != nil {
+		t.Errorf("expected no filter when off, got %v", err)
+	}
+	if _, err := NewToolPermissionFilter(auditor, "strict"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+
+	annotate, err := NewToolPermissionFilter(auditor, ToolPermissionsAnnotate)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tool, ok := annotate(mcp.Tool{Name: "delete_netbird_policy", Description: "Delete a Netbird policy"})
+	if !ok || !strings.Contains(tool.Description, "role auditor) has no write access to policies") {
+		t.Errorf("expected write note on delete tool, got %q", tool.Description)
+	}
+	tool, _ = annotate(mcp.Tool{Name: "list_netbird_policies", Description: "List all Netbird policies"})
+	if tool.Description != "List all Netbird policies" {
+		t.Errorf("expected read tool unchanged, got %q", tool.Description)
+	}
+
+	user := &NetbirdCurrentUser{
+		NetbirdUser: NetbirdUser{Role: "user"},
+		Permissions: &NetbirdUserPermissions{Modules: map[string]map[string]bool{"peers": {"read": true}}},
+	}
+	hide, _ := NewToolPermissionFilter(user, ToolPermissionsHide)
+	for name, visible := range map[string]bool{
+		"list_netbird_peers":       true,
+		"update_netbird_peer":      false,
+		"list_netbird_groups":      false,
+		"netbird_diagnostics":      true,
+		"get_netbird_current_user": true,
+	} {
+		if _, ok := hide(mcp.Tool{Name: name}); ok != visible {
+			t.Errorf("%s: expected visible=%v", name, visible)
+		}
+	}
+}
+
+func TestTools_DeclareBehavior(t *testing.T) {
+	for _, tool := range registeredTools(t) {
+		a := tool.Annotations
+		if a.ReadOnlyHint == nil || a.DestructiveHint == nil || a.IdempotentHint == nil || a.OpenWorldHint == nil {
+			t.Errorf("%s: expected every annotation hint to be set, got %+v", tool.Name, a)
+			continue
+		}
+		if p, ok := toolPermissionFor(tool.Name); ok && *a.ReadOnlyHint == p.write {
+			t.Errorf("%s: read-only hint %v contradicts its permission check", tool.Name, *a.ReadOnlyHint)
+		}
+		if strings.HasPrefix(tool.Name, "delete_") && !*a.DestructiveHint {
+			t.Errorf("%s: expected delete tool to be destructive", tool.Name)
+		}
+	}
+}
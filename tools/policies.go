@@ -4,16 +4,67 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/invopop/jsonschema"
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// Values the Netbird API accepts in policy rules. The tool schemas publish them
+// through JSONSchemaExtend, and ValidatePolicyRules checks rules against them.
+var (
+	policyRuleActions   = []string{"accept", "drop"}
+	policyRuleProtocols = []string{"tcp", "udp", "icmp", "all"}
+)
+
+// Valid port numbers, for policy rule and ingress port ranges
+const (
+	minPort = 1
+	maxPort = 65535
+)
+
+// cidrPattern matches an IPv4 or IPv6 network range in CIDR notation, for route
+// networks and posture check network ranges
+const cidrPattern = `^([0-9]+\.){3}[0-9]+/[0-9]+$|^[0-9a-fA-F:]*:[0-9a-fA-F:.]*/[0-9]+$`
+
+// requireCIDR sets the pattern of the named string properties, or of the items of
+// the named array properties, to cidrPattern
+func requireCIDR(schema *jsonschema.Schema, names ...string) {
+	for _, name := range names {
+		property, ok := schema.Properties.Get(name)
+		if !ok {
+			continue
+		}
+		if property.Items != nil {
+			property = property.Items
+		}
+		property.Pattern = cidrPattern
+	}
+}
+
 type PortRange struct {
 	Start int `json:"start"`
 	End   int `json:"end"`
 }
 
+// JSONSchemaExtend limits start and end to valid port numbers
+func (PortRange) JSONSchemaExtend(schema *jsonschema.Schema) {
+	limitPorts(schema, "start", "end")
+}
+
+// limitPorts sets the bounds of the named integer properties to valid port numbers
+func limitPorts(schema *jsonschema.Schema, names ...string) {
+	for _, name := range names {
+		if property, ok := schema.Properties.Get(name); ok {
+			property.Minimum = json.Number(strconv.Itoa(minPort))
+			property.Maximum = json.Number(strconv.Itoa(maxPort))
+		}
+	}
+}
+
 type ResourceReference struct {
 	ID   string `json:"id"`
 	Type string `json:"type"`
@@ -35,13 +86,83 @@ type NetbirdPolicyRule struct {
 	DestinationResource *ResourceReference      `json:"destinationResource,omitempty"`
 }
 
+// JSONSchemaExtend constrains action and protocol to the values the API accepts
+func (NetbirdPolicyRule) JSONSchemaExtend(schema *jsonschema.Schema) {
+	setEnum(schema, "action", policyRuleActions)
+	setEnum(schema, "protocol", policyRuleProtocols)
+}
+
+// setEnum restricts the named string property to values
+func setEnum(schema *jsonschema.Schema, name string, values []string) {
+	if property, ok := schema.Properties.Get(name); ok {
+		property.Enum = make([]any, len(values))
+		for i, v := range values {
+			property.Enum[i] = v
+		}
+	}
+}
+
 type NetbirdPolicy struct {
 	Description         string              `json:"description"`
 	Enabled             bool                `json:"enabled"`
 	ID                  string              `json:"id"`
 	Name                string              `json:"name"`
 	Rules               []NetbirdPolicyRule `json:"rules"`
-	SourcePostureChecks any                 `json:"source_posture_checks"`
+	SourcePostureChecks PostureCheckIDs     `json:"source_posture_checks"`
+}
+
+// PostureCheckIDs are the IDs in a policy's source_posture_checks, which the API
+// returns as IDs but older servers return as objects
+type PostureCheckIDs []string
+
+func (ids *PostureCheckIDs) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	if items == nil {
+		*ids = nil
+		return nil
+	}
+	parsed := make(PostureCheckIDs, 0, len(items))
+	for _, item := range items {
+		var id string
+		if err := json.Unmarshal(item, &id); err == nil {
+			parsed = append(parsed, id)
+			continue
+		}
+		var check struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(item, &check); err != nil {
+			return fmt.Errorf("source_posture_checks: %w", err)
+		}
+		parsed = append(parsed, check.ID)
+	}
+	*ids = parsed
+	return nil
+}
+
+// validatePostureCheckIDs checks that every ID is an existing posture check. It
+// reports every unknown ID at once.
+func validatePostureCheckIDs(ctx context.Context, client *mcpnetbird.NetbirdClient, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var checks []NetbirdPostureCheck
+	if err := client.Get(ctx, "/posture-checks", &checks); err != nil {
+		return fmt.Errorf("listing posture checks: %w", err)
+	}
+	var problems []string
+	for _, id := range ids {
+		if !slices.ContainsFunc(checks, func(c NetbirdPostureCheck) bool { return c.ID == id }) {
+			problems = append(problems, fmt.Sprintf("posture check %q not found", id))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
 }
 
 // FormatRuleForAPI converts a rule from MCP format to NetBird API request format.
@@ -121,9 +242,6 @@ func ValidatePolicyRules(rules []map[string]interface{}) error {
 		return nil // Empty rules are allowed
 	}
 
-	validActions := map[string]bool{"accept": true, "drop": true}
-	validProtocols := map[string]bool{"tcp": true, "udp": true, "icmp": true, "all": true}
-
 	for i, rule := range rules {
 		ruleName := getRuleIdentifier(rule, i)
 
@@ -146,8 +264,8 @@ func ValidatePolicyRules(rules []map[string]interface{}) error {
 
 		// Validate action enum
 		if action, ok := rule["action"].(string); ok {
-			if !validActions[action] {
-				return fmt.Errorf("rule %s: invalid action '%s', must be 'accept' or 'drop'", ruleName, action)
+			if !slices.Contains(policyRuleActions, action) {
+				return fmt.Errorf("rule %s: invalid action '%s', must be one of %s", ruleName, action, strings.Join(policyRuleActions, ", "))
 			}
 		} else {
 			return fmt.Errorf("rule %s: field 'action' must be a string", ruleName)
@@ -155,8 +273,8 @@ func ValidatePolicyRules(rules []map[string]interface{}) error {
 
 		// Validate protocol enum
 		if protocol, ok := rule["protocol"].(string); ok {
-			if !validProtocols[protocol] {
-				return fmt.Errorf("rule %s: invalid protocol '%s', must be 'tcp', 'udp', 'icmp', or 'all'", ruleName, protocol)
+			if !slices.Contains(policyRuleProtocols, protocol) {
+				return fmt.Errorf("rule %s: invalid protocol '%s', must be one of %s", ruleName, protocol, strings.Join(policyRuleProtocols, ", "))
 			}
 		} else {
 			return fmt.Errorf("rule %s: field 'protocol' must be a string", ruleName)
@@ -261,6 +379,9 @@ func validatePortRanges(portRanges interface{}, ruleName string) error {
 			return fmt.Errorf("rule %s: port_ranges[%d].end must be a number", ruleName, i)
 		}
 
+		if start < minPort || end > maxPort {
+			return fmt.Errorf("rule %s: port_ranges[%d] invalid: ports must be between %d and %d", ruleName, i, minPort, maxPort)
+		}
 		if start > end {
 			return fmt.Errorf("rule %s: port_ranges[%d] invalid: start (%d) must be <= end (%d)", ruleName, i, start, end)
 		}
@@ -269,7 +390,9 @@ func validatePortRanges(portRanges interface{}, ruleName string) error {
 	return nil
 }
 
-type ListNetbirdPoliciesParams struct{}
+type ListNetbirdPoliciesParams struct {
+	ListOptions
+}
 
 func listNetbirdPolicies(ctx context.Context, args ListNetbirdPoliciesParams) ([]NetbirdPolicy, error) {
 	var client *mcpnetbird.NetbirdClient
@@ -290,7 +413,8 @@ func listNetbirdPolicies(ctx context.Context, args ListNetbirdPoliciesParams) ([
 var ListNetbirdPolicies = mcpnetbird.MustTool(
 	"list_netbird_policies",
 	"List all Netbird policies",
-	listNetbirdPolicies,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdPolicies),
 )
 
 type GetNetbirdPolicyParams struct {
@@ -314,14 +438,16 @@ func getNetbirdPolicy(ctx context.Context, args GetNetbirdPolicyParams) (*Netbir
 var GetNetbirdPolicy = mcpnetbird.MustTool(
 	"get_netbird_policy",
 	"Get a specific Netbird policy by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdPolicy,
 )
 
 type CreateNetbirdPolicyParams struct {
-	Name        string               `json:"name" jsonschema:"required,description=Policy name"`
-	Description *string              `json:"description,omitempty" jsonschema:"description=Policy description"`
-	Enabled     *bool                `json:"enabled,omitempty" jsonschema:"description=Enable the policy"`
-	Rules       *[]NetbirdPolicyRule `json:"rules,omitempty" jsonschema:"description=Policy rules"`
+	Name                string               `json:"name" jsonschema:"required,description=Policy name"`
+	Description         *string              `json:"description,omitempty" jsonschema:"description=Policy description"`
+	Enabled             *bool                `json:"enabled,omitempty" jsonschema:"description=Enable the policy"`
+	Rules               *[]NetbirdPolicyRule `json:"rules,omitempty" jsonschema:"description=Policy rules"`
+	SourcePostureChecks *[]string            `json:"source_posture_checks,omitempty" jsonschema:"description=IDs of the posture checks source peers must pass"`
 }
 
 // structToMap converts a struct to map[string]interface{} using JSON marshaling
@@ -345,6 +471,12 @@ func createNetbirdPolicy(ctx context.Context, args CreateNetbirdPolicyParams) (*
 		client = mcpnetbird.NewNetbirdClient(ctx)
 	}
 	
+	if args.SourcePostureChecks != nil {
+		if err := validatePostureCheckIDs(ctx, client, *args.SourcePostureChecks); err != nil {
+			return nil, fmt.Errorf("validation error: %w", err)
+		}
+	}
+
 	// If rules are provided, validate and format them
 	if args.Rules != nil && len(*args.Rules) > 0 {
 		// Convert rules to map[string]interface{} for validation and formatting
@@ -383,6 +515,9 @@ func createNetbirdPolicy(ctx context.Context, args CreateNetbirdPolicyParams) (*
 		if args.Enabled != nil {
 			requestBody["enabled"] = *args.Enabled
 		}
+		if args.SourcePostureChecks != nil {
+			requestBody["source_posture_checks"] = *args.SourcePostureChecks
+		}
 		
 		var policy NetbirdPolicy
 		if err := client.Post(ctx, "/policies", requestBody, &policy); err != nil {
@@ -402,15 +537,17 @@ func createNetbirdPolicy(ctx context.Context, args CreateNetbirdPolicyParams) (*
 var CreateNetbirdPolicy = mcpnetbird.MustTool(
 	"create_netbird_policy",
 	"Create a new Netbird policy",
+	mcpnetbird.AdditiveTool,
 	createNetbirdPolicy,
 )
 
 type UpdateNetbirdPolicyParams struct {
-	PolicyID    string               `json:"policy_id" jsonschema:"required,description=The ID of the policy to update"`
-	Name        *string              `json:"name,omitempty" jsonschema:"description=Policy name"`
-	Description *string              `json:"description,omitempty" jsonschema:"description=Policy description"`
-	Enabled     *bool                `json:"enabled,omitempty" jsonschema:"description=Enable the policy"`
-	Rules       *[]NetbirdPolicyRule `json:"rules,omitempty" jsonschema:"description=Policy rules"`
+	PolicyID            string               `json:"policy_id" jsonschema:"required,description=The ID of the policy to update"`
+	Name                *string              `json:"name,omitempty" jsonschema:"description=Policy name"`
+	Description         *string              `json:"description,omitempty" jsonschema:"description=Policy description"`
+	Enabled             *bool                `json:"enabled,omitempty" jsonschema:"description=Enable the policy"`
+	Rules               *[]NetbirdPolicyRule `json:"rules,omitempty" jsonschema:"description=Policy rules"`
+	SourcePostureChecks *[]string            `json:"source_posture_checks,omitempty" jsonschema:"description=IDs of the posture checks source peers must pass. Replaces the current list; use attach_posture_check_to_policies or detach_posture_check_from_policies to change one check"`
 }
 
 func updateNetbirdPolicy(ctx context.Context, args UpdateNetbirdPolicyParams) (*NetbirdPolicy, error) {
@@ -421,9 +558,15 @@ func updateNetbirdPolicy(ctx context.Context, args UpdateNetbirdPolicyParams) (*
 		client = mcpnetbird.NewNetbirdClient(ctx)
 	}
 	
-	// If rules are provided, validate and format them
+	if args.SourcePostureChecks != nil {
+		if err := validatePostureCheckIDs(ctx, client, *args.SourcePostureChecks); err != nil {
+			return nil, fmt.Errorf("validation error: %w", err)
+		}
+	}
+
+	// If rules are provided, validate them before any API call
 	if args.Rules != nil && len(*args.Rules) > 0 {
-		// Convert rules to map[string]interface{} for validation and formatting
+		// Convert rules to map[string]interface{} for validation
 		rulesMap := make([]map[string]interface{}, len(*args.Rules))
 		for i, rule := range *args.Rules {
 			ruleMap, err := structToMap(rule)
@@ -432,46 +575,41 @@ func updateNetbirdPolicy(ctx context.Context, args UpdateNetbirdPolicyParams) (*
 			}
 			rulesMap[i] = ruleMap
 		}
-		
-		// Validate rules before API call
 		if err := ValidatePolicyRules(rulesMap); err != nil {
 			return nil, fmt.Errorf("validation error: %w", err)
 		}
-		
-		// Format rules for API (convert sources/destinations to string arrays)
-		formattedRules := make([]map[string]interface{}, len(rulesMap))
-		for i, rule := range rulesMap {
-			formatted, err := FormatRuleForAPI(rule)
-			if err != nil {
-				return nil, fmt.Errorf("formatting rule %d: %w", i, err)
-			}
-			formattedRules[i] = formatted
-		}
-		
-		// Create request body with formatted rules
-		requestBody := map[string]interface{}{
-			"rules": formattedRules,
-		}
-		if args.Name != nil {
-			requestBody["name"] = *args.Name
-		}
-		if args.Description != nil {
-			requestBody["description"] = *args.Description
-		}
-		if args.Enabled != nil {
-			requestBody["enabled"] = *args.Enabled
-		}
-		
-		var policy NetbirdPolicy
-		if err := client.Put(ctx, "/policies/"+args.PolicyID, requestBody, &policy); err != nil {
-			return nil, err
-		}
-		return &policy, nil
 	}
-	
-	// No rules provided, use original args
+
+	// The API replaces the whole policy, so merge the given fields into the
+	// current one to keep the fields that were left out
+	var current NetbirdPolicy
+	if err := client.Get(ctx, "/policies/"+args.PolicyID, &current); err != nil {
+		return nil, fmt.Errorf("fetching policy %s: %w", args.PolicyID, err)
+	}
+	if args.Name != nil {
+		current.Name = *args.Name
+	}
+	if args.Description != nil {
+		current.Description = *args.Description
+	}
+	if args.Enabled != nil {
+		current.Enabled = *args.Enabled
+	}
+	if args.Rules != nil {
+		current.Rules = *args.Rules
+	}
+	if args.SourcePostureChecks != nil {
+		current.SourcePostureChecks = *args.SourcePostureChecks
+	}
+
+	// Format the rules for the API (sources and destinations as group IDs)
+	requestBody, errs := policyUpdateBody(current)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("policy %s: %w", args.PolicyID, errs[0])
+	}
+
 	var policy NetbirdPolicy
-	if err := client.Put(ctx, "/policies/"+args.PolicyID, args, &policy); err != nil {
+	if err := client.Put(ctx, "/policies/"+args.PolicyID, requestBody, &policy); err != nil {
 		return nil, err
 	}
 	return &policy, nil
@@ -479,12 +617,14 @@ func updateNetbirdPolicy(ctx context.Context, args UpdateNetbirdPolicyParams) (*
 
 var UpdateNetbirdPolicy = mcpnetbird.MustTool(
 	"update_netbird_policy",
-	"Update an existing Netbird policy",
+	"Update an existing Netbird policy. Omitted fields keep their current values; rules, when given, replace all of the policy's rules",
+	mcpnetbird.UpdateTool,
 	updateNetbirdPolicy,
 )
 
 type DeleteNetbirdPolicyParams struct {
 	PolicyID string `json:"policy_id" jsonschema:"required,description=The ID of the policy to delete"`
+	Confirmation
 }
 
 func deleteNetbirdPolicy(ctx context.Context, args DeleteNetbirdPolicyParams) (map[string]string, error) {
@@ -502,8 +642,14 @@ func deleteNetbirdPolicy(ctx context.Context, args DeleteNetbirdPolicyParams) (m
 
 var DeleteNetbirdPolicy = mcpnetbird.MustTool(
 	"delete_netbird_policy",
-	"Delete a Netbird policy",
-	deleteNetbirdPolicy,
+	"Delete a Netbird policy. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdPolicyParams) string {
+			return "/policies/" + args.PolicyID
+		}),
+		deleteNetbirdPolicy,
+	),
 )
 
 // GetPolicyTemplate returns example policy structures with simple and complex rules.
@@ -572,6 +718,7 @@ func getPolicyTemplateTool(ctx context.Context, args GetPolicyTemplateParams) (m
 var GetPolicyTemplateTool = mcpnetbird.MustTool(
 	"get_policy_template",
 	"Get an example policy structure with simple and complex rules. Includes examples of: simple rules with ports, complex rules with port_ranges and authorized_groups, and rules with resource references. Use this to understand the correct format for creating policies.",
+	mcpnetbird.ReadOnlyTool,
 	getPolicyTemplateTool,
 )
 
@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/invopop/jsonschema"
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 func TestListNetbirdPolicies(t *testing.T) {
@@ -1055,7 +1059,7 @@ func TestValidatePolicyRules_PropertyPortRangeInvariant(t *testing.T) {
 	
 	// Also test valid port ranges (start <= end)
 	for i := 0; i < 50; i++ {
-		start := i * 10
+		start := i*10 + 1
 		end := start + (i % 10)
 		
 		rule := map[string]interface{}{
@@ -1343,6 +1347,74 @@ func TestValidatePolicyRules_ValidPortRange(t *testing.T) {
 	}
 }
 
+func TestValidatePolicyRules_PortOutOfRange(t *testing.T) {
+	for _, portRange := range []map[string]interface{}{
+		{"start": 0, "end": 80},
+		{"start": 443, "end": 65536},
+	} {
+		rules := []map[string]interface{}{
+			{
+				"name":          "test-rule",
+				"enabled":       true,
+				"action":        "accept",
+				"bidirectional": false,
+				"protocol":      "tcp",
+				"sources":       []string{"group-1"},
+				"destinations":  []string{"group-2"},
+				"port_ranges":   []interface{}{portRange},
+			},
+		}
+		err := ValidatePolicyRules(rules)
+		if err == nil || !contains(err.Error(), "between 1 and 65535") {
+			t.Errorf("expected out of range error for %v, got %v", portRange, err)
+		}
+	}
+}
+
+func TestPolicyRuleSchema_MatchesValidation(t *testing.T) {
+	rules, ok := CreateNetbirdPolicy.Tool.InputSchema.Properties["rules"].(*jsonschema.Schema)
+	if !ok || rules.Items == nil {
+		t.Fatalf("expected rules array schema, got %#v", CreateNetbirdPolicy.Tool.InputSchema.Properties["rules"])
+	}
+	enumOf := func(name string) []string {
+		property, _ := rules.Items.Properties.Get(name)
+		var values []string
+		for _, v := range property.Enum {
+			values = append(values, v.(string))
+		}
+		return values
+	}
+	if got := enumOf("action"); !slices.Equal(got, policyRuleActions) {
+		t.Errorf("expected action enum %v, got %v", policyRuleActions, got)
+	}
+	if got := enumOf("protocol"); !slices.Equal(got, policyRuleProtocols) {
+		t.Errorf("expected protocol enum %v, got %v", policyRuleProtocols, got)
+	}
+	portRanges, _ := rules.Items.Properties.Get("port_ranges")
+	start, _ := portRanges.Items.Properties.Get("start")
+	if start.Minimum != "1" || start.Maximum != "65535" {
+		t.Errorf("expected port bounds on port ranges, got %s-%s", start.Minimum, start.Maximum)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		"name": "Test Policy",
+		"rules": []any{map[string]any{
+			"name": "r1", "enabled": true, "action": "accept", "bidirectional": true, "protocol": "sctp",
+			"sources": []any{map[string]any{"id": "g1"}}, "destinations": []any{map[string]any{"id": "g2"}},
+			"port_ranges": []any{map[string]any{"start": 80, "end": 70000}},
+		}},
+	}
+	result, err := CreateNetbirdPolicy.Handler(context.Background(), request)
+	argErr, ok := result.StructuredContent.(*mcpnetbird.ArgumentError)
+	if err != nil || !result.IsError || !ok || len(argErr.Problems) != 2 {
+		t.Fatalf("expected protocol and port problems, got %+v, %v", result, err)
+	}
+	if argErr.Problems[0].Path != "rules[0].port_ranges[0].end" || argErr.Problems[1].Path != "rules[0].protocol" {
+		t.Errorf("unexpected problems %+v", argErr.Problems)
+	}
+}
+
 func TestValidatePolicyRules_MissingSource(t *testing.T) {
 	rules := []map[string]interface{}{
 		{
@@ -1729,6 +1801,11 @@ func TestUpdateNetbirdPolicy_WithRules(t *testing.T) {
 
 	// Create mock HTTP server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/policies/policy-789" && r.Method == "GET" {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(NetbirdPolicy{ID: "policy-789", Name: "Old Policy", Enabled: true})
+			return
+		}
 		if r.URL.Path != "/policies/policy-789" || r.Method != "PUT" {
 			http.NotFound(w, r)
 			return
@@ -1841,22 +1918,65 @@ func TestUpdateNetbirdPolicy_ValidationError(t *testing.T) {
 	}
 }
 
-func TestUpdateNetbirdPolicy_WithoutRules(t *testing.T) {
-	// Mock response data
-	mockResp := NetbirdPolicy{
-		ID:      "policy-999",
-		Name:    "Updated Name Only",
-		Enabled: true,
+func TestUpdateNetbirdPolicy_RulesOnlyKeepsOtherFields(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	rules := []NetbirdPolicyRule{{
+		Name: "ssh", Action: "accept", Protocol: "tcp", Enabled: true,
+		Sources:      []NetbirdPeerGroup{{ID: "g1"}},
+		Destinations: []NetbirdPeerGroup{{ID: "g3"}},
+	}}
+	if _, err := updateNetbirdPolicy(ctx, UpdateNetbirdPolicyParams{PolicyID: "pol3", Rules: &rules}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// Create mock HTTP server
+	body := store.lastWrite(t, "/policies/pol3")
+	if body["name"] != "db access" || body["enabled"] != true {
+		t.Errorf("expected the name and enabled state to be kept, got %v", body)
+	}
+	if checks := bodyIDs(body, "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected the posture checks to be kept, got %v", checks)
+	}
+	sent, _ := body["rules"].([]any)
+	if len(sent) != 1 || !slices.Equal(bodyIDs(sent[0].(map[string]any), "sources"), []string{"g1"}) {
+		t.Errorf("expected the rules to be replaced, got %v", body["rules"])
+	}
+}
+
+func TestUpdateNetbirdPolicy_WithoutRules(t *testing.T) {
+	// Current policy, whose rules must be kept
+	current := NetbirdPolicy{
+		ID:          "policy-999",
+		Name:        "Old Name",
+		Description: "ssh access",
+		Enabled:     true,
+		Rules: []NetbirdPolicyRule{{
+			Name: "ssh", Action: "accept", Protocol: "tcp", Enabled: true,
+			Sources:      []NetbirdPeerGroup{{ID: "g1", Name: "devs"}},
+			Destinations: []NetbirdPeerGroup{{ID: "g2", Name: "servers"}},
+		}},
+		SourcePostureChecks: []string{"pc1"},
+	}
+
+	var putBody map[string]any
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/policies/policy-999" || r.Method != "PUT" {
+		if r.URL.Path != "/policies/policy-999" {
 			http.NotFound(w, r)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(mockResp)
+		switch r.Method {
+		case "GET":
+			_ = json.NewEncoder(w).Encode(current)
+		case "PUT":
+			_ = json.NewDecoder(r.Body).Decode(&putBody)
+			updated := current
+			updated.Name = putBody["name"].(string)
+			_ = json.NewEncoder(w).Encode(updated)
+		default:
+			http.NotFound(w, r)
+		}
 	}))
 	defer server.Close()
 
@@ -1865,20 +1985,36 @@ func TestUpdateNetbirdPolicy_WithoutRules(t *testing.T) {
 	defer func() { mcpnetbird.TestNetbirdClient = nil }()
 
 	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
-	
+
 	name := "Updated Name Only"
-	
+
 	policy, err := updateNetbirdPolicy(ctx, UpdateNetbirdPolicyParams{
 		PolicyID: "policy-999",
 		Name:     &name,
 	})
-	
+
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if policy.Name != "Updated Name Only" {
 		t.Errorf("unexpected policy name: %s", policy.Name)
 	}
+
+	// The PUT replaces the policy, so every current field is sent along
+	if putBody["name"] != "Updated Name Only" || putBody["description"] != "ssh access" || putBody["enabled"] != true {
+		t.Errorf("expected the name to change and the other fields to be kept, got %v", putBody)
+	}
+	rules, _ := putBody["rules"].([]any)
+	if len(rules) != 1 {
+		t.Fatalf("expected the current rules to be kept, got %v", putBody["rules"])
+	}
+	rule := rules[0].(map[string]any)
+	if rule["name"] != "ssh" || !slices.Equal(bodyIDs(rule, "sources"), []string{"g1"}) || !slices.Equal(bodyIDs(rule, "destinations"), []string{"g2"}) {
+		t.Errorf("expected the rule with group IDs, got %v", rule)
+	}
+	if checks := bodyIDs(putBody, "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected the posture checks to be kept, got %v", checks)
+	}
 }
 
 // Unit test for GetPolicyTemplate
@@ -1976,3 +2112,51 @@ func TestGetPolicyTemplate(t *testing.T) {
 		t.Error("template missing resource reference example")
 	}
 }
+
+func TestPostureCheckIDsUnmarshaling(t *testing.T) {
+	var policy NetbirdPolicy
+	if err := json.Unmarshal([]byte(`{"id": "pol1", "source_posture_checks": ["pc1", {"id": "pc2", "name": "geo"}]}`), &policy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(policy.SourcePostureChecks, PostureCheckIDs{"pc1", "pc2"}) {
+		t.Errorf("expected IDs from strings and objects, got %v", policy.SourcePostureChecks)
+	}
+
+	policy = NetbirdPolicy{}
+	if err := json.Unmarshal([]byte(`{"id": "pol1", "source_posture_checks": null}`), &policy); err != nil || policy.SourcePostureChecks != nil {
+		t.Errorf("expected no posture checks, got %v, %v", policy.SourcePostureChecks, err)
+	}
+}
+
+func TestCreateAndUpdateNetbirdPolicy_SourcePostureChecks(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	rules := []NetbirdPolicyRule{{
+		Name: "ssh", Action: "accept", Protocol: "tcp", Enabled: true,
+		Sources:      []NetbirdPeerGroup{{ID: "g1"}},
+		Destinations: []NetbirdPeerGroup{{ID: "g3"}},
+	}}
+	_, err := createNetbirdPolicy(ctx, CreateNetbirdPolicyParams{Name: "checked", Rules: &rules, SourcePostureChecks: &[]string{"pc1", "pc8", "pc9"}})
+	if err == nil || !strings.Contains(err.Error(), `posture check "pc8" not found; posture check "pc9" not found`) {
+		t.Fatalf("expected every unknown posture check to be reported, got %v", err)
+	}
+	if len(store.writes) != 0 {
+		t.Fatal("expected nothing to be written")
+	}
+
+	// The fake store echoes the request, whose rules don't decode as a policy
+	if _, err := createNetbirdPolicy(ctx, CreateNetbirdPolicyParams{Name: "checked", SourcePostureChecks: &[]string{"pc1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checks := bodyIDs(store.lastWrite(t, "/policies"), "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected the posture check to be sent, got %v", checks)
+	}
+
+	if _, err := updateNetbirdPolicy(ctx, UpdateNetbirdPolicyParams{PolicyID: "pol1", SourcePostureChecks: &[]string{"pc1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checks := bodyIDs(store.lastWrite(t, "/policies/pol1"), "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected the posture check to be sent, got %v", checks)
+	}
+}
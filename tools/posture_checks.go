@@ -2,8 +2,12 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/invopop/jsonschema"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -25,18 +29,23 @@ type OSVersionCheck struct {
 }
 
 type Location struct {
-	CountryCode string `json:"country_code"`
+	CountryCode string `json:"country_code" jsonschema:"pattern=^[A-Z]{2}$,description=ISO 3166-1 alpha-2 country code"`
 	CityName    string `json:"city_name"`
 }
 
 type GeoLocationCheck struct {
 	Locations []Location `json:"locations"`
-	Action    string     `json:"action"`
+	Action    string     `json:"action" jsonschema:"enum=allow,enum=deny"`
 }
 
 type NetworkRangeCheck struct {
-	Ranges []string `json:"ranges"`
-	Action string   `json:"action"`
+	Ranges []string `json:"ranges" jsonschema:"description=Network ranges in CIDR format"`
+	Action string   `json:"action" jsonschema:"enum=allow,enum=deny"`
+}
+
+// JSONSchemaExtend requires every range to be in CIDR notation
+func (NetworkRangeCheck) JSONSchemaExtend(schema *jsonschema.Schema) {
+	requireCIDR(schema, "ranges")
 }
 
 type ProcessPath struct {
@@ -64,7 +73,9 @@ type NetbirdPostureCheck struct {
 	Checks      CheckConfig `json:"checks"`
 }
 
-type ListNetbirdPostureChecksParams struct{}
+type ListNetbirdPostureChecksParams struct {
+	ListOptions
+}
 
 func listNetbirdPostureChecks(ctx context.Context, args ListNetbirdPostureChecksParams) ([]NetbirdPostureCheck, error) {
 	client := mcpnetbird.NewNetbirdClient(ctx)
@@ -80,7 +91,8 @@ func listNetbirdPostureChecks(ctx context.Context, args ListNetbirdPostureChecks
 var ListNetbirdPostureChecks = mcpnetbird.MustTool(
 	"list_netbird_posture_checks",
 	"List all Netbird posture checks",
-	listNetbirdPostureChecks,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdPostureChecks),
 )
 
 type GetNetbirdPostureCheckParams struct {
@@ -99,6 +111,7 @@ func getNetbirdPostureCheck(ctx context.Context, args GetNetbirdPostureCheckPara
 var GetNetbirdPostureCheck = mcpnetbird.MustTool(
 	"get_netbird_posture_check",
 	"Get a specific Netbird posture check by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdPostureCheck,
 )
 
@@ -120,6 +133,7 @@ func createNetbirdPostureCheck(ctx context.Context, args CreateNetbirdPostureChe
 var CreateNetbirdPostureCheck = mcpnetbird.MustTool(
 	"create_netbird_posture_check",
 	"Create a new Netbird posture check",
+	mcpnetbird.AdditiveTool,
 	createNetbirdPostureCheck,
 )
 
@@ -142,25 +156,330 @@ func updateNetbirdPostureCheck(ctx context.Context, args UpdateNetbirdPostureChe
 var UpdateNetbirdPostureCheck = mcpnetbird.MustTool(
 	"update_netbird_posture_check",
 	"Update an existing Netbird posture check",
+	mcpnetbird.UpdateTool,
 	updateNetbirdPostureCheck,
 )
 
 type DeleteNetbirdPostureCheckParams struct {
 	PostureCheckID string `json:"posture_check_id" jsonschema:"required,description=The ID of the posture check to delete"`
+	Force          bool   `json:"force,omitempty" jsonschema:"description=Force delete by removing the posture check from the policies using it first"`
+	Confirmation
 }
 
-func deleteNetbirdPostureCheck(ctx context.Context, args DeleteNetbirdPostureCheckParams) (map[string]string, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
-	if err := client.Delete(ctx, "/posture-checks/"+args.PostureCheckID); err != nil {
+// describePostureCheckDeletion reports the posture check and the policies using it
+func describePostureCheckDeletion(ctx context.Context, args DeleteNetbirdPostureCheckParams) (*Impact, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	var check NetbirdPostureCheck
+	if err := client.Get(ctx, "/posture-checks/"+args.PostureCheckID, &check); err != nil {
 		return nil, err
 	}
-	return map[string]string{"status": "deleted", "posture_check_id": args.PostureCheckID}, nil
+	deps, err := FindDependencies(ctx, client, "posture_check", args.PostureCheckID)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependencies: %w", err)
+	}
+
+	impact := &Impact{Action: "delete /posture-checks/" + args.PostureCheckID, Target: check, Affected: deps.References}
+	if policies, _ := splitPolicyDependencies(deps.References); len(policies) > 0 {
+		if args.Force {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("the posture check will be removed from %d policies", len(policies)))
+		} else {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("the posture check is used by %d policies and will not be deleted unless force=true", len(policies)))
+		}
+	}
+	return impact, nil
+}
+
+func deleteNetbirdPostureCheck(ctx context.Context, args DeleteNetbirdPostureCheckParams) (map[string]interface{}, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	deps, err := FindDependencies(ctx, client, "posture_check", args.PostureCheckID)
+	if err != nil {
+		return nil, fmt.Errorf("checking dependencies: %w", err)
+	}
+	policyIDs, _ := splitPolicyDependencies(deps.References)
+
+	if len(policyIDs) == 0 {
+		if err := client.Delete(ctx, "/posture-checks/"+args.PostureCheckID); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"status": "deleted", "posture_check_id": args.PostureCheckID, "force": false}, nil
+	}
+	if !args.Force {
+		return nil, fmt.Errorf("cannot delete posture check '%s': used by %d policies %v. Use force=true to remove it from them first",
+			args.PostureCheckID, len(policyIDs), policyIDs)
+	}
+
+	// Keep the posture check so the operation can recreate it on rollback
+	var check NetbirdPostureCheck
+	if err := client.Get(ctx, "/posture-checks/"+args.PostureCheckID, &check); err != nil {
+		return nil, fmt.Errorf("fetching posture check %s: %w", args.PostureCheckID, err)
+	}
+	preImage := objectPreImage{
+		Path: "/posture-checks/" + args.PostureCheckID,
+		Body: map[string]any{"name": check.Name, "description": check.Description, "checks": check.Checks},
+	}
+
+	op := startOperation(ctx, "delete_posture_check_force "+args.PostureCheckID)
+	detach := func(policy *NetbirdPolicy) {
+		policy.SourcePostureChecks = slices.DeleteFunc(slices.Clone(policy.SourcePostureChecks), func(id string) bool {
+			return id == args.PostureCheckID
+		})
+	}
+	result, err := forceDelete(ctx, client, op, "posture check "+args.PostureCheckID, policyIDs, detach, func() error {
+		entry := op.record(journalKindPostureCheck, journalActionDelete, args.PostureCheckID, preImage)
+		if err := client.Delete(ctx, "/posture-checks/"+args.PostureCheckID); err != nil {
+			return err
+		}
+		op.applied(entry)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"status":            "deleted",
+		"posture_check_id":  args.PostureCheckID,
+		"force":             true,
+		"operation_id":      result.OperationID,
+		"policies_modified": result.PoliciesModified,
+		"errors":            result.Errors,
+	}, nil
 }
 
 var DeleteNetbirdPostureCheck = mcpnetbird.MustTool(
 	"delete_netbird_posture_check",
-	"Delete a Netbird posture check",
-	deleteNetbirdPostureCheck,
+	"Delete a Netbird posture check. If policies use it, returns an error listing them unless force=true, which removes the posture check from those policies first; if any policy cannot be updated the changes are rolled back and the posture check is kept. The first call returns an impact summary and a confirmation_token; call again with the token to delete.",
+	mcpnetbird.DeleteTool,
+	withConfirmation(describePostureCheckDeletion, deleteNetbirdPostureCheck),
+)
+
+// PolicyPostureChecksResult reports how attach_posture_check_to_policies or
+// detach_posture_check_from_policies changed the posture checks of policies
+type PolicyPostureChecksResult struct {
+	PostureCheckID    string            `json:"posture_check_id"`
+	PostureCheckName  string            `json:"posture_check_name"`
+	OperationID       string            `json:"operation_id,omitempty"`
+	ChangedPolicies   []string          `json:"changed_policies"`
+	UnchangedPolicies []string          `json:"unchanged_policies"`
+	Errors            map[string]string `json:"errors,omitempty"` // policy ID -> error
+}
+
+type PolicyPostureChecksParams struct {
+	PostureCheck string   `json:"posture_check" jsonschema:"required,description=Posture check ID or name"`
+	Policies     []string `json:"policies" jsonschema:"required,minItems=1,description=Policy IDs or names"`
+	Confirmation
+}
+
+// resolvePostureCheck finds a posture check by ID or name
+func resolvePostureCheck(ctx context.Context, client *mcpnetbird.NetbirdClient, ref string) (NetbirdPostureCheck, error) {
+	var checks []NetbirdPostureCheck
+	if err := client.Get(ctx, "/posture-checks", &checks); err != nil {
+		return NetbirdPostureCheck{}, fmt.Errorf("listing posture checks: %w", err)
+	}
+	return resolveByIDOrName("posture check", ref, checks,
+		func(c NetbirdPostureCheck) string { return c.ID },
+		func(c NetbirdPostureCheck) string { return c.Name },
+	)
+}
+
+// describePoliciesPostureCheck returns a describe function listing the policies
+// attach_posture_check_to_policies or detach_posture_check_from_policies would change
+func describePoliciesPostureCheck(add bool) func(context.Context, PolicyPostureChecksParams) (*Impact, error) {
+	return func(ctx context.Context, args PolicyPostureChecksParams) (*Impact, error) {
+		var client *mcpnetbird.NetbirdClient
+		if mcpnetbird.TestNetbirdClient != nil {
+			client = mcpnetbird.TestNetbirdClient
+		} else {
+			client = mcpnetbird.NewNetbirdClient(ctx)
+		}
+
+		check, err := resolvePostureCheck(ctx, client, args.PostureCheck)
+		if err != nil {
+			return nil, err
+		}
+		policies, err := resolvePolicies(ctx, client, args.Policies)
+		if err != nil {
+			return nil, err
+		}
+
+		affected := make([]PolicyReference, 0, len(policies))
+		var unchanged []string
+		for _, policy := range policies {
+			if slices.Contains(policy.SourcePostureChecks, check.ID) == add {
+				unchanged = append(unchanged, policy.Name)
+				continue
+			}
+			affected = append(affected, PolicyReference{PolicyID: policy.ID, PolicyName: policy.Name, Location: "source_posture_checks"})
+		}
+
+		impact := &Impact{Target: check, Affected: affected}
+		if add {
+			impact.Action = fmt.Sprintf("attach posture check %s to %d policies", check.ID, len(affected))
+		} else {
+			impact.Action = fmt.Sprintf("detach posture check %s from %d policies", check.ID, len(affected))
+			if len(affected) > 0 {
+				impact.Warnings = append(impact.Warnings, fmt.Sprintf("peers will no longer need to pass the posture check to use %d policies", len(affected)))
+			}
+		}
+		if len(unchanged) > 0 {
+			impact.Warnings = append(impact.Warnings, fmt.Sprintf("%d policies are left as they are: %s", len(unchanged), strings.Join(unchanged, ", ")))
+		}
+		return impact, nil
+	}
+}
+
+// resolvePolicies finds policies by ID or name, dropping duplicates. It reports
+// every reference that can't be resolved at once.
+func resolvePolicies(ctx context.Context, client *mcpnetbird.NetbirdClient, refs []string) ([]NetbirdPolicy, error) {
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return nil, fmt.Errorf("listing policies: %w", err)
+	}
+
+	var resolved []NetbirdPolicy
+	var problems []string
+	for _, ref := range refs {
+		policy, err := resolveByIDOrName("policy", ref, policies,
+			func(p NetbirdPolicy) string { return p.ID },
+			func(p NetbirdPolicy) string { return p.Name },
+		)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		if !slices.ContainsFunc(resolved, func(p NetbirdPolicy) bool { return p.ID == policy.ID }) {
+			resolved = append(resolved, policy)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+// changePolicyPostureCheck adds the posture check to, or removes it from, a
+// policy by read-modify-write, keeping its rules and other posture checks. The
+// update is recorded in op. It reports whether the policy was written.
+func changePolicyPostureCheck(ctx context.Context, client *mcpnetbird.NetbirdClient, op *Operation, policyID, checkID string, add bool) (bool, error) {
+	var policy NetbirdPolicy
+	if err := client.Uncached().Get(ctx, "/policies/"+policyID, &policy); err != nil {
+		return false, fmt.Errorf("fetching policy %s: %w", policyID, err)
+	}
+	preImage := remapPolicyGroups(policy, nil)
+
+	has := slices.Contains(policy.SourcePostureChecks, checkID)
+	switch {
+	case add && !has:
+		policy.SourcePostureChecks = append(slices.Clone(policy.SourcePostureChecks), checkID)
+	case !add && has:
+		policy.SourcePostureChecks = slices.DeleteFunc(slices.Clone(policy.SourcePostureChecks), func(id string) bool { return id == checkID })
+	default:
+		return false, nil
+	}
+
+	body, errs := policyUpdateBody(policy)
+	if len(errs) > 0 {
+		return false, fmt.Errorf("policy %s: %w", policyID, errs[0])
+	}
+	entry := op.record(journalKindPolicy, journalActionUpdate, policyID, preImage)
+	if err := client.Put(ctx, "/policies/"+policyID, body, nil); err != nil {
+		return false, fmt.Errorf("updating policy %s: %w", policyID, err)
+	}
+	op.applied(entry)
+	return true, nil
+}
+
+// changePoliciesPostureCheck attaches the posture check to, or detaches it from,
+// every policy in args. Policies are updated concurrently, at most
+// BulkConcurrency at a time, and the changes are journaled so
+// rollback_netbird_operation can undo them.
+func changePoliciesPostureCheck(ctx context.Context, args PolicyPostureChecksParams, add bool) (*PolicyPostureChecksResult, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	check, err := resolvePostureCheck(ctx, client, args.PostureCheck)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := resolvePolicies(ctx, client, args.Policies)
+	if err != nil {
+		return nil, err
+	}
+
+	opName := "detach_posture_check_from_policies " + check.ID
+	if add {
+		opName = "attach_posture_check_to_policies " + check.ID
+	}
+	op := startOperation(ctx, opName)
+
+	changed := make([]bool, len(policies))
+	errs := make([]error, len(policies))
+	skipped := runBounded(ctx, len(policies), BulkConcurrency, func(ctx context.Context, i int) {
+		changed[i], errs[i] = changePolicyPostureCheck(ctx, client, op, policies[i].ID, check.ID, add)
+	})
+	for _, i := range skipped {
+		errs[i] = fmt.Errorf("skipped: %w", ctx.Err())
+	}
+
+	result := &PolicyPostureChecksResult{
+		PostureCheckID:    check.ID,
+		PostureCheckName:  check.Name,
+		ChangedPolicies:   []string{},
+		UnchangedPolicies: []string{},
+		Errors:            make(map[string]string),
+	}
+	for i, policy := range policies {
+		switch {
+		case errs[i] != nil:
+			result.Errors[policy.ID] = errs[i].Error()
+		case changed[i]:
+			result.OperationID = op.ID
+			result.ChangedPolicies = append(result.ChangedPolicies, policy.ID)
+		default:
+			result.UnchangedPolicies = append(result.UnchangedPolicies, policy.ID)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return result, fmt.Errorf("changing posture checks of policies: %w", err)
+	}
+	return result, nil
+}
+
+func attachPostureCheckToPolicies(ctx context.Context, args PolicyPostureChecksParams) (*PolicyPostureChecksResult, error) {
+	return changePoliciesPostureCheck(ctx, args, true)
+}
+
+var AttachPostureCheckToPolicies = mcpnetbird.MustTool(
+	"attach_posture_check_to_policies",
+	"Add a posture check to the source posture checks of one or more policies, keeping their rules and other posture checks. The posture check and policies can be given by ID or name. Returns the policies that changed, those that already had the check and an operation_id that rollback_netbird_operation can use to undo the changes. The first call returns the policies that would change and a confirmation_token; call again with the token to apply.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describePoliciesPostureCheck(true), attachPostureCheckToPolicies),
+)
+
+func detachPostureCheckFromPolicies(ctx context.Context, args PolicyPostureChecksParams) (*PolicyPostureChecksResult, error) {
+	return changePoliciesPostureCheck(ctx, args, false)
+}
+
+var DetachPostureCheckFromPolicies = mcpnetbird.MustTool(
+	"detach_posture_check_from_policies",
+	"Remove a posture check from the source posture checks of one or more policies, keeping their rules and other posture checks. The posture check and policies can be given by ID or name. Returns the policies that changed, those that didn't have the check and an operation_id that rollback_netbird_operation can use to undo the changes. The first call returns the policies that would change and a confirmation_token; call again with the token to apply.",
+	mcpnetbird.BulkTool,
+	withConfirmation(describePoliciesPostureCheck(false), detachPostureCheckFromPolicies),
 )
 
 func AddNetbirdPostureCheckTools(mcp *server.MCPServer) {
@@ -169,4 +488,6 @@ func AddNetbirdPostureCheckTools(mcp *server.MCPServer) {
 	CreateNetbirdPostureCheck.Register(mcp)
 	UpdateNetbirdPostureCheck.Register(mcp)
 	DeleteNetbirdPostureCheck.Register(mcp)
+	AttachPostureCheckToPolicies.Register(mcp)
+	DetachPostureCheckFromPolicies.Register(mcp)
 }
@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestAttachAndDetachPostureCheck(t *testing.T) {
+	store := newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	// pol3 already has the check
+	result, err := attachPostureCheckToPolicies(ctx, PolicyPostureChecksParams{PostureCheck: "min version", Policies: []string{"dev access", "pol3"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(result.ChangedPolicies, []string{"pol1"}) || !slices.Equal(result.UnchangedPolicies, []string{"pol3"}) || result.OperationID == "" {
+		t.Errorf("expected pol1 to change and pol3 not to, got %+v", result)
+	}
+	policy := store.lastWrite(t, "/policies/pol1")
+	if checks := bodyIDs(policy, "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected pc1 to be attached to pol1, got %v", checks)
+	}
+	if rules, _ := policy["rules"].([]any); len(rules) != 1 {
+		t.Errorf("expected the rules of pol1 to be kept, got %v", policy["rules"])
+	}
+
+	result, err = detachPostureCheckFromPolicies(ctx, PolicyPostureChecksParams{PostureCheck: "pc1", Policies: []string{"pol3"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checks := bodyIDs(store.lastWrite(t, "/policies/pol3"), "source_posture_checks"); len(checks) != 0 {
+		t.Errorf("expected pc1 to be detached from pol3, got %v", checks)
+	}
+
+	// Rolling back puts the check back
+	if _, err := rollbackNetbirdOperation(ctx, RollbackNetbirdOperationParams{OperationID: result.OperationID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checks := bodyIDs(store.lastWrite(t, "/policies/pol3"), "source_posture_checks"); !slices.Equal(checks, []string{"pc1"}) {
+		t.Errorf("expected pc1 to be restored on pol3, got %v", checks)
+	}
+
+	impact, err := describePoliciesPostureCheck(false)(ctx, PolicyPostureChecksParams{PostureCheck: "pc1", Policies: []string{"pol1", "pol3"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if affected := impact.Affected.([]PolicyReference); len(affected) != 1 || affected[0].PolicyID != "pol3" {
+		t.Errorf("expected only pol3 to be affected, got %+v", impact.Affected)
+	}
+	if len(impact.Warnings) != 2 || !strings.Contains(impact.Warnings[1], "dev access") {
+		t.Errorf("expected the loosened policy and the unchanged one to be reported, got %v", impact.Warnings)
+	}
+
+	if _, err := attachPostureCheckToPolicies(ctx, PolicyPostureChecksParams{PostureCheck: "pc1", Policies: []string{"pol1", "nope"}}); err == nil || !strings.Contains(err.Error(), `policy "nope" not found`) {
+		t.Errorf("expected unknown policy error, got %v", err)
+	}
+	if _, err := attachPostureCheckToPolicies(ctx, PolicyPostureChecksParams{PostureCheck: "geo", Policies: []string{"pol1"}}); err == nil || !strings.Contains(err.Error(), `posture check "geo" not found`) {
+		t.Errorf("expected unknown posture check error, got %v", err)
+	}
+}
+
+func TestCreateNetbirdPostureCheck_NetworkRangesMustBeCIDR(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		"name": "office only",
+		"checks": map[string]any{
+			"peer_network_range_check": map[string]any{"ranges": []any{"10.0.0.0/8", "office-lan"}, "action": "allow"},
+		},
+	}
+	result, err := CreateNetbirdPostureCheck.Handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	argErr, ok := result.StructuredContent.(*mcpnetbird.ArgumentError)
+	if !result.IsError || !ok || len(argErr.Problems) != 1 || argErr.Problems[0].Path != "checks.peer_network_range_check.ranges[1]" {
+		t.Errorf("expected the second range to be rejected, got %+v", result)
+	}
+}
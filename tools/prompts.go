@@ -0,0 +1,178 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// promptArgument is an argument of a workflow prompt
+type promptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// workflowPrompt is a parameterized prompt that steers the assistant through the
+// tool sequence of a common Netbird task. Text is a text/template executed with
+// the prompt arguments; arguments that weren't given render as empty strings.
+type workflowPrompt struct {
+	Name        string
+	Description string
+	Arguments   []promptArgument
+	Text        string
+}
+
+var workflowPrompts = []workflowPrompt{
+	{
+		Name:        "onboard_netbird_team",
+		Description: "Onboard a new team: create its group, invite its members, issue a setup key for its devices and grant it access with a policy",
+		Arguments: []promptArgument{
+			{Name: "team", Description: "Team name, used for the group, setup key and policy names", Required: true},
+			{Name: "members", Description: "Comma-separated email addresses of the team members"},
+			{Name: "access", Description: "What the team needs to reach, e.g. a group, network or service"},
+		},
+		Text: `Onboard the team "{{.team}}" to Netbird.
+
+1. Call search_netbird with "{{.team}}" to check whether a group, setup key or policy for this team already exists. Reuse existing objects instead of creating duplicates.
+2. Create the group with create_netbird_group, named after the team.
+{{- if .members}}
+3. For each of these members: {{.members}}
+   - call list_netbird_users with filter on the email to see whether the user exists;
+   - existing users: add the group to their auto_groups with update_netbird_user, keeping their other groups;
+   - new users: invite them with invite_netbird_user, role "user" and the group in auto_groups.
+{{- else}}
+3. Ask me for the email addresses of the team members, then add existing users to the group with update_netbird_user (keeping their other auto_groups) and invite new ones with invite_netbird_user.
+{{- end}}
+4. Create a reusable setup key with create_netbird_setup_key named "{{.team}} devices", the group in auto_groups and an expiry of at most 30 days, so the team's devices join the group automatically.
+5. {{if .access}}Grant the group access to: {{.access}}.{{else}}Ask me what the team needs to reach.{{end}} Use get_policy_template for the rule format, look up the destination with search_netbird or list_netbird_groups, and create a policy with create_netbird_policy that allows only the protocols and ports the team needs. Prefer a specific destination group over "All".
+6. Summarize what was created, with IDs, and the setup key to share with the team.`,
+	},
+	{
+		Name:        "audit_netbird_group_access",
+		Description: "Audit what the members of a group can reach and who can reach them, and flag overly broad rules",
+		Arguments: []promptArgument{
+			{Name: "group", Description: "Group name or ID", Required: true},
+		},
+		Text: `Audit access for the Netbird group "{{.group}}".
+
+1. Find the group with list_netbird_groups (filter by name) or get_netbird_group, and note its ID, peers and resources.
+2. Call list_policies_by_group with the group ID to find every policy that references it.
+3. For each policy, call get_netbird_policy and record, per rule: whether the group is a source, a destination or both, the other side's groups or resource, the action, protocol, ports, whether the rule is bidirectional, and whether the policy and rule are enabled. Resolve group IDs to names with get_netbird_group.
+4. Check other ways the group grants access: list_netbird_routes for routes distributed to or routed by the group, list_netbird_networks and list_netbird_network_routers for networks routed by its peers, and list_netbird_setup_keys for keys that auto-assign it.
+5. Report:
+   - what members of "{{.group}}" can reach, and what can reach them;
+   - risky rules: the "All" group, protocol "all", port ranges wider than needed, bidirectional rules that only need one direction, and accept rules without posture checks;
+   - disabled policies or rules that still reference the group.
+Do not change anything; recommend changes and the tools to make them.`,
+	},
+	{
+		Name:        "consolidate_netbird_groups",
+		Description: "Find duplicate or overlapping groups and merge them into one, moving their members and references",
+		Arguments: []promptArgument{
+			{Name: "name_filter", Description: "Only consider groups whose name contains this text"},
+		},
+		Text: `Consolidate duplicate Netbird groups{{if .name_filter}} whose name contains "{{.name_filter}}"{{end}}.
+
+1. Call list_netbird_groups{{if .name_filter}} with filter name~{{.name_filter}}{{end}} and find duplicates: groups with the same or near-identical names, or with the same peers and resources. Skip "All" and groups managed by an identity provider (issued "jwt" or "integration").
+2. For each set of duplicates, call list_policies_by_group for every group and pick the group to keep, usually the one referenced by the most policies.
+3. Present the plan, the group to keep and the groups to remove, with their peers and policies, and wait for my approval before changing anything.
+4. After approval, call merge_netbird_groups for each set (target = the kept group, sources = the groups to remove). It adds their peers and resources to the kept group, moves their references in policies, routes, nameservers, networks, setup keys, users and DNS settings, and deletes them.
+   It first returns an impact summary and a confirmation_token: show me the summary, then call again with the token.
+5. Report the operation IDs returned by merge_netbird_groups; rollback_netbird_operation undoes them if something went wrong.`,
+	},
+	{
+		Name:        "investigate_netbird_peer",
+		Description: "Investigate why a peer can't connect, or can't reach a destination",
+		Arguments: []promptArgument{
+			{Name: "peer", Description: "Peer name, hostname, Netbird IP or ID", Required: true},
+			{Name: "destination", Description: "Peer, IP or resource the peer can't reach, if the problem is a specific connection"},
+		},
+		Text: `Investigate why the Netbird peer "{{.peer}}" {{if .destination}}can't reach "{{.destination}}"{{else}}can't connect{{end}}.
+
+1. Find the peer with search_netbird and load it with get_netbird_peer.
+2. Check its state: connected and last_seen, login_expired, approval_required, the Netbird client version and OS. An expired login or pending approval alone explains a peer that can't connect.
+3. List its groups, then call list_policies_by_group for each to find the enabled policies that apply to it.
+{{- if .destination}}
+4. Find "{{.destination}}" with search_netbird. Check whether an enabled policy rule allows traffic from one of the peer's groups to the destination's groups or resource, on the protocol and port in question, and in the right direction.
+{{- else}}
+4. Check that at least one enabled policy connects its groups to the peers it needs to reach.
+{{- end}}
+5. For each applicable policy with source_posture_checks, load the checks with get_netbird_posture_check and compare them with the peer's OS, client version and location. A failed posture check silently blocks the policy.
+6. If the destination is behind a route or network, check list_netbird_routes, or list_netbird_networks and list_netbird_network_routers for the network: the route must be enabled, distributed to one of the peer's groups, and have a connected routing peer.
+7. If API calls fail, run netbird_diagnostics.
+Report the most likely cause first, with the evidence, and the change that would fix it. Do not change anything without my approval.`,
+	},
+	{
+		Name:        "prepare_netbird_ingress_port",
+		Description: "Expose a port of a peer through an ingress port allocation, checking for conflicts first",
+		Arguments: []promptArgument{
+			{Name: "peer", Description: "Name, IP or ID of the peer running the service", Required: true},
+			{Name: "port", Description: "Port of the service on the peer", Required: true},
+			{Name: "protocol", Description: "tcp or udp (default tcp)"},
+		},
+		Text: `Expose port {{.port}}/{{if .protocol}}{{.protocol}}{{else}}tcp{{end}} of the Netbird peer "{{.peer}}" through an ingress port.
+
+1. Find the peer with search_netbird and confirm with get_netbird_peer that it is connected.
+2. Call list_netbird_port_allocations for the peer and check that no existing allocation already forwards port {{.port}}. If one does, show it to me instead of creating another.
+3. Create the allocation with create_netbird_port_allocation: peer_id, a descriptive name such as "{{.peer}}-{{.port}}", enabled true, and port_ranges [{"start": {{.port}}, "end": {{.port}}, "protocol": "{{if .protocol}}{{.protocol}}{{else}}tcp{{end}}"}].
+4. Load the result with get_netbird_port_allocation and report the ingress IP and the public port mapped to {{.port}}.
+5. Remind me that the service must listen on the peer's Netbird interface or all interfaces, and that anyone who can reach the ingress IP can reach it.`,
+	},
+}
+
+// render executes the prompt's template with args, after checking that the required arguments are present
+func (p workflowPrompt) render(args map[string]string) (string, error) {
+	var missing []string
+	for _, arg := range p.Arguments {
+		if arg.Required && strings.TrimSpace(args[arg.Name]) == "" {
+			missing = append(missing, arg.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("prompt %s: missing required arguments: %s", p.Name, strings.Join(missing, ", "))
+	}
+
+	tmpl, err := template.New(p.Name).Option("missingkey=zero").Parse(p.Text)
+	if err != nil {
+		return "", fmt.Errorf("prompt %s: %w", p.Name, err)
+	}
+	var text strings.Builder
+	if err := tmpl.Execute(&text, args); err != nil {
+		return "", fmt.Errorf("prompt %s: %w", p.Name, err)
+	}
+	return text.String(), nil
+}
+
+func (p workflowPrompt) handler(ctx context.Context, request mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	text, err := p.render(request.Params.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewGetPromptResult(p.Description, []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(text)),
+	}), nil
+}
+
+func (p workflowPrompt) prompt() mcp.Prompt {
+	opts := []mcp.PromptOption{mcp.WithPromptDescription(p.Description)}
+	for _, arg := range p.Arguments {
+		argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+		if arg.Required {
+			argOpts = append(argOpts, mcp.RequiredArgument())
+		}
+		opts = append(opts, mcp.WithArgument(arg.Name, argOpts...))
+	}
+	return mcp.NewPrompt(p.Name, opts...)
+}
+
+// AddNetbirdPrompts registers the workflow prompts
+func AddNetbirdPrompts(s *server.MCPServer) {
+	for _, p := range workflowPrompts {
+		s.AddPrompt(p.prompt(), p.handler)
+	}
+}
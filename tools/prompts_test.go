@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func getPrompt(t *testing.T, s *server.MCPServer, name string, args map[string]string) mcp.JSONRPCMessage {
+	t.Helper()
+	message, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "prompts/get",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	return s.HandleMessage(context.Background(), message)
+}
+
+func TestNetbirdPrompts(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	AddNetbirdPrompts(s)
+
+	response, ok := getPrompt(t, s, "consolidate_netbird_groups", map[string]string{"name_filter": "dev"}).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatal("expected a successful response")
+	}
+	result := response.Result.(mcp.GetPromptResult)
+	if len(result.Messages) != 1 || result.Messages[0].Role != mcp.RoleUser {
+		t.Fatalf("expected one user message, got %+v", result.Messages)
+	}
+	text := result.Messages[0].Content.(mcp.TextContent).Text
+	listPolicies := strings.Index(text, "list_policies_by_group")
+	merge := strings.Index(text, "merge_netbird_groups")
+	if listPolicies < 0 || merge < listPolicies {
+		t.Errorf("expected list_policies_by_group before merge_netbird_groups, got %q", text)
+	}
+	if !strings.Contains(text, `filter name~dev`) {
+		t.Errorf("expected name filter in prompt, got %q", text)
+	}
+
+	if _, ok := getPrompt(t, s, "investigate_netbird_peer", nil).(mcp.JSONRPCError); !ok {
+		t.Error("expected an error when a required argument is missing")
+	}
+}
+
+func TestWorkflowPrompts_ReferenceRegisteredTools(t *testing.T) {
+	names := make(map[string]bool)
+	for _, tool := range registeredTools(t) {
+		names[tool.Name] = true
+	}
+	toolName := regexp.MustCompile(`\b[a-z]+(?:_[a-z]+)*_netbird(?:_[a-z]+)*\b|\blist_policies_by_group\b|\breplace_group_in_policies\b|\bget_policy_template\b`)
+
+	for _, p := range workflowPrompts {
+		args := make(map[string]string)
+		for _, arg := range p.Arguments {
+			args[arg.Name] = "x"
+		}
+		for _, values := range []map[string]string{args, requiredOnly(p)} {
+			text, err := p.render(values)
+			if err != nil {
+				t.Fatalf("%s: %v", p.Name, err)
+			}
+			if strings.Contains(text, "<no value>") {
+				t.Errorf("%s: unrendered argument in %q", p.Name, text)
+			}
+			for _, name := range toolName.FindAllString(text, -1) {
+				if !names[name] {
+					t.Errorf("%s references unknown tool %s", p.Name, name)
+				}
+			}
+		}
+	}
+}
+
+func requiredOnly(p workflowPrompt) map[string]string {
+	args := make(map[string]string)
+	for _, arg := range p.Arguments {
+		if arg.Required {
+			args[arg.Name] = "x"
+		}
+	}
+	return args
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+const resourceMIMEType = "application/json"
+
+// jsonResource adapts a fetch function to a resource handler that returns its result as JSON
+func jsonResource[R any](fetch func(ctx context.Context) (R, error)) server.ResourceHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		result, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return resourceContents(request.Params.URI, result)
+	}
+}
+
+// jsonResourceTemplate adapts a fetch-by-ID function to the handler of a resource
+// template with an {id} variable
+func jsonResourceTemplate[R any](fetch func(ctx context.Context, id string) (R, error)) server.ResourceTemplateHandlerFunc {
+	return func(ctx context.Context, request mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		id := resourceID(request.Params.Arguments["id"])
+		if id == "" {
+			return nil, fmt.Errorf("resource URI %s has no ID", request.Params.URI)
+		}
+		result, err := fetch(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return resourceContents(request.Params.URI, result)
+	}
+}
+
+// resourceID returns a template variable, which mcp-go passes as a list of values
+func resourceID(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		if len(v) == 1 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func resourceContents(uri string, result any) ([]mcp.ResourceContents, error) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %s: %w", uri, err)
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{URI: uri, MIMEType: resourceMIMEType, Text: string(data)},
+	}, nil
+}
+
+// AddNetbirdResources exposes Netbird objects as MCP resources that clients can
+// attach as context: netbird://<type> lists all objects of a type and
+// netbird://<type>/{id} reads one
+func AddNetbirdResources(s *server.MCPServer) {
+	s.AddResource(
+		mcp.NewResource("netbird://account", "Netbird account",
+			mcp.WithResourceDescription("Account settings"),
+			mcp.WithMIMEType(resourceMIMEType),
+		),
+		jsonResource(func(ctx context.Context) (*NetbirdAccount, error) {
+			return getNetbirdAccount(ctx, GetNetbirdAccountParams{})
+		}),
+	)
+
+	s.AddResource(
+		mcp.NewResource("netbird://peers", "Netbird peers",
+			mcp.WithResourceDescription("All peers with their IPs, groups and connection status"),
+			mcp.WithMIMEType(resourceMIMEType),
+		),
+		jsonResource(func(ctx context.Context) ([]NetbirdPeer, error) {
+			return listNetbirdPeers(ctx, ListNetbirdPeersParams{})
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("netbird://peers/{id}", "Netbird peer",
+			mcp.WithTemplateDescription("A single peer by ID"),
+			mcp.WithTemplateMIMEType(resourceMIMEType),
+		),
+		jsonResourceTemplate(func(ctx context.Context, id string) (*NetbirdPeer, error) {
+			return getNetbirdPeer(ctx, GetNetbirdPeerParams{PeerID: id})
+		}),
+	)
+
+	s.AddResource(
+		mcp.NewResource("netbird://groups", "Netbird groups",
+			mcp.WithResourceDescription("All groups with their peers and resources"),
+			mcp.WithMIMEType(resourceMIMEType),
+		),
+		jsonResource(func(ctx context.Context) ([]NetbirdGroup, error) {
+			return listNetbirdGroups(ctx, ListNetbirdGroupsParams{})
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("netbird://groups/{id}", "Netbird group",
+			mcp.WithTemplateDescription("A single group by ID"),
+			mcp.WithTemplateMIMEType(resourceMIMEType),
+		),
+		jsonResourceTemplate(func(ctx context.Context, id string) (*NetbirdGroup, error) {
+			return getNetbirdGroup(ctx, GetNetbirdGroupParams{GroupID: id})
+		}),
+	)
+
+	s.AddResource(
+		mcp.NewResource("netbird://policies", "Netbird policies",
+			mcp.WithResourceDescription("All access control policies with their rules"),
+			mcp.WithMIMEType(resourceMIMEType),
+		),
+		jsonResource(func(ctx context.Context) ([]NetbirdPolicy, error) {
+			return listNetbirdPolicies(ctx, ListNetbirdPoliciesParams{})
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("netbird://policies/{id}", "Netbird policy",
+			mcp.WithTemplateDescription("A single policy by ID"),
+			mcp.WithTemplateMIMEType(resourceMIMEType),
+		),
+		jsonResourceTemplate(func(ctx context.Context, id string) (*NetbirdPolicy, error) {
+			return getNetbirdPolicy(ctx, GetNetbirdPolicyParams{PolicyID: id})
+		}),
+	)
+
+	s.AddResource(
+		mcp.NewResource("netbird://networks", "Netbird networks",
+			mcp.WithResourceDescription("All networks"),
+			mcp.WithMIMEType(resourceMIMEType),
+		),
+		jsonResource(func(ctx context.Context) ([]NetbirdNetwork, error) {
+			return listNetbirdNetworks(ctx, ListNetbirdNetworksParams{})
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("netbird://networks/{id}", "Netbird network",
+			mcp.WithTemplateDescription("A single network by ID"),
+			mcp.WithTemplateMIMEType(resourceMIMEType),
+		),
+		jsonResourceTemplate(func(ctx context.Context, id string) (*NetbirdNetwork, error) {
+			return getNetbirdNetwork(ctx, GetNetbirdNetworkParams{NetworkID: id})
+		}),
+	)
+
+	s.AddResource(
+		mcp.NewResource("netbird://routes", "Netbird routes",
+			mcp.WithResourceDescription("All network routes"),
+			mcp.WithMIMEType(resourceMIMEType),
+		),
+		jsonResource(func(ctx context.Context) ([]NetbirdRoute, error) {
+			return listNetbirdRoutes(ctx, ListNetbirdRoutesParams{})
+		}),
+	)
+	s.AddResourceTemplate(
+		mcp.NewResourceTemplate("netbird://routes/{id}", "Netbird route",
+			mcp.WithTemplateDescription("A single route by ID"),
+			mcp.WithTemplateMIMEType(resourceMIMEType),
+		),
+		jsonResourceTemplate(func(ctx context.Context, id string) (*NetbirdRoute, error) {
+			return getNetbirdRoute(ctx, GetNetbirdRouteParams{RouteID: id})
+		}),
+	)
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func readResource(t *testing.T, s *server.MCPServer, uri string) mcp.JSONRPCMessage {
+	t.Helper()
+	message, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "resources/read",
+		"params":  map[string]any{"uri": uri},
+	})
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	return s.HandleMessage(ctx, message)
+}
+
+func TestNetbirdResources(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/groups":
+			_, _ = w.Write([]byte(`[{"id":"g1","name":"devs","peers_count":2}]`))
+		case "/peers/p1":
+			_, _ = w.Write([]byte(`{"id":"p1","name":"laptop","ip":"100.64.0.1"}`))
+		case "/policies/pol1":
+			_, _ = w.Write([]byte(`{"id":"pol1","name":"devs to prod","enabled":true,"rules":[]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer apiServer.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(apiServer.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	s := server.NewMCPServer("test", "0.0.0")
+	AddNetbirdResources(s)
+
+	cases := map[string]string{
+		"netbird://groups":        `"name": "devs"`,
+		"netbird://peers/p1":      `"ip": "100.64.0.1"`,
+		"netbird://policies/pol1": `"name": "devs to prod"`,
+	}
+	for uri, want := range cases {
+		response, ok := readResource(t, s, uri).(mcp.JSONRPCResponse)
+		if !ok {
+			t.Errorf("%s: expected a successful response", uri)
+			continue
+		}
+		result := response.Result.(mcp.ReadResourceResult)
+		if len(result.Contents) != 1 {
+			t.Fatalf("%s: expected one content item, got %d", uri, len(result.Contents))
+		}
+		contents := result.Contents[0].(mcp.TextResourceContents)
+		if contents.URI != uri || contents.MIMEType != "application/json" || !strings.Contains(contents.Text, want) {
+			t.Errorf("%s: unexpected contents %+v", uri, contents)
+		}
+	}
+
+	if _, ok := readResource(t, s, "netbird://peers/missing").(mcp.JSONRPCError); !ok {
+		t.Error("expected an error for a missing peer")
+	}
+}
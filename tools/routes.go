@@ -4,6 +4,7 @@ import (
 	"context"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/invopop/jsonschema"
 	"github.com/mark3labs/mcp-go/server"
 )
 
@@ -25,7 +26,9 @@ type NetbirdRoute struct {
 	SkipAutoApply       bool     `json:"skip_auto_apply"`
 }
 
-type ListNetbirdRoutesParams struct{}
+type ListNetbirdRoutesParams struct {
+	ListOptions
+}
 
 func listNetbirdRoutes(ctx context.Context, args ListNetbirdRoutesParams) ([]NetbirdRoute, error) {
 	var client *mcpnetbird.NetbirdClient
@@ -46,19 +49,20 @@ func listNetbirdRoutes(ctx context.Context, args ListNetbirdRoutesParams) ([]Net
 var ListNetbirdRoutes = mcpnetbird.MustTool(
 	"list_netbird_routes",
 	"List all Netbird routes",
-	listNetbirdRoutes,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdRoutes),
 )
 
 type UpdateNetbirdRouteParams struct {
 	RouteID      string    `json:"route_id" jsonschema:"required,description=The ID of the route to update"`
 	Description  *string   `json:"description,omitempty" jsonschema:"description=Route description"`
-	NetworkID    *string   `json:"network_id,omitempty" jsonschema:"description=Route network identifier to group HA routes (1-40 characters)"`
+	NetworkID    *string   `json:"network_id,omitempty" jsonschema:"minLength=1,maxLength=40,description=Route network identifier to group HA routes (1-40 characters)"`
 	Enabled      *bool     `json:"enabled,omitempty" jsonschema:"description=Route status"`
 	Peer         *string   `json:"peer,omitempty" jsonschema:"description=Peer ID to route through (cannot be used with peer_groups)"`
 	PeerGroups   *[]string `json:"peer_groups,omitempty" jsonschema:"description=Peer group IDs to route through (cannot be used with peer)"`
 	Network      *string   `json:"network,omitempty" jsonschema:"description=Network range in CIDR format (conflicts with domains)"`
 	Domains      *[]string `json:"domains,omitempty" jsonschema:"description=Domain list to be dynamically resolved (conflicts with network)"`
-	Metric       *int      `json:"metric,omitempty" jsonschema:"description=Route metric number (1-9999, lower has higher priority)"`
+	Metric       *int      `json:"metric,omitempty" jsonschema:"minimum=1,maximum=9999,description=Route metric number (1-9999; lower has higher priority)"`
 	Masquerade   *bool     `json:"masquerade,omitempty" jsonschema:"description=Enable masquerading (NAT)"`
 	Groups       *[]string `json:"groups,omitempty" jsonschema:"description=Group IDs containing routing peers"`
 	KeepRoute    *bool     `json:"keep_route,omitempty" jsonschema:"description=Keep route after domain doesn't resolve"`
@@ -66,6 +70,11 @@ type UpdateNetbirdRouteParams struct {
 	SkipAutoApply *bool   `json:"skip_auto_apply,omitempty" jsonschema:"description=Skip auto-application for exit node route (0.0.0.0/0)"`
 }
 
+// JSONSchemaExtend requires network to be a CIDR range
+func (UpdateNetbirdRouteParams) JSONSchemaExtend(schema *jsonschema.Schema) {
+	requireCIDR(schema, "network")
+}
+
 func updateNetbirdRoute(ctx context.Context, args UpdateNetbirdRouteParams) (*NetbirdRoute, error) {
 	var client *mcpnetbird.NetbirdClient
 	if mcpnetbird.TestNetbirdClient != nil {
@@ -85,18 +94,19 @@ func updateNetbirdRoute(ctx context.Context, args UpdateNetbirdRouteParams) (*Ne
 var UpdateNetbirdRoute = mcpnetbird.MustTool(
 	"update_netbird_route",
 	"Update an existing Netbird route",
+	mcpnetbird.UpdateTool,
 	updateNetbirdRoute,
 )
 
 type CreateNetbirdRouteParams struct {
 	Description  *string   `json:"description,omitempty" jsonschema:"description=Route description"`
-	NetworkID    string    `json:"network_id" jsonschema:"required,description=Route network identifier to group HA routes (1-40 characters)"`
+	NetworkID    string    `json:"network_id" jsonschema:"required,minLength=1,maxLength=40,description=Route network identifier to group HA routes (1-40 characters)"`
 	Enabled      *bool     `json:"enabled,omitempty" jsonschema:"description=Route status"`
 	Peer         *string   `json:"peer,omitempty" jsonschema:"description=Peer ID to route through (cannot be used with peer_groups)"`
 	PeerGroups   *[]string `json:"peer_groups,omitempty" jsonschema:"description=Peer group IDs to route through (cannot be used with peer)"`
 	Network      *string   `json:"network,omitempty" jsonschema:"description=Network range in CIDR format (conflicts with domains)"`
 	Domains      *[]string `json:"domains,omitempty" jsonschema:"description=Domain list to be dynamically resolved (conflicts with network)"`
-	Metric       *int      `json:"metric,omitempty" jsonschema:"description=Route metric number (1-9999, lower has higher priority)"`
+	Metric       *int      `json:"metric,omitempty" jsonschema:"minimum=1,maximum=9999,description=Route metric number (1-9999; lower has higher priority)"`
 	Masquerade   *bool     `json:"masquerade,omitempty" jsonschema:"description=Enable masquerading (NAT)"`
 	Groups       []string  `json:"groups" jsonschema:"required,description=Group IDs containing routing peers"`
 	KeepRoute    *bool     `json:"keep_route,omitempty" jsonschema:"description=Keep route after domain doesn't resolve"`
@@ -104,6 +114,11 @@ type CreateNetbirdRouteParams struct {
 	SkipAutoApply *bool   `json:"skip_auto_apply,omitempty" jsonschema:"description=Skip auto-application for exit node route (0.0.0.0/0)"`
 }
 
+// JSONSchemaExtend requires network to be a CIDR range
+func (CreateNetbirdRouteParams) JSONSchemaExtend(schema *jsonschema.Schema) {
+	requireCIDR(schema, "network")
+}
+
 func createNetbirdRoute(ctx context.Context, args CreateNetbirdRouteParams) (*NetbirdRoute, error) {
 	var client *mcpnetbird.NetbirdClient
 	if mcpnetbird.TestNetbirdClient != nil {
@@ -123,11 +138,13 @@ func createNetbirdRoute(ctx context.Context, args CreateNetbirdRouteParams) (*Ne
 var CreateNetbirdRoute = mcpnetbird.MustTool(
 	"create_netbird_route",
 	"Create a new Netbird route",
+	mcpnetbird.AdditiveTool,
 	createNetbirdRoute,
 )
 
 type DeleteNetbirdRouteParams struct {
 	RouteID string `json:"route_id" jsonschema:"required,description=The ID of the route to delete"`
+	Confirmation
 }
 
 func deleteNetbirdRoute(ctx context.Context, args DeleteNetbirdRouteParams) (map[string]string, error) {
@@ -147,8 +164,14 @@ func deleteNetbirdRoute(ctx context.Context, args DeleteNetbirdRouteParams) (map
 
 var DeleteNetbirdRoute = mcpnetbird.MustTool(
 	"delete_netbird_route",
-	"Delete a Netbird route",
-	deleteNetbirdRoute,
+	"Delete a Netbird route. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(
+		describeDeletion(func(args DeleteNetbirdRouteParams) string {
+			return "/routes/" + args.RouteID
+		}),
+		deleteNetbirdRoute,
+	),
 )
 
 type GetNetbirdRouteParams struct {
@@ -172,6 +195,7 @@ func getNetbirdRoute(ctx context.Context, args GetNetbirdRouteParams) (*NetbirdR
 var GetNetbirdRoute = mcpnetbird.MustTool(
 	"get_netbird_route",
 	"Get a specific Netbird route by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdRoute,
 )
 
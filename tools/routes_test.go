@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestCreateNetbirdRoute_NetworkMustBeCIDR(t *testing.T) {
+	var created int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"r1"}`))
+	}))
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	for network, valid := range map[string]bool{
+		"10.0.0.0/16":   true,
+		"0.0.0.0/0":     true,
+		"2001:db8::/32": true,
+		"10.0.0.1":      false,
+		"office-lan":    false,
+		"10.0.0.0/16 ":  false,
+	} {
+		request := mcp.CallToolRequest{}
+		request.Params.Arguments = map[string]any{
+			"network_id": "office",
+			"network":    network,
+			"groups":     []any{"g1"},
+			"metric":     9999,
+		}
+		result, err := CreateNetbirdRoute.Handler(ctx, request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		argErr, rejected := result.StructuredContent.(*mcpnetbird.ArgumentError)
+		if valid && result.IsError {
+			t.Errorf("expected %q to be accepted, got %+v", network, result)
+		}
+		if !valid && (!rejected || argErr.Problems[0].Path != "network") {
+			t.Errorf("expected %q to be rejected, got %+v", network, result)
+		}
+	}
+	if created != 3 {
+		t.Errorf("expected 3 routes to reach the API, got %d", created)
+	}
+}
+
+func TestCreateNetbirdRoute_Bounds(t *testing.T) {
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{
+		"network_id": "an-identifier-that-is-longer-than-forty-characters",
+		"network":    "10.0.0.0/16",
+		"groups":     []any{"g1"},
+		"metric":     0,
+	}
+	result, err := CreateNetbirdRoute.Handler(context.Background(), request)
+	argErr, ok := result.StructuredContent.(*mcpnetbird.ArgumentError)
+	if err != nil || !result.IsError || !ok || len(argErr.Problems) != 2 {
+		t.Fatalf("expected network_id and metric to be rejected, got %+v, %v", result, err)
+	}
+	if argErr.Problems[0].Path != "metric" || argErr.Problems[1].Path != "network_id" {
+		t.Errorf("unexpected problems %+v", argErr.Problems)
+	}
+}
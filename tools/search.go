@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Resource types returned in SearchHit.Type
+const (
+	searchTypePeer            = "peer"
+	searchTypeGroup           = "group"
+	searchTypePolicy          = "policy"
+	searchTypePolicyRule      = "policy_rule"
+	searchTypeRoute           = "route"
+	searchTypeNetwork         = "network"
+	searchTypeNetworkResource = "network_resource"
+	searchTypeNameserver      = "nameserver"
+	searchTypeUser            = "user"
+	searchTypeSetupKey        = "setup_key"
+)
+
+var searchTypes = []string{
+	searchTypePeer,
+	searchTypeGroup,
+	searchTypePolicy,
+	searchTypePolicyRule,
+	searchTypeRoute,
+	searchTypeNetwork,
+	searchTypeNetworkResource,
+	searchTypeNameserver,
+	searchTypeUser,
+	searchTypeSetupKey,
+}
+
+// SearchHit is a single match returned by search_netbird
+type SearchHit struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parent_id,omitempty"` // policy ID for rules, network ID for network resources
+	Field    string `json:"field"`
+	Value    string `json:"value"`
+}
+
+// SearchResult contains all hits for a query and any resource types that could not be searched
+type SearchResult struct {
+	Query  string            `json:"query"`
+	Hits   []SearchHit       `json:"hits"`
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// searcher collects hits for a case-insensitive substring query. If the query is an
+// IP address it also matches CIDR values containing that address.
+type searcher struct {
+	query string
+	lower string
+	addr  netip.Addr
+	hits  []SearchHit
+}
+
+func newSearcher(query string) *searcher {
+	s := &searcher{query: query, lower: strings.ToLower(query)}
+	if addr, err := netip.ParseAddr(query); err == nil {
+		s.addr = addr
+	}
+	return s
+}
+
+func (s *searcher) matches(value string) bool {
+	if value == "" {
+		return false
+	}
+	if strings.Contains(strings.ToLower(value), s.lower) {
+		return true
+	}
+	if s.addr.IsValid() {
+		if prefix, err := netip.ParsePrefix(value); err == nil && prefix.Contains(s.addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// check records a hit for the first field whose value matches. fields alternates
+// field name and value.
+func (s *searcher) check(hit SearchHit, fields ...string) {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if s.matches(fields[i+1]) {
+			hit.Field = fields[i]
+			hit.Value = fields[i+1]
+			s.hits = append(s.hits, hit)
+			return
+		}
+	}
+}
+
+func (s *searcher) checkList(hit SearchHit, field string, values []string) {
+	for _, v := range values {
+		if s.matches(v) {
+			hit.Field = field
+			hit.Value = v
+			s.hits = append(s.hits, hit)
+			return
+		}
+	}
+}
+
+type SearchNetbirdParams struct {
+	Query string   `json:"query" jsonschema:"required,description=Free-text query matched case-insensitively against names\\, IPs\\, DNS labels\\, networks\\, domains and emails. An IP address also matches routes and resources whose CIDR contains it"`
+	Types []string `json:"types,omitempty" jsonschema:"description=Restrict the search to these resource types (peer\\, group\\, policy\\, policy_rule\\, route\\, network\\, network_resource\\, nameserver\\, user\\, setup_key)"`
+}
+
+// SearchNetbird searches peers, groups, policies, routes, networks, network resources,
+// nameservers, users and setup keys for the query. A resource type that cannot be
+// fetched is reported in Errors and does not fail the whole search.
+func SearchNetbird(ctx context.Context, query string, types []string) (*SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+
+	wanted := make(map[string]bool)
+	for _, t := range types {
+		valid := false
+		for _, known := range searchTypes {
+			if t == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown resource type '%s', must be one of %v", t, searchTypes)
+		}
+		wanted[t] = true
+	}
+	want := func(t string) bool {
+		return len(wanted) == 0 || wanted[t]
+	}
+
+	s := newSearcher(query)
+	result := &SearchResult{Query: query, Errors: make(map[string]string)}
+
+	if want(searchTypePeer) {
+		peers, err := listNetbirdPeers(ctx, ListNetbirdPeersParams{})
+		if err != nil {
+			result.Errors[searchTypePeer] = err.Error()
+		}
+		for _, p := range peers {
+			hit := SearchHit{Type: searchTypePeer, ID: p.ID, Name: p.Name}
+			before := len(s.hits)
+			s.check(hit,
+				"id", p.ID,
+				"name", p.Name,
+				"hostname", p.Hostname,
+				"ip", p.IP,
+				"dns_label", p.DNSLabel,
+				"serial_number", p.SerialNumber,
+				"connection_ip", p.ConnectionIP,
+			)
+			if len(s.hits) == before {
+				s.checkList(hit, "extra_dns_labels", p.ExtraDNSLabels)
+			}
+		}
+	}
+
+	if want(searchTypeGroup) {
+		groups, err := listNetbirdGroups(ctx, ListNetbirdGroupsParams{})
+		if err != nil {
+			result.Errors[searchTypeGroup] = err.Error()
+		}
+		for _, g := range groups {
+			s.check(SearchHit{Type: searchTypeGroup, ID: g.ID, Name: g.Name}, "id", g.ID, "name", g.Name)
+		}
+	}
+
+	if want(searchTypePolicy) || want(searchTypePolicyRule) {
+		policies, err := listNetbirdPolicies(ctx, ListNetbirdPoliciesParams{})
+		if err != nil {
+			result.Errors[searchTypePolicy] = err.Error()
+		}
+		for _, p := range policies {
+			if want(searchTypePolicy) {
+				s.check(SearchHit{Type: searchTypePolicy, ID: p.ID, Name: p.Name},
+					"id", p.ID, "name", p.Name, "description", p.Description)
+			}
+			if want(searchTypePolicyRule) {
+				for _, r := range p.Rules {
+					s.check(SearchHit{Type: searchTypePolicyRule, ID: r.ID, Name: r.Name, ParentID: p.ID},
+						"id", r.ID, "name", r.Name, "description", r.Description)
+				}
+			}
+		}
+	}
+
+	if want(searchTypeRoute) {
+		routes, err := listNetbirdRoutes(ctx, ListNetbirdRoutesParams{})
+		if err != nil {
+			result.Errors[searchTypeRoute] = err.Error()
+		}
+		for _, r := range routes {
+			hit := SearchHit{Type: searchTypeRoute, ID: r.ID, Name: r.NetworkID}
+			before := len(s.hits)
+			s.check(hit, "id", r.ID, "network", r.Network, "network_id", r.NetworkID, "description", r.Description)
+			if len(s.hits) == before {
+				s.checkList(hit, "domains", r.Domains)
+			}
+		}
+	}
+
+	if want(searchTypeNetwork) || want(searchTypeNetworkResource) {
+		networks, err := listNetbirdNetworks(ctx, ListNetbirdNetworksParams{})
+		if err != nil {
+			result.Errors[searchTypeNetwork] = err.Error()
+		}
+		for _, n := range networks {
+			if want(searchTypeNetwork) {
+				s.check(SearchHit{Type: searchTypeNetwork, ID: n.ID, Name: n.Name}, "id", n.ID, "name", n.Name)
+			}
+			if !want(searchTypeNetworkResource) {
+				continue
+			}
+			resources, err := listNetbirdNetworkResources(ctx, ListNetbirdNetworkResourcesParams{NetworkID: n.ID})
+			if err != nil {
+				result.Errors[searchTypeNetworkResource+":"+n.ID] = err.Error()
+				continue
+			}
+			for _, r := range resources {
+				s.check(SearchHit{Type: searchTypeNetworkResource, ID: r.ID, Name: r.Name, ParentID: n.ID},
+					"id", r.ID, "address", r.Address, "name", r.Name)
+			}
+		}
+	}
+
+	if want(searchTypeNameserver) {
+		nameservers, err := listNetbirdNameservers(ctx, ListNetbirdNameserversParams{})
+		if err != nil {
+			result.Errors[searchTypeNameserver] = err.Error()
+		}
+		for _, ns := range nameservers {
+			ips := make([]string, 0, len(ns.Nameservers))
+			for _, server := range ns.Nameservers {
+				ips = append(ips, server.IP)
+			}
+			hit := SearchHit{Type: searchTypeNameserver, ID: ns.ID, Name: ns.Name}
+			before := len(s.hits)
+			s.check(hit, "id", ns.ID, "name", ns.Name)
+			if len(s.hits) == before {
+				s.checkList(hit, "nameservers.ip", ips)
+			}
+			if len(s.hits) == before {
+				s.checkList(hit, "domains", ns.Domains)
+			}
+		}
+	}
+
+	if want(searchTypeUser) {
+		users, err := listNetbirdUsers(ctx, ListNetbirdUsersParams{})
+		if err != nil {
+			result.Errors[searchTypeUser] = err.Error()
+		}
+		for _, u := range users {
+			s.check(SearchHit{Type: searchTypeUser, ID: u.ID, Name: u.Name}, "id", u.ID, "email", u.Email, "name", u.Name)
+		}
+	}
+
+	if want(searchTypeSetupKey) {
+		keys, err := listNetbirdSetupKeys(ctx, ListNetbirdSetupKeysParams{})
+		if err != nil {
+			result.Errors[searchTypeSetupKey] = err.Error()
+		}
+		for _, k := range keys {
+			s.check(SearchHit{Type: searchTypeSetupKey, ID: k.ID, Name: k.Name}, "id", k.ID, "name", k.Name)
+		}
+	}
+
+	result.Hits = s.hits
+	if result.Hits == nil {
+		result.Hits = []SearchHit{}
+	}
+	return result, nil
+}
+
+func searchNetbird(ctx context.Context, args SearchNetbirdParams) (*SearchResult, error) {
+	return SearchNetbird(ctx, args.Query, args.Types)
+}
+
+var SearchNetbirdTool = mcpnetbird.MustTool(
+	"search_netbird",
+	"Search across peers (name, hostname, IP, DNS label, serial), groups, policies and rule names, routes (network/domains), network resources (address), nameservers, users (email) and setup keys. Returns typed hits with IDs. Use this to find every place an IP, name or domain appears instead of calling each list tool.",
+	mcpnetbird.ReadOnlyTool,
+	searchNetbird,
+)
+
+func AddNetbirdSearchTools(mcp *server.MCPServer) {
+	SearchNetbirdTool.Register(mcp)
+}
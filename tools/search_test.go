@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func createMockSearchServer(t *testing.T, failing map[string]bool) *httptest.Server {
+	responses := map[string]any{
+		"/peers": []NetbirdPeer{
+			{ID: "peer-1", Name: "db-server", Hostname: "db01", IP: "100.64.0.10", DNSLabel: "db01.netbird.cloud"},
+			{ID: "peer-2", Name: "laptop", Hostname: "alice-mbp", IP: "100.64.0.11", SerialNumber: "C02XYZ"},
+		},
+		"/groups": []NetbirdGroup{
+			{ID: "group-1", Name: "db-admins"},
+		},
+		"/policies": []NetbirdPolicy{
+			{ID: "policy-1", Name: "app-to-db", Rules: []NetbirdPolicyRule{{ID: "rule-1", Name: "allow-postgres"}}},
+		},
+		"/routes": []NetbirdRoute{
+			{ID: "route-1", NetworkID: "office", Network: "10.10.0.0/16"},
+			{ID: "route-2", NetworkID: "saas", Domains: []string{"db.example.com"}},
+		},
+		"/networks": []NetbirdNetwork{
+			{ID: "net-1", Name: "datacenter"},
+		},
+		"/networks/net-1/resources": []NetbirdNetworkResource{
+			{ID: "res-1", Name: "db-subnet", Address: "10.20.0.0/24"},
+		},
+		"/dns/nameservers": []NetbirdNameservers{
+			{ID: "ns-1", Name: "internal", Nameservers: []Nameserver{{IP: "10.10.0.53", NSType: "udp", Port: 53}}},
+		},
+		"/users": []NetbirdUser{
+			{ID: "user-1", Name: "Alice", Email: "alice@example.com"},
+		},
+		"/setup-keys": []NetbirdSetupKey{
+			{ID: "key-1", Name: "db-bootstrap"},
+		},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing[r.URL.Path] {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request path: %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func hitKeys(result *SearchResult) map[string]string {
+	keys := make(map[string]string)
+	for _, hit := range result.Hits {
+		keys[hit.Type+"/"+hit.ID] = hit.Field
+	}
+	return keys
+}
+
+func TestSearchNetbird_AcrossResourceTypes(t *testing.T) {
+	server := createMockSearchServer(t, nil)
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	result, err := SearchNetbird(ctx, "DB", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"peer/peer-1":            "name",
+		"group/group-1":          "name",
+		"policy/policy-1":        "name",
+		"route/route-2":          "domains",
+		"network_resource/res-1": "name",
+		"setup_key/key-1":        "name",
+	}
+	got := hitKeys(result)
+	for key, field := range expected {
+		if got[key] != field {
+			t.Errorf("expected hit %s on field %q, got %q", key, field, got[key])
+		}
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d hits, got %d: %v", len(expected), len(got), got)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+}
+
+func TestSearchNetbird_IPMatchesCIDR(t *testing.T) {
+	server := createMockSearchServer(t, nil)
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	result, err := SearchNetbird(ctx, "10.10.0.53", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := hitKeys(result)
+	if got["route/route-1"] != "network" {
+		t.Errorf("expected route containing the IP, got %v", got)
+	}
+	if got["nameserver/ns-1"] != "nameservers.ip" {
+		t.Errorf("expected nameserver with the IP, got %v", got)
+	}
+	if _, ok := got["network_resource/res-1"]; ok {
+		t.Errorf("did not expect resource outside the CIDR, got %v", got)
+	}
+}
+
+func TestSearchNetbird_TypesAndErrors(t *testing.T) {
+	server := createMockSearchServer(t, map[string]bool{"/users": true})
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	result, err := SearchNetbird(ctx, "alice", []string{"peer", "user"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := hitKeys(result)
+	if got["peer/peer-2"] != "hostname" || len(got) != 1 {
+		t.Errorf("expected only the peer hit, got %v", got)
+	}
+	if _, ok := result.Errors["user"]; !ok {
+		t.Errorf("expected users error to be reported, got %v", result.Errors)
+	}
+
+	if _, err := SearchNetbird(ctx, "alice", []string{"pears"}); err == nil {
+		t.Error("expected error for unknown type")
+	}
+	if _, err := SearchNetbird(ctx, "  ", nil); err == nil {
+		t.Error("expected error for empty query")
+	}
+}
@@ -26,10 +26,17 @@ type NetbirdSetupKey struct {
 	AllowExtraDNSLabels *bool     `json:"allow_extra_dns_labels,omitempty"`
 }
 
-type ListNetbirdSetupKeysParams struct{}
+type ListNetbirdSetupKeysParams struct {
+	ListOptions
+}
 
 func listNetbirdSetupKeys(ctx context.Context, args ListNetbirdSetupKeysParams) ([]NetbirdSetupKey, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
 	var keys []NetbirdSetupKey
 	if err := client.Get(ctx, "/setup-keys", &keys); err != nil {
 		return nil, err
@@ -40,7 +47,8 @@ func listNetbirdSetupKeys(ctx context.Context, args ListNetbirdSetupKeysParams)
 var ListNetbirdSetupKeys = mcpnetbird.MustTool(
 	"list_netbird_setup_keys",
 	"List all Netbird setup keys",
-	listNetbirdSetupKeys,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdSetupKeys),
 )
 
 type GetNetbirdSetupKeyParams struct {
@@ -59,15 +67,16 @@ func getNetbirdSetupKey(ctx context.Context, args GetNetbirdSetupKeyParams) (*Ne
 var GetNetbirdSetupKey = mcpnetbird.MustTool(
 	"get_netbird_setup_key",
 	"Get a specific Netbird setup key by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdSetupKey,
 )
 
 type CreateNetbirdSetupKeyParams struct {
 	Name                string    `json:"name" jsonschema:"required,description=Setup key name"`
-	Type                string    `json:"type" jsonschema:"required,description=Key type (reusable or one-off)"`
+	Type                string    `json:"type" jsonschema:"required,enum=reusable,enum=one-off,description=Key type (reusable or one-off)"`
 	ExpiresIn           int       `json:"expires_in" jsonschema:"required,description=Expiration time in seconds"`
 	AutoGroups          *[]string `json:"auto_groups,omitempty" jsonschema:"description=Auto-assign groups"`
-	UsageLimit          *int      `json:"usage_limit,omitempty" jsonschema:"description=Usage limit (0 for unlimited)"`
+	UsageLimit          *int      `json:"usage_limit,omitempty" jsonschema:"minimum=0,description=Usage limit (0 for unlimited)"`
 	Ephemeral           *bool     `json:"ephemeral,omitempty" jsonschema:"description=Ephemeral peer (deleted on disconnect)"`
 	AllowExtraDNSLabels *bool     `json:"allow_extra_dns_labels,omitempty" jsonschema:"description=Allow extra DNS labels"`
 }
@@ -84,6 +93,7 @@ func createNetbirdSetupKey(ctx context.Context, args CreateNetbirdSetupKeyParams
 var CreateNetbirdSetupKey = mcpnetbird.MustTool(
 	"create_netbird_setup_key",
 	"Create a new Netbird setup key",
+	mcpnetbird.AdditiveTool,
 	createNetbirdSetupKey,
 )
 
@@ -106,15 +116,44 @@ func updateNetbirdSetupKey(ctx context.Context, args UpdateNetbirdSetupKeyParams
 var UpdateNetbirdSetupKey = mcpnetbird.MustTool(
 	"update_netbird_setup_key",
 	"Update an existing Netbird setup key",
+	mcpnetbird.UpdateTool,
 	updateNetbirdSetupKey,
 )
 
 type DeleteNetbirdSetupKeyParams struct {
 	KeyID string `json:"key_id" jsonschema:"required,description=The ID of the setup key to delete"`
+	Confirmation
+}
+
+// describeSetupKeyDeletion reports the setup key and warns if it can still enroll
+// peers. No other object refers to a setup key.
+func describeSetupKeyDeletion(ctx context.Context, args DeleteNetbirdSetupKeyParams) (*Impact, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	var key NetbirdSetupKey
+	if err := client.Get(ctx, "/setup-keys/"+args.KeyID, &key); err != nil {
+		return nil, err
+	}
+	impact := &Impact{Action: "delete /setup-keys/" + args.KeyID, Target: key}
+	if key.Valid {
+		impact.Warnings = append(impact.Warnings, "the setup key can still enroll peers; peers enrolled with it keep working")
+	}
+	return impact, nil
 }
 
 func deleteNetbirdSetupKey(ctx context.Context, args DeleteNetbirdSetupKeyParams) (map[string]string, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
 	if err := client.Delete(ctx, "/setup-keys/"+args.KeyID); err != nil {
 		return nil, err
 	}
@@ -123,8 +162,9 @@ func deleteNetbirdSetupKey(ctx context.Context, args DeleteNetbirdSetupKeyParams
 
 var DeleteNetbirdSetupKey = mcpnetbird.MustTool(
 	"delete_netbird_setup_key",
-	"Delete a Netbird setup key",
-	deleteNetbirdSetupKey,
+	"Delete a Netbird setup key. The impact summary warns if the key can still enroll peers. The first call returns an impact summary and a confirmation_token; call again with the token to delete.",
+	mcpnetbird.DeleteTool,
+	withConfirmation(describeSetupKeyDeletion, deleteNetbirdSetupKey),
 )
 
 func AddNetbirdSetupKeyTools(mcp *server.MCPServer) {
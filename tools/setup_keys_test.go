@@ -1,9 +1,13 @@
 package tools
 
 import (
+	"context"
 	"encoding/json"
+	"slices"
 	"strings"
 	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
 )
 
 func TestCreateNetbirdSetupKeyParamsMarshaling(t *testing.T) {
@@ -214,3 +218,28 @@ func TestNetbirdSetupKeyMarshaling(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteNetbirdSetupKey_WarnsAboutValidKeys(t *testing.T) {
+	objects := mergeTestObjects()
+	objects["/setup-keys/k3"] = NetbirdSetupKey{ID: "k3", Name: "ci", Valid: true}
+	store := newFakeMergeStore(t, objects, mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	impact, err := describeSetupKeyDeletion(ctx, DeleteNetbirdSetupKeyParams{KeyID: "k3"})
+	if err != nil || len(impact.Warnings) != 1 || !strings.Contains(impact.Warnings[0], "can still enroll peers") {
+		t.Errorf("expected a warning for a valid key, got %+v, %v", impact, err)
+	}
+	impact, err = describeSetupKeyDeletion(ctx, DeleteNetbirdSetupKeyParams{KeyID: "k1"})
+	if err != nil || len(impact.Warnings) != 0 {
+		t.Errorf("expected no warning for a key that can't enroll peers, got %+v, %v", impact, err)
+	}
+
+	for _, keyID := range []string{"k3", "k1"} {
+		if _, err := deleteNetbirdSetupKey(ctx, DeleteNetbirdSetupKeyParams{KeyID: keyID}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !slices.Equal(store.deleted, []string{"/setup-keys/k3", "/setup-keys/k1"}) {
+		t.Errorf("expected both keys to be deleted, got %v", store.deleted)
+	}
+}
@@ -0,0 +1,346 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// topologyNode is an object in the rendered topology. Key is unique across kinds.
+type topologyNode struct {
+	Key   string
+	Kind  string
+	Label string
+}
+
+type topologyEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// topology is a directed graph of Netbird objects, in insertion order
+type topology struct {
+	nodes []topologyNode
+	index map[string]int
+	edges []topologyEdge
+	seen  map[topologyEdge]bool
+}
+
+func newTopology() *topology {
+	return &topology{index: make(map[string]int), seen: make(map[topologyEdge]bool)}
+}
+
+// node adds a node unless it exists and returns its key
+func (t *topology) node(kind, id, label string) string {
+	key := kind + ":" + id
+	if _, ok := t.index[key]; !ok {
+		t.index[key] = len(t.nodes)
+		t.nodes = append(t.nodes, topologyNode{Key: key, Kind: kind, Label: label})
+	}
+	return key
+}
+
+func (t *topology) edge(from, to, label string) {
+	edge := topologyEdge{From: from, To: to, Label: label}
+	if !t.seen[edge] {
+		t.seen[edge] = true
+		t.edges = append(t.edges, edge)
+	}
+}
+
+// scope returns the part of t around the node with key: the node, its
+// neighbours and every edge touching one of them. A policy next to a group
+// thereby keeps the other groups of its rules.
+func (t *topology) scope(key string) *topology {
+	focus := map[string]bool{key: true}
+	for _, edge := range t.edges {
+		if edge.From == key {
+			focus[edge.To] = true
+		}
+		if edge.To == key {
+			focus[edge.From] = true
+		}
+	}
+
+	scoped := newTopology()
+	keep := map[string]bool{key: true}
+	for _, edge := range t.edges {
+		if focus[edge.From] || focus[edge.To] {
+			keep[edge.From] = true
+			keep[edge.To] = true
+		}
+	}
+	for _, node := range t.nodes {
+		if keep[node.Key] {
+			scoped.index[node.Key] = len(scoped.nodes)
+			scoped.nodes = append(scoped.nodes, node)
+		}
+	}
+	for _, edge := range t.edges {
+		if focus[edge.From] || focus[edge.To] {
+			scoped.edge(edge.From, edge.To, edge.Label)
+		}
+	}
+	return scoped
+}
+
+// topologyShapes are the Graphviz shapes of each kind of node
+var topologyShapes = map[string]string{
+	"group":    "ellipse",
+	"policy":   "box",
+	"network":  "folder",
+	"router":   "diamond",
+	"resource": "note",
+	"route":    "cds",
+	"peer":     "circle",
+}
+
+// dotQuote quotes s as a Graphviz ID
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// dot renders t in the Graphviz DOT language
+func (t *topology) dot() string {
+	var b strings.Builder
+	b.WriteString("digraph netbird {\n  rankdir=LR;\n")
+	for _, node := range t.nodes {
+		fmt.Fprintf(&b, "  %s [label=%s, shape=%s];\n", dotQuote(node.Key), dotQuote(node.Label), topologyShapes[node.Kind])
+	}
+	for _, edge := range t.edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&b, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+		} else {
+			fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Label))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaidShapes are the opening and closing brackets of each kind of node
+var mermaidShapes = map[string][2]string{
+	"group":    {"([", "])"},
+	"policy":   {"[", "]"},
+	"network":  {"[[", "]]"},
+	"router":   {"{{", "}}"},
+	"resource": {"[/", "/]"},
+	"route":    {">", "]"},
+	"peer":     {"((", "))"},
+}
+
+// mermaidQuote quotes s as a Mermaid label
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "\n", "<br/>").Replace(s) + `"`
+}
+
+// mermaid renders t as a Mermaid flowchart. Nodes get short IDs since Mermaid
+// IDs can't contain the characters of Netbird IDs and names.
+func (t *topology) mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(t.nodes))
+	for i, node := range t.nodes {
+		ids[node.Key] = fmt.Sprintf("n%d", i)
+		shape := mermaidShapes[node.Kind]
+		fmt.Fprintf(&b, "  %s%s%s%s\n", ids[node.Key], shape[0], mermaidQuote(node.Label), shape[1])
+	}
+	for _, edge := range t.edges {
+		if edge.Label == "" {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids[edge.From], ids[edge.To])
+		} else {
+			fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[edge.From], mermaidQuote(edge.Label), ids[edge.To])
+		}
+	}
+	return b.String()
+}
+
+// buildTopology fetches groups, peers, policies, networks and routes and links
+// them: groups to the policies whose rules use them as sources, policies to
+// their destination groups, networks to their routers and resources, and routes
+// to their routing peers and peer groups
+func buildTopology(ctx context.Context, client *mcpnetbird.NetbirdClient) (*topology, error) {
+	var groups []NetbirdGroup
+	if err := client.Get(ctx, "/groups", &groups); err != nil {
+		return nil, fmt.Errorf("listing groups: %w", err)
+	}
+	groupNames := make(map[string]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+	var peers []NetbirdPeer
+	if err := client.Get(ctx, "/peers", &peers); err != nil {
+		return nil, fmt.Errorf("listing peers: %w", err)
+	}
+	peerNames := make(map[string]string, len(peers))
+	for _, peer := range peers {
+		peerNames[peer.ID] = peer.Name
+	}
+	nameOr := func(names map[string]string, id string) string {
+		if name := names[id]; name != "" {
+			return name
+		}
+		return id
+	}
+
+	t := newTopology()
+	group := func(id string) string { return t.node("group", id, nameOr(groupNames, id)) }
+	peer := func(id string) string { return t.node("peer", id, nameOr(peerNames, id)) }
+
+	networks, err := listNetworkChildren(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	resourceNames := make(map[string]string)
+	for _, network := range networks {
+		networkKey := t.node("network", network.network.ID, network.network.Name)
+		for _, router := range network.routers {
+			label := "router"
+			if router.Peer != nil && *router.Peer != "" {
+				label = "router " + nameOr(peerNames, *router.Peer)
+			}
+			routerKey := t.node("router", router.ID, label)
+			t.edge(networkKey, routerKey, "")
+			if router.Peer != nil && *router.Peer != "" {
+				t.edge(routerKey, peer(*router.Peer), "peer")
+			}
+			if router.PeerGroups != nil {
+				for _, groupID := range *router.PeerGroups {
+					t.edge(routerKey, group(groupID), "peer group")
+				}
+			}
+		}
+		for _, resource := range network.resources {
+			resourceNames[resource.ID] = resource.Name
+			resourceKey := t.node("resource", resource.ID, resource.Name+"\n"+resource.Address)
+			t.edge(networkKey, resourceKey, "")
+			for _, g := range resource.Groups {
+				t.edge(resourceKey, group(g.ID), "member of")
+			}
+		}
+	}
+
+	// resource is the node of a resource a policy rule refers to
+	resource := func(ref ResourceReference) string {
+		if ref.Type == "peer" {
+			return peer(ref.ID)
+		}
+		return t.node("resource", ref.ID, nameOr(resourceNames, ref.ID))
+	}
+
+	var policies []NetbirdPolicy
+	if err := client.Get(ctx, "/policies", &policies); err != nil {
+		return nil, fmt.Errorf("listing policies: %w", err)
+	}
+	for _, policy := range policies {
+		policyKey := t.node("policy", policy.ID, policy.Name)
+		for _, rule := range policy.Rules {
+			label := rule.Name
+			if rule.Bidirectional {
+				label += " (bidirectional)"
+			}
+			for _, source := range rule.Sources {
+				t.edge(group(source.ID), policyKey, label)
+			}
+			if rule.SourceResource != nil {
+				t.edge(resource(*rule.SourceResource), policyKey, label)
+			}
+			for _, destination := range rule.Destinations {
+				t.edge(policyKey, group(destination.ID), label)
+			}
+			if rule.DestinationResource != nil {
+				t.edge(policyKey, resource(*rule.DestinationResource), label)
+			}
+		}
+	}
+
+	var routes []NetbirdRoute
+	if err := client.Get(ctx, "/routes", &routes); err != nil {
+		return nil, fmt.Errorf("listing routes: %w", err)
+	}
+	for _, route := range routes {
+		destination := route.Network
+		if len(route.Domains) > 0 {
+			destination = strings.Join(route.Domains, ", ")
+		}
+		routeKey := t.node("route", route.ID, route.NetworkID+"\n"+destination)
+		if route.Peer != "" {
+			t.edge(routeKey, peer(route.Peer), "routed by")
+		}
+		for _, groupID := range route.PeerGroups {
+			t.edge(routeKey, group(groupID), "routed by")
+		}
+	}
+
+	return t, nil
+}
+
+const (
+	topologyFormatDOT     = "dot"
+	topologyFormatMermaid = "mermaid"
+)
+
+type RenderNetbirdTopologyParams struct {
+	Format  string `json:"format,omitempty" jsonschema:"enum=dot,enum=mermaid,description=Output format: dot (Graphviz) or mermaid. Defaults to dot."`
+	Group   string `json:"group,omitempty" jsonschema:"description=Only show what is around this group (ID or name)"`
+	Network string `json:"network,omitempty" jsonschema:"description=Only show what is around this network (ID or name)"`
+}
+
+func renderNetbirdTopology(ctx context.Context, args RenderNetbirdTopologyParams) (string, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	if args.Group != "" && args.Network != "" {
+		return "", fmt.Errorf("group and network can't both be set")
+	}
+	t, err := buildTopology(ctx, client)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case args.Group != "":
+		groups, err := resolveGroups(ctx, client, []string{args.Group})
+		if err != nil {
+			return "", err
+		}
+		t = t.scope(t.node("group", groups[0].ID, groups[0].Name))
+	case args.Network != "":
+		var networks []NetbirdNetwork
+		if err := client.Get(ctx, "/networks", &networks); err != nil {
+			return "", fmt.Errorf("listing networks: %w", err)
+		}
+		network, err := resolveByIDOrName("network", args.Network, networks,
+			func(n NetbirdNetwork) string { return n.ID },
+			func(n NetbirdNetwork) string { return n.Name },
+		)
+		if err != nil {
+			return "", err
+		}
+		t = t.scope(t.node("network", network.ID, network.Name))
+	}
+
+	if args.Format == topologyFormatMermaid {
+		return t.mermaid(), nil
+	}
+	return t.dot(), nil
+}
+
+var RenderNetbirdTopology = mcpnetbird.MustTool(
+	"render_netbird_topology",
+	"Render the Netbird topology as Graphviz DOT or a Mermaid flowchart: groups to the policies using them as sources and on to their destination groups, networks with their routers and resources, and routes with their routing peers and peer groups. Set group or network to only show the objects around it.",
+	mcpnetbird.ReadOnlyTool,
+	renderNetbirdTopology,
+)
+
+func AddNetbirdTopologyTools(mcp *server.MCPServer) {
+	RenderNetbirdTopology.Register(mcp)
+}
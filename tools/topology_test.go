@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func TestRenderNetbirdTopology_DOT(t *testing.T) {
+	newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	text, err := renderNetbirdTopology(ctx, RenderNetbirdTopologyParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"digraph netbird {",
+		`"group:g2" [label="developers", shape=ellipse];`,
+		`"group:g2" -> "policy:pol1" [label="ssh"];`,
+		`"policy:pol1" -> "group:g3" [label="ssh"];`,
+		`"peer:p1" -> "policy:pol3" [label="db"];`,
+		`"policy:pol3" -> "resource:res1" [label="db"];`,
+		`"network:n1" -> "router:rtr1";`,
+		`"router:rtr1" -> "group:g2" [label="peer group"];`,
+		`"resource:res1" [label="db\n10.1.0.5/32", shape=note];`,
+		`"route:rt1" -> "group:g3" [label="routed by"];`,
+		`"route:rt2" -> "peer:p1" [label="routed by"];`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in\n%s", want, text)
+		}
+	}
+}
+
+func TestRenderNetbirdTopology_MermaidScopedToGroup(t *testing.T) {
+	newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	text, err := renderNetbirdTopology(ctx, RenderNetbirdTopologyParams{Format: topologyFormatMermaid, Group: "dev-team"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(text, "flowchart LR\n") {
+		t.Errorf("expected a flowchart, got\n%s", text)
+	}
+	// Policies using the group keep their other groups; unrelated objects are left out
+	for _, want := range []string{`(["dev-team"])`, `(["developers"])`, `["dev access"]`, `-->|"ssh"|`, `[/"db<br/>10.1.0.5/32"/]`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %s in\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{`"ops"`, `"exit<br/>0.0.0.0/0"`} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("expected %s to be left out of\n%s", unwanted, text)
+		}
+	}
+}
+
+func TestRenderNetbirdTopology_ScopedToNetwork(t *testing.T) {
+	newFakeMergeStore(t, dependencyTestObjects(), mergeTestGroups()...)
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+
+	text, err := renderNetbirdTopology(ctx, RenderNetbirdTopologyParams{Network: "lab"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(text, `"policy:pol3" -> "resource:res1"`) || strings.Contains(text, "route:") {
+		t.Errorf("expected the network and the policies using its resources only, got\n%s", text)
+	}
+
+	if _, err := renderNetbirdTopology(ctx, RenderNetbirdTopologyParams{Network: "office"}); err == nil || !strings.Contains(err.Error(), `network "office" not found`) {
+		t.Errorf("expected unknown network error, got %v", err)
+	}
+	if _, err := renderNetbirdTopology(ctx, RenderNetbirdTopologyParams{Group: "devs", Network: "lab"}); err == nil {
+		t.Error("expected an error when both group and network are set")
+	}
+}
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"time"
 
 	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
@@ -21,10 +23,17 @@ type NetbirdUser struct {
 	Issued             string    `json:"issued"`
 }
 
-type ListNetbirdUsersParams struct{}
+type ListNetbirdUsersParams struct {
+	ListOptions
+}
 
 func listNetbirdUsers(ctx context.Context, args ListNetbirdUsersParams) ([]NetbirdUser, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
 	var users []NetbirdUser
 	if err := client.Get(ctx, "/users", &users); err != nil {
 		return nil, err
@@ -35,7 +44,8 @@ func listNetbirdUsers(ctx context.Context, args ListNetbirdUsersParams) ([]Netbi
 var ListNetbirdUsers = mcpnetbird.MustTool(
 	"list_netbird_users",
 	"List all Netbird users",
-	listNetbirdUsers,
+	mcpnetbird.ReadOnlyTool,
+	withListOptions(listNetbirdUsers),
 )
 
 type GetNetbirdUserParams struct {
@@ -54,13 +64,69 @@ func getNetbirdUser(ctx context.Context, args GetNetbirdUserParams) (*NetbirdUse
 var GetNetbirdUser = mcpnetbird.MustTool(
 	"get_netbird_user",
 	"Get a specific Netbird user by ID",
+	mcpnetbird.ReadOnlyTool,
 	getNetbirdUser,
 )
 
+// NetbirdUserPermissions are the per-module permissions returned with the current user.
+// Modules maps a module such as "peers" to its operations (read, create, update, delete).
+type NetbirdUserPermissions struct {
+	IsRestricted bool                       `json:"is_restricted"`
+	Modules      map[string]map[string]bool `json:"modules,omitempty"`
+}
+
+// NetbirdCurrentUser is the user owning the API token, as returned by /users/current
+type NetbirdCurrentUser struct {
+	NetbirdUser
+	Permissions *NetbirdUserPermissions `json:"permissions,omitempty"`
+}
+
+func fetchCurrentUser(ctx context.Context, client *mcpnetbird.NetbirdClient) (*NetbirdCurrentUser, error) {
+	var user NetbirdCurrentUser
+	if err := client.Get(ctx, "/users/current", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CurrentUserResult is the current user together with the tool categories it can use
+type CurrentUserResult struct {
+	*NetbirdCurrentUser
+	ToolAccess []CategoryAccess `json:"tool_access"`
+}
+
+type GetNetbirdCurrentUserParams struct{}
+
+// FetchNetbirdCurrentUser returns the user owning the API token in ctx
+func FetchNetbirdCurrentUser(ctx context.Context) (*NetbirdCurrentUser, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+	return fetchCurrentUser(ctx, client)
+}
+
+func getNetbirdCurrentUser(ctx context.Context, args GetNetbirdCurrentUserParams) (*CurrentUserResult, error) {
+	user, err := FetchNetbirdCurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &CurrentUserResult{NetbirdCurrentUser: user, ToolAccess: categoryAccess(user)}, nil
+}
+
+var GetNetbirdCurrentUser = mcpnetbird.MustTool(
+	"get_netbird_current_user",
+	"Get the Netbird user owning the API token: its role, per-module permissions, whether it is a service user, and which tool categories it can read or write",
+	mcpnetbird.ReadOnlyTool,
+	getNetbirdCurrentUser,
+)
+
 type InviteNetbirdUserParams struct {
 	Email      string    `json:"email" jsonschema:"required,description=User email address"`
 	Name       *string   `json:"name,omitempty" jsonschema:"description=User name"`
-	Role       string    `json:"role" jsonschema:"required,description=User role (admin, user, owner)"`
+	Role       string    `json:"role" jsonschema:"required,enum=owner,enum=admin,enum=network_admin,enum=billing_admin,enum=auditor,enum=user,description=User role"`
 	AutoGroups *[]string `json:"auto_groups,omitempty" jsonschema:"description=Auto-assign groups"`
 }
 
@@ -76,12 +142,13 @@ func inviteNetbirdUser(ctx context.Context, args InviteNetbirdUserParams) (*Netb
 var InviteNetbirdUser = mcpnetbird.MustTool(
 	"invite_netbird_user",
 	"Invite a new Netbird user",
+	mcpnetbird.AdditiveTool,
 	inviteNetbirdUser,
 )
 
 type UpdateNetbirdUserParams struct {
 	UserID     string    `json:"user_id" jsonschema:"required,description=The ID of the user to update"`
-	Role       *string   `json:"role,omitempty" jsonschema:"description=User role (admin, user, owner)"`
+	Role       *string   `json:"role,omitempty" jsonschema:"enum=owner,enum=admin,enum=network_admin,enum=billing_admin,enum=auditor,enum=user,description=User role"`
 	AutoGroups *[]string `json:"auto_groups,omitempty" jsonschema:"description=Auto-assign groups"`
 	IsBlocked  *bool     `json:"is_blocked,omitempty" jsonschema:"description=Block the user"`
 }
@@ -98,15 +165,43 @@ func updateNetbirdUser(ctx context.Context, args UpdateNetbirdUserParams) (*Netb
 var UpdateNetbirdUser = mcpnetbird.MustTool(
 	"update_netbird_user",
 	"Update an existing Netbird user",
+	mcpnetbird.UpdateTool,
 	updateNetbirdUser,
 )
 
 type DeleteNetbirdUserParams struct {
 	UserID string `json:"user_id" jsonschema:"required,description=The ID of the user to delete"`
+	Confirmation
+}
+
+// describeUserDeletion reports the user to delete. The API has no endpoint to
+// get a single user, so it is looked up in the user list.
+func describeUserDeletion(ctx context.Context, args DeleteNetbirdUserParams) (*Impact, error) {
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
+
+	var users []NetbirdUser
+	if err := client.Get(ctx, "/users", &users); err != nil {
+		return nil, fmt.Errorf("listing users: %w", err)
+	}
+	index := slices.IndexFunc(users, func(u NetbirdUser) bool { return u.ID == args.UserID })
+	if index < 0 {
+		return nil, fmt.Errorf("user %s not found", args.UserID)
+	}
+	return &Impact{Action: "delete /users/" + args.UserID, Target: users[index]}, nil
 }
 
 func deleteNetbirdUser(ctx context.Context, args DeleteNetbirdUserParams) (map[string]string, error) {
-	client := mcpnetbird.NewNetbirdClient(ctx)
+	var client *mcpnetbird.NetbirdClient
+	if mcpnetbird.TestNetbirdClient != nil {
+		client = mcpnetbird.TestNetbirdClient
+	} else {
+		client = mcpnetbird.NewNetbirdClient(ctx)
+	}
 	if err := client.Delete(ctx, "/users/"+args.UserID); err != nil {
 		return nil, err
 	}
@@ -115,13 +210,15 @@ func deleteNetbirdUser(ctx context.Context, args DeleteNetbirdUserParams) (map[s
 
 var DeleteNetbirdUser = mcpnetbird.MustTool(
 	"delete_netbird_user",
-	"Delete a Netbird user",
-	deleteNetbirdUser,
+	"Delete a Netbird user. The first call returns an impact summary and a confirmation_token; call again with the token to delete",
+	mcpnetbird.DeleteTool,
+	withConfirmation(describeUserDeletion, deleteNetbirdUser),
 )
 
 func AddNetbirdUserTools(mcp *server.MCPServer) {
 	ListNetbirdUsers.Register(mcp)
 	GetNetbirdUser.Register(mcp)
+	GetNetbirdCurrentUser.Register(mcp)
 	InviteNetbirdUser.Register(mcp)
 	UpdateNetbirdUser.Register(mcp)
 	DeleteNetbirdUser.Register(mcp)
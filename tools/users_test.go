@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mcpnetbird "github.com/XNet-NGO/mcp-netbird"
+)
+
+func TestGetNetbirdCurrentUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/current" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"id": "svc1", "name": "ci", "role": "network_admin", "is_service_user": true,
+			"permissions": {"is_restricted": true, "modules": {"routes": {"read": true, "update": true}}}
+		}`))
+	}))
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	result, err := getNetbirdCurrentUser(mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token"), GetNetbirdCurrentUserParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Role != "network_admin" || !result.IsServiceUser {
+		t.Errorf("unexpected user %+v", result.NetbirdCurrentUser)
+	}
+	if result.Permissions == nil || !result.Permissions.IsRestricted || !result.Permissions.Modules["routes"]["update"] {
+		t.Errorf("expected permissions to be decoded, got %+v", result.Permissions)
+	}
+	for _, a := range result.ToolAccess {
+		want := a.Category == "routes"
+		if a.Read != want || a.Write != want {
+			t.Errorf("unexpected access %+v", a)
+		}
+	}
+}
+
+func TestDeleteNetbirdUser_Confirmation(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/users":
+			_, _ = w.Write([]byte(`[{"id":"u1","email":"alice@example.com","role":"user"},{"id":"u2","email":"bob@example.com","role":"admin"}]`))
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/users/"):
+			deleted = append(deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			// The API has no GET /users/{id}
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	mcpnetbird.TestNetbirdClient = mcpnetbird.NewNetbirdClientWithBaseURL(server.URL)
+	defer func() { mcpnetbird.TestNetbirdClient = nil }()
+
+	ctx := mcpnetbird.WithNetbirdAPIKey(context.Background(), "test-token")
+	confirmedDeleteUser := withConfirmation(describeUserDeletion, deleteNetbirdUser)
+
+	first, err := confirmedDeleteUser(ctx, DeleteNetbirdUserParams{UserID: "u2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	required, ok := first.(*ConfirmationRequired)
+	if !ok {
+		t.Fatalf("expected confirmation request, got %T", first)
+	}
+	if user, _ := required.Impact.Target.(NetbirdUser); user.Email != "bob@example.com" {
+		t.Errorf("expected impact to describe the user, got %+v", required.Impact)
+	}
+
+	if _, err := confirmedDeleteUser(ctx, DeleteNetbirdUserParams{UserID: "u2", Confirmation: Confirmation{required.ConfirmationToken}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "/users/u2" {
+		t.Errorf("expected the user to be deleted, got %v", deleted)
+	}
+
+	if _, err := confirmedDeleteUser(ctx, DeleteNetbirdUserParams{UserID: "u9"}); err == nil || !strings.Contains(err.Error(), "user u9 not found") {
+		t.Errorf("expected an unknown user to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package mcpnetbird
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+type toolTestResult struct {
+	ID    string   `json:"id"`
+	Peers []string `json:"peers"`
+}
+
+func TestConvertTool_Annotations(t *testing.T) {
+	tool, _, err := ConvertTool("delete_thing", "test tool", DeleteTool, func(ctx context.Context, args auditTestParams) (string, error) {
+		return "deleted", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := tool.Annotations
+	if *a.ReadOnlyHint || !*a.DestructiveHint || !*a.IdempotentHint || *a.OpenWorldHint {
+		t.Errorf("unexpected annotations %+v", a)
+	}
+
+	data, _ := json.Marshal(tool)
+	var encoded map[string]any
+	_ = json.Unmarshal(data, &encoded)
+	annotations, _ := encoded["annotations"].(map[string]any)
+	if annotations["readOnlyHint"] != false || annotations["destructiveHint"] != true {
+		t.Errorf("expected annotations in the tool definition, got %s", data)
+	}
+	if _, ok := encoded["outputSchema"]; ok {
+		t.Errorf("expected no output schema for a string result, got %s", data)
+	}
+}
+
+func TestConvertTool_OutputSchema(t *testing.T) {
+	tool, handler, err := ConvertTool("get_thing", "test tool", ReadOnlyTool, func(ctx context.Context, args auditTestParams) (*toolTestResult, error) {
+		return &toolTestResult{ID: args.Name, Peers: []string{"p1"}}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tool.OutputSchema.Type != "object" {
+		t.Fatalf("expected an object output schema, got %+v", tool.OutputSchema)
+	}
+	for _, name := range []string{"id", "peers"} {
+		if _, ok := tool.OutputSchema.Properties[name]; !ok {
+			t.Errorf("expected %s in output schema properties", name)
+		}
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"name": "thing1"}
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	structured, ok := result.StructuredContent.(*toolTestResult)
+	if !ok || structured.ID != "thing1" {
+		t.Errorf("expected structured content, got %#v", result.StructuredContent)
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; text != `{"id":"thing1","peers":["p1"]}` {
+		t.Errorf("expected JSON text content for older clients, got %q", text)
+	}
+
+	listTool, _, _ := ConvertTool("list_things", "test tool", ReadOnlyTool, func(ctx context.Context, args auditTestParams) ([]toolTestResult, error) {
+		return nil, nil
+	})
+	if listTool.OutputSchema.Type != "" {
+		t.Errorf("expected no output schema for a list result, got %+v", listTool.OutputSchema)
+	}
+}
@@ -0,0 +1,133 @@
+package mcpnetbird
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	netbirdTracingEnvVar = "NETBIRD_TRACING"
+
+	tracerName = "github.com/XNet-NGO/mcp-netbird"
+)
+
+// LoadTracingExporter returns the trace exporter with priority: CLI > env var. Empty disables tracing.
+func LoadTracingExporter(cliExporter string) string {
+	if cliExporter != "" {
+		return cliExporter
+	}
+	return os.Getenv(netbirdTracingEnvVar)
+}
+
+// SetupTracing installs a global tracer provider and the W3C trace context
+// propagator. exporter is "otlp", configured through the standard
+// OTEL_EXPORTER_OTLP_* environment variables, or "stdout", which pretty-prints
+// spans to stderr so they don't corrupt the protocol in stdio mode. The returned
+// function flushes and stops the exporter.
+func SetupTracing(ctx context.Context, exporter, version string) (func(context.Context) error, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "otlp":
+		spanExporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("invalid trace exporter '%s': must be otlp or stdout", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", "mcp-netbird"),
+			attribute.String("service.version", version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// tracer returns the package tracer from the global provider, which is a no-op
+// unless SetupTracing was called
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// ExtractTraceContextSSE is an SSEContextFunc that continues the trace of the
+// incoming HTTP request from its W3C traceparent and tracestate headers.
+var ExtractTraceContextSSE server.SSEContextFunc = func(ctx context.Context, req *http.Request) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+}
+
+// traceToolHandler wraps a tool handler so each call is a span carrying the tool
+// name and its redacted arguments
+func traceToolHandler(name string, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		attrs := []attribute.KeyValue{attribute.String("mcp.tool.name", name)}
+		if args, err := json.Marshal(RedactArguments(request.GetArguments())); err == nil {
+			attrs = append(attrs, attribute.String("mcp.tool.arguments", string(args)))
+		}
+
+		ctx, span := tracer().Start(ctx, "tool "+name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(attrs...),
+		)
+		defer span.End()
+
+		result, err := handler(ctx, request)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	}
+}
+
+// startAPISpan starts the client span of a Netbird API request
+func startAPISpan(ctx context.Context, method, path string) (context.Context, trace.Span) {
+	template := apiPathTemplate(path)
+	return tracer().Start(ctx, method+" "+template,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("url.template", template),
+		),
+	)
+}
+
+// endAPISpan records the outcome of a Netbird API request and ends its span
+func endAPISpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
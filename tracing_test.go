@@ -0,0 +1,149 @@
+package mcpnetbird
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func setupTestTracing(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	originalProvider := otel.GetTracerProvider()
+	originalPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(originalProvider)
+		otel.SetTextMapPropagator(originalPropagator)
+	})
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestLoadTracingExporter(t *testing.T) {
+	original := os.Getenv(netbirdTracingEnvVar)
+	defer os.Setenv(netbirdTracingEnvVar, original)
+
+	os.Setenv(netbirdTracingEnvVar, "")
+	if got := LoadTracingExporter(""); got != "" {
+		t.Errorf("expected tracing disabled by default, got %q", got)
+	}
+	os.Setenv(netbirdTracingEnvVar, "otlp")
+	if got := LoadTracingExporter(""); got != "otlp" {
+		t.Errorf("expected env value, got %q", got)
+	}
+	if got := LoadTracingExporter("stdout"); got != "stdout" {
+		t.Errorf("expected CLI value to take priority, got %q", got)
+	}
+	if _, err := SetupTracing(context.Background(), "zipkin", "test"); err == nil {
+		t.Error("expected error for unknown exporter")
+	}
+}
+
+func TestTracing_ToolCallSpanWithAPIChild(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"p1"}`))
+	}))
+	defer server.Close()
+
+	recorder := setupTestTracing(t)
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	tool := MustTool("get_thing", "test tool", ReadOnlyTool, func(ctx context.Context, args auditTestParams) (map[string]any, error) {
+		var out map[string]any
+		if err := client.Get(ctx, "/peers/"+args.Name, &out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	})
+
+	// Continue the trace of an incoming SSE request
+	incoming := httptest.NewRequest(http.MethodGet, "/message", nil)
+	incoming.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ExtractTraceContextSSE(WithNetbirdAPIKey(context.Background(), "token"), incoming)
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"name": "p1", "key": "nbp_secret"}
+	if _, err := tool.Handler(ctx, request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	apiSpan, toolSpan := spans[0], spans[1]
+
+	if toolSpan.Name() != "tool get_thing" {
+		t.Errorf("unexpected tool span name %q", toolSpan.Name())
+	}
+	if got := toolSpan.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected tool span to continue the incoming trace, got %s", got)
+	}
+	args, ok := spanAttribute(toolSpan, "mcp.tool.arguments")
+	if !ok || !strings.Contains(args.AsString(), `"name":"p1"`) {
+		t.Errorf("expected tool arguments attribute, got %q", args.AsString())
+	}
+	if strings.Contains(args.AsString(), "nbp_secret") {
+		t.Errorf("expected secret arguments to be redacted, got %q", args.AsString())
+	}
+
+	if apiSpan.Name() != "GET /peers/{id}" {
+		t.Errorf("unexpected API span name %q", apiSpan.Name())
+	}
+	if apiSpan.Parent().SpanID() != toolSpan.SpanContext().SpanID() {
+		t.Error("expected API span to be a child of the tool span")
+	}
+	if status, _ := spanAttribute(apiSpan, "http.response.status_code"); status.AsInt64() != 200 {
+		t.Errorf("expected status code attribute 200, got %v", status.AsInt64())
+	}
+	if !strings.Contains(traceparent, apiSpan.SpanContext().SpanID().String()) {
+		t.Errorf("expected traceparent of the API span on the outgoing request, got %q", traceparent)
+	}
+}
+
+func TestTracing_RecordsAPIErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	recorder := setupTestTracing(t)
+
+	client := NewNetbirdClientWithBaseURL(server.URL)
+	_ = client.Get(WithNetbirdAPIKey(context.Background(), "token"), "/groups/g1", nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status().Code.String() != "Error" {
+		t.Errorf("expected error status, got %s", spans[0].Status().Code)
+	}
+	if status, _ := spanAttribute(spans[0], "http.response.status_code"); status.AsInt64() != 404 {
+		t.Errorf("expected status code attribute 404, got %v", status.AsInt64())
+	}
+}
@@ -0,0 +1,202 @@
+package mcpnetbird
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/invopop/jsonschema"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// ArgumentProblem is one way in which the arguments of a tool call don't match
+// the tool's input schema. Path locates the offending value, e.g. rules[0].action.
+type ArgumentProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ArgumentError reports that the call arguments don't match the tool's input
+// schema. It lists every problem found rather than the first. Tool handlers
+// return it as the structured content of an error result, see argumentErrorResult.
+type ArgumentError struct {
+	Tool     string            `json:"tool"`
+	Problems []ArgumentProblem `json:"problems"`
+}
+
+func (e *ArgumentError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		if p.Path == "" {
+			problems[i] = p.Message
+		} else {
+			problems[i] = p.Path + ": " + p.Message
+		}
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Tool, strings.Join(problems, "; "))
+}
+
+// argumentErrorResult is the tool result of a call rejected with err. It is a
+// tool error rather than a protocol error, so the client sees the problems.
+func argumentErrorResult(err *ArgumentError) *mcp.CallToolResult {
+	result := mcp.NewToolResultError(err.Error())
+	result.StructuredContent = err
+	return result
+}
+
+// validateArguments checks decoded JSON arguments against the schema generated for a
+// tool's parameters: types, required properties, enums, patterns, numeric and length
+// bounds, and unknown properties. A null value is treated like an omitted one.
+func validateArguments(schema *jsonschema.Schema, value any) []ArgumentProblem {
+	var problems []ArgumentProblem
+	validateValue(schema, value, "", &problems)
+	return problems
+}
+
+func validateValue(schema *jsonschema.Schema, value any, path string, problems *[]ArgumentProblem) {
+	if schema == nil || value == nil {
+		return
+	}
+	report := func(format string, a ...any) {
+		*problems = append(*problems, ArgumentProblem{Path: path, Message: fmt.Sprintf(format, a...)})
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			report("expected object, got %s", jsonTypeName(value))
+			return
+		}
+		for _, name := range schema.Required {
+			if object[name] == nil {
+				*problems = append(*problems, ArgumentProblem{Path: joinArgumentPath(path, name), Message: "required"})
+			}
+		}
+		names := make([]string, 0, len(object))
+		for name := range object {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			propertyPath := joinArgumentPath(path, name)
+			if schema.Properties != nil {
+				if property, ok := schema.Properties.Get(name); ok {
+					validateValue(property, object[name], propertyPath, problems)
+					continue
+				}
+			}
+			switch schema.AdditionalProperties {
+			case nil, jsonschema.TrueSchema:
+			case jsonschema.FalseSchema:
+				*problems = append(*problems, ArgumentProblem{Path: propertyPath, Message: "unknown property"})
+			default:
+				validateValue(schema.AdditionalProperties, object[name], propertyPath, problems)
+			}
+		}
+		return
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			report("expected array, got %s", jsonTypeName(value))
+			return
+		}
+		if schema.MinItems != nil && uint64(len(items)) < *schema.MinItems {
+			report("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && uint64(len(items)) > *schema.MaxItems {
+			report("must have at most %d items", *schema.MaxItems)
+		}
+		for i, item := range items {
+			validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+		return
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			report("expected string, got %s", jsonTypeName(value))
+			return
+		}
+		length := uint64(utf8.RuneCountInString(str))
+		if schema.MinLength != nil && length < *schema.MinLength {
+			report("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			report("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if re, err := regexp.Compile(schema.Pattern); err == nil && !re.MatchString(str) {
+				report("%q doesn't match the pattern %s", str, schema.Pattern)
+			}
+		}
+	case "integer", "number":
+		n, ok := value.(float64)
+		if !ok || (schema.Type == "integer" && n != math.Trunc(n)) {
+			report("expected %s, got %s", schema.Type, jsonTypeName(value))
+			return
+		}
+		if minimum, err := schema.Minimum.Float64(); err == nil && n < minimum {
+			report("must be at least %s, got %v", schema.Minimum, n)
+		}
+		if maximum, err := schema.Maximum.Float64(); err == nil && n > maximum {
+			report("must be at most %s, got %v", schema.Maximum, n)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			report("expected boolean, got %s", jsonTypeName(value))
+			return
+		}
+	}
+
+	if len(schema.Enum) > 0 && !enumContains(schema.Enum, value) {
+		allowed := make([]string, len(schema.Enum))
+		for i, v := range schema.Enum {
+			allowed[i] = fmt.Sprint(v)
+		}
+		report("must be one of %s, got %v", strings.Join(allowed, ", "), value)
+	}
+}
+
+// enumContains compares by printed value, since enum values from struct tags are
+// Go ints or strings while decoded arguments are float64s or strings
+func enumContains(enum []any, value any) bool {
+	for _, v := range enum {
+		if fmt.Sprint(v) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinArgumentPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonTypeName names the JSON type of a value decoded by encoding/json
+func jsonTypeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,215 @@
+package mcpnetbird
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+type validationTestRule struct {
+	Action string `json:"action" jsonschema:"enum=accept,enum=drop"`
+	Ports  []int  `json:"ports,omitempty"`
+}
+
+type validationTestParams struct {
+	Name    string               `json:"name" jsonschema:"required"`
+	Enabled bool                 `json:"enabled,omitempty"`
+	Limit   int                  `json:"limit,omitempty"`
+	Rules   []validationTestRule `json:"rules,omitempty"`
+	Filter  map[string]string    `json:"filter,omitempty"`
+}
+
+func callValidationTestTool(t *testing.T, arguments any) (string, error) {
+	t.Helper()
+	_, handler, err := ConvertTool("create_thing", "test tool", AdditiveTool, func(ctx context.Context, args validationTestParams) (string, error) {
+		return "created " + args.Name, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = arguments
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected protocol error: %v", err)
+	}
+	if result.IsError {
+		return "", argumentError(t, result)
+	}
+	return result.Content[0].(mcp.TextContent).Text, nil
+}
+
+// argumentError returns the ArgumentError reported by an error result
+func argumentError(t *testing.T, result *mcp.CallToolResult) *ArgumentError {
+	t.Helper()
+	argErr, ok := result.StructuredContent.(*ArgumentError)
+	if !result.IsError || !ok {
+		t.Fatalf("expected an ArgumentError result, got %+v", result)
+	}
+	return argErr
+}
+
+func TestConvertTool_ValidArguments(t *testing.T) {
+	text, err := callValidationTestTool(t, map[string]any{
+		"name":    "thing1",
+		"enabled": true,
+		"limit":   nil,
+		"rules":   []any{map[string]any{"action": "accept", "ports": []any{22, 443}}},
+		"filter":  map[string]any{"os": "linux"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text != "created thing1" {
+		t.Errorf("unexpected result %q", text)
+	}
+}
+
+func TestConvertTool_InvalidArgumentsListsEveryProblem(t *testing.T) {
+	_, err := callValidationTestTool(t, map[string]any{
+		"enabled": "yes",
+		"limit":   2.5,
+		"rules": []any{
+			map[string]any{"action": "allow", "ports": []any{"22"}},
+			"drop",
+		},
+		"filter": map[string]any{"connected": true},
+		"peers":  []any{"p1"},
+	})
+	var argErr *ArgumentError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("expected an ArgumentError, got %v", err)
+	}
+	if argErr.Tool != "create_thing" {
+		t.Errorf("expected tool name in error, got %q", argErr.Tool)
+	}
+
+	want := map[string]string{
+		"name":              "required",
+		"enabled":           "expected boolean, got string",
+		"limit":             "expected integer, got number",
+		"rules[0].action":   "must be one of accept, drop, got allow",
+		"rules[0].ports[0]": "expected integer, got string",
+		"rules[1]":          "expected object, got string",
+		"filter.connected":  "expected string, got boolean",
+		"peers":             "unknown property",
+	}
+	got := make(map[string]string, len(argErr.Problems))
+	for _, p := range argErr.Problems {
+		got[p.Path] = p.Message
+	}
+	for path, message := range want {
+		if got[path] != message {
+			t.Errorf("expected %s: %q, got %q", path, message, got[path])
+		}
+	}
+	if len(argErr.Problems) != len(want) {
+		t.Errorf("expected %d problems, got %+v", len(want), argErr.Problems)
+	}
+	if !strings.Contains(err.Error(), "invalid arguments for create_thing: ") || !strings.Contains(err.Error(), "peers: unknown property") {
+		t.Errorf("expected every problem in the error message, got %q", err.Error())
+	}
+}
+
+func TestConvertTool_MissingArguments(t *testing.T) {
+	_, err := callValidationTestTool(t, nil)
+	var argErr *ArgumentError
+	if !errors.As(err, &argErr) || len(argErr.Problems) != 1 || argErr.Problems[0].Path != "name" {
+		t.Errorf("expected missing name to be reported, got %v", err)
+	}
+
+	_, err = callValidationTestTool(t, []any{"thing1"})
+	if !errors.As(err, &argErr) || argErr.Problems[0].Message != "expected object, got array" {
+		t.Errorf("expected non-object arguments to be rejected, got %v", err)
+	}
+}
+
+func TestConvertTool_InvalidArgumentsOnTheWire(t *testing.T) {
+	s := server.NewMCPServer("test", "0.0.0")
+	tool := MustTool("create_thing", "test tool", AdditiveTool, func(ctx context.Context, args validationTestParams) (string, error) {
+		return "created " + args.Name, nil
+	})
+	tool.Register(s)
+
+	message, _ := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": "create_thing", "arguments": map[string]any{"enabled": "yes"}},
+	})
+	response, ok := s.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+	if !ok {
+		t.Fatalf("expected a tool result rather than a JSON-RPC error")
+	}
+	data, _ := json.Marshal(response.Result)
+	var result struct {
+		IsError bool `json:"isError"`
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		StructuredContent ArgumentError `json:"structuredContent"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("unexpected result %s: %v", data, err)
+	}
+	if !result.IsError || len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, "enabled: expected boolean, got string") {
+		t.Errorf("expected an error result listing the problems, got %s", data)
+	}
+	if result.StructuredContent.Tool != "create_thing" || len(result.StructuredContent.Problems) != 2 {
+		t.Errorf("expected the problems as structured content, got %s", data)
+	}
+}
+
+type constraintTestParams struct {
+	ID     string   `json:"id" jsonschema:"minLength=2,maxLength=4,pattern=^[a-z]+$"`
+	Port   int      `json:"port" jsonschema:"minimum=1,maximum=65535"`
+	Ranges []string `json:"ranges" jsonschema:"minItems=1,pattern=^[0-9./]+$"`
+}
+
+func TestConvertTool_Constraints(t *testing.T) {
+	_, handler, err := ConvertTool("create_constrained", "test tool", AdditiveTool, func(ctx context.Context, args constraintTestParams) (string, error) {
+		return "ok", nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	request := mcp.CallToolRequest{}
+	request.Params.Arguments = map[string]any{"id": "abc", "port": 443, "ranges": []any{"10.0.0.0/8"}}
+	if result, err := handler(context.Background(), request); err != nil || result.IsError {
+		t.Errorf("expected valid arguments to pass, got %+v, %v", result, err)
+	}
+
+	request.Params.Arguments = map[string]any{"id": "ABCDE", "port": 0, "ranges": []any{}}
+	result, err := handler(context.Background(), request)
+	if err != nil {
+		t.Fatalf("unexpected protocol error: %v", err)
+	}
+	argErr := argumentError(t, result)
+	err = argErr
+	want := []string{
+		"id: must be at most 4 characters long",
+		`id: "ABCDE" doesn't match the pattern ^[a-z]+$`,
+		"port: must be at least 1, got 0",
+		"ranges: must have at least 1 items",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("expected %q in %q", w, err.Error())
+		}
+	}
+	if len(argErr.Problems) != len(want) {
+		t.Errorf("expected %d problems, got %+v", len(want), argErr.Problems)
+	}
+
+	request.Params.Arguments = map[string]any{"port": 65536, "ranges": []any{"lan"}}
+	result, _ = handler(context.Background(), request)
+	if err = argumentError(t, result); !strings.Contains(err.Error(), "port: must be at most 65535, got 65536") || !strings.Contains(err.Error(), "ranges[0]:") {
+		t.Errorf("expected maximum and item pattern problems, got %v", err)
+	}
+}